@@ -25,10 +25,17 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // FunctionSpec defines the desired state of Function.
+// +kubebuilder:validation:XValidation:rule="(has(self.gitRepo) && size(self.gitRepo) > 0) != has(self.source)",message="defina exatamente um entre gitRepo e source"
+// +kubebuilder:validation:XValidation:rule="!has(self.source) || !has(self.build.pipelineRef)",message="pipelineRef só é suportado com gitRepo"
 type FunctionSpec struct {
 	// O URL do repositório Git contendo o código-fonte da função.
-	// +kubebuilder:validation:Required
-	GitRepo string `json:"gitRepo"`
+	// Obrigatório, a menos que 'source' seja definido.
+	// +kubebuilder:validation:Optional
+	GitRepo string `json:"gitRepo,omitempty"`
+
+	// Opcional. Fonte alternativa ao repositório Git: arquivos inline ou um artefato OCI.
+	// +kubebuilder:validation:Optional
+	Source *SourceSpec `json:"source,omitempty"`
 
 	// Opcional. A revisão Git (branch, tag, ou hash) a ser usada.
 	// Padrão: 'main' se não especificado.
@@ -56,7 +63,87 @@ type FunctionSpec struct {
 	Observability ObservabilitySpec `json:"observability,omitempty"`
 }
 
+// SourceSpec define uma fonte de código alternativa ao Git
+// +kubebuilder:validation:XValidation:rule="[has(self.inline), has(self.oci), has(self.s3)].filter(x, x).size() == 1",message="defina exatamente um tipo de source"
+type SourceSpec struct {
+	// Opcional. Código-fonte inline, no próprio spec ou em um ConfigMap.
+	// +kubebuilder:validation:Optional
+	Inline *InlineSource `json:"inline,omitempty"`
+
+	// Opcional. Tarball do código-fonte publicado como artefato OCI (ex: via 'oras push').
+	// +kubebuilder:validation:Optional
+	OCI *OCISource `json:"oci,omitempty"`
+
+	// Opcional. Arquivo do código-fonte em um bucket compatível com S3 (AWS S3, MinIO, etc).
+	// +kubebuilder:validation:Optional
+	S3 *S3Source `json:"s3,omitempty"`
+}
+
+// InlineSource define arquivos de código-fonte declarados inline
+// +kubebuilder:validation:XValidation:rule="has(self.files) != has(self.configMapRef)",message="defina exatamente um entre files e configMapRef"
+type InlineSource struct {
+	// Opcional. Mapa de caminho relativo (ex: "src/main.py") para o conteúdo do arquivo.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MinProperties=1
+	Files map[string]string `json:"files,omitempty"`
+
+	// Opcional. ConfigMap no namespace da Function cujas chaves viram arquivos na raiz do código-fonte.
+	// +kubebuilder:validation:Optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+}
+
+// OCISource define um artefato OCI contendo o tarball do código-fonte
+type OCISource struct {
+	// A referência do artefato fixada por digest (ex: "registry.example.com/my-func-src@sha256:...").
+	// Tags podem mudar sem alterar o spec, então não disparariam um novo build.
+	// O artefato deve conter um arquivo .tar, .tar.gz ou .tgz com o código-fonte.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[^@\s]+@sha256:[0-9a-f]{64}$`
+	Image string `json:"image"`
+}
+
+// S3Source define um arquivo de código-fonte (.tar, .tar.gz ou .tgz) em um bucket compatível com S3
+// +kubebuilder:validation:XValidation:rule="has(self.key) != has(self.prefix)",message="defina exatamente um entre key e prefix"
+type S3Source struct {
+	// O nome do bucket.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+
+	// Opcional. A chave do objeto (ex: "releases/my-func.tar.gz").
+	// +kubebuilder:validation:Optional
+	Key string `json:"key,omitempty"`
+
+	// Opcional. Um prefixo de chaves; o arquivo modificado mais recentemente sob o prefixo é usado.
+	// +kubebuilder:validation:Optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Opcional. O endpoint do serviço (ex: "http://minio.minio.svc:9000"): apenas esquema, host e porta.
+	// Precisa estar entre os endpoints liberados pelo operator ('--allowed-s3-endpoints').
+	// Padrão: o endpoint da AWS para a região.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^https?://[^/?#@\s]+/?$`
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Opcional. A região do bucket.
+	// Padrão: 'us-east-1'.
+	// +kubebuilder:validation:Optional
+	Region string `json:"region,omitempty"`
+
+	// Opcional. Secret no namespace da Function com as chaves 'AWS_ACCESS_KEY_ID' e 'AWS_SECRET_ACCESS_KEY'.
+	// Se não especificado, o acesso é anônimo.
+	// +kubebuilder:validation:Optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+
+	// Opcional. Intervalo de verificação de novas versões do objeto (ETag).
+	// Padrão: 5m. Mínimo: 30s.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('30s')",message="pollInterval deve ser de pelo menos 30s"
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+}
+
 // BuildSpec define os parâmetros para o pipeline de build
+// +kubebuilder:validation:XValidation:rule="!has(self.pipelineRef) || (!has(self.preBuild) && !has(self.postBuild))",message="preBuild e postBuild não podem ser usados com pipelineRef"
 type BuildSpec struct {
 	// O nome do Secret do tipo 'kubernetes.io/dockerconfigjson'
 	// no mesmo namespace, usado para autenticar com o registry.
@@ -68,6 +155,122 @@ type BuildSpec struct {
 	// O pipeline irá adicionar o digest @sha256:
 	// +kubebuilder:validation:Required
 	Image string `json:"image"`
+
+	// Opcional. Controles de agendamento dos pods de build (TaskRuns do pipeline).
+	// Campos definidos aqui sobrescrevem o template padrão configurado no operator.
+	// +kubebuilder:validation:Optional
+	PodTemplate *BuildPodTemplate `json:"podTemplate,omitempty"`
+
+	// Opcional. Tasks do Tekton executadas após o clone e antes do build (ex: testes, linters).
+	// Rodam em paralelo; o build só começa se todas passarem.
+	// Se vazio, usa o padrão do namespace (ConfigMap 'zenith-build-steps'), se existir.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	PreBuild []BuildStep `json:"preBuild,omitempty"`
+
+	// Opcional. Tasks do Tekton executadas após o push da imagem (ex: testes de contrato, scanners).
+	// Rodam em paralelo; o deploy só acontece se todas passarem.
+	// Se vazio, usa o padrão do namespace (ConfigMap 'zenith-build-steps'), se existir.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	PostBuild []BuildStep `json:"postBuild,omitempty"`
+
+	// Opcional. Subdiretório do código-fonte onde está a função (ex: "services/checkout").
+	// Padrão: raiz do repositório.
+	// +kubebuilder:validation:Optional
+	SourceSubpath string `json:"sourceSubpath,omitempty"`
+
+	// Opcional. Usa um Pipeline existente do Tekton (ou uma referência via resolver)
+	// no lugar do pipeline embutido do operator.
+	// O Pipeline deve declarar os parâmetros 'url', 'revision' e 'image', o workspace 'source'
+	// e o resultado 'APP_IMAGE_DIGEST'. O parâmetro 'subpath' é opcional.
+	// +kubebuilder:validation:Optional
+	PipelineRef *BuildPipelineRef `json:"pipelineRef,omitempty"`
+}
+
+// BuildPipelineRef referencia um Pipeline do Tekton que segue o contrato de build do operator
+// +kubebuilder:validation:XValidation:rule="has(self.name) != has(self.resolver)",message="defina exatamente um entre name e resolver"
+type BuildPipelineRef struct {
+	// Opcional. O nome de um Pipeline no namespace da Function.
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
+
+	// Opcional. O resolver remoto do Tekton (ex: "git", "bundles", "hub", "cluster").
+	// +kubebuilder:validation:Optional
+	Resolver string `json:"resolver,omitempty"`
+
+	// Opcional. Parâmetros passados para o resolver.
+	// +kubebuilder:validation:Optional
+	ResolverParams []BuildStepParam `json:"resolverParams,omitempty"`
+
+	// Opcional. Parâmetros adicionais passados ao Pipeline, além dos parâmetros do contrato.
+	// +kubebuilder:validation:Optional
+	Params []BuildStepParam `json:"params,omitempty"`
+}
+
+// BuildStep define uma Task do Tekton, referenciada por nome, executada no pipeline de build.
+// Os valores dos parâmetros podem usar os resultados do pipeline, como
+// $(tasks.fetch-source.results.commit) e $(tasks.build-and-push.results.APP_IMAGE_DIGEST) (somente postBuild).
+type BuildStep struct {
+	// Nome do passo. Vira o nome da task no pipeline, prefixado com 'zenith-pre-' ou 'zenith-post-'.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=51
+	Name string `json:"name"`
+
+	// A Task do Tekton a ser executada (no namespace da Function).
+	// +kubebuilder:validation:Required
+	TaskRef BuildStepTaskRef `json:"taskRef"`
+
+	// Opcional. Parâmetros passados para a Task.
+	// +kubebuilder:validation:Optional
+	Params []BuildStepParam `json:"params,omitempty"`
+
+	// Opcional. Nome do workspace declarado pela Task que recebe o código-fonte.
+	// Padrão: 'source'.
+	// +kubebuilder:validation:Optional
+	Workspace string `json:"workspace,omitempty"`
+}
+
+// BuildStepTaskRef referencia uma Task do Tekton
+type BuildStepTaskRef struct {
+	// O nome da Task.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// BuildStepParam define um parâmetro de uma Task de BuildStep
+type BuildStepParam struct {
+	// O nome do parâmetro.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// O valor do parâmetro.
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
+}
+
+// BuildPodTemplate define onde e como os pods de build são agendados
+type BuildPodTemplate struct {
+	// Opcional. Seletor de nós onde os pods de build podem ser agendados.
+	// +kubebuilder:validation:Optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Opcional. Tolerations dos pods de build (ex: para nós de build com taints).
+	// +kubebuilder:validation:Optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Opcional. Regras de afinidade dos pods de build.
+	// +kubebuilder:validation:Optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Opcional. O PriorityClass dos pods de build.
+	// +kubebuilder:validation:Optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Opcional. O SecurityContext dos pods de build.
+	// +kubebuilder:validation:Optional
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
 }
 
 // FunctionVisibility defines the network visibility of a function.
@@ -82,11 +285,19 @@ const (
 )
 
 // DeploySpec define os parâmetros para o runtime
+// +kubebuilder:validation:XValidation:rule="!has(self.traffic) || !has(self.rollout)",message="traffic e rollout não podem ser usados juntos"
+// +kubebuilder:validation:XValidation:rule="!has(self.port) || !has(self.dapr) || !self.dapr.enabled || !has(self.dapr.appPort) || self.dapr.appPort == 0 || self.dapr.appPort == self.port.number",message="dapr.appPort deve ser igual a port.number"
+// +kubebuilder:validation:XValidation:rule="!has(self.timeoutSeconds) || !has(self.responseStartTimeoutSeconds) || self.responseStartTimeoutSeconds <= self.timeoutSeconds",message="responseStartTimeoutSeconds não pode ser maior que timeoutSeconds"
 type DeploySpec struct {
 	// Opcional. Configura a injeção do sidecar Dapr.
 	// +kubebuilder:validation:Optional
 	Dapr DaprConfig `json:"dapr,omitempty"`
 
+	// Opcional. A porta e o protocolo em que a função escuta.
+	// Padrão: 8080 com HTTP/1 (ou 'dapr.appPort', se definido).
+	// +kubebuilder:validation:Optional
+	Port *PortSpec `json:"port,omitempty"`
+
 	// Opcional. Variáveis de ambiente para injetar no container da função.
 	// Suporta valores estáticos, referências a Secrets/ConfigMaps, e referências a campos do Pod.
 	// +kubebuilder:validation:Optional
@@ -101,12 +312,611 @@ type DeploySpec struct {
 	// +kubebuilder:validation:Optional
 	Scale *ScaleSpec `json:"scale,omitempty"`
 
+	// Opcional. Requests e limits de CPU/memória do container da função.
+	// Sem valores, valem os padrões do cluster (config-defaults do Knative ou LimitRange).
+	// +kubebuilder:validation:Optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Opcional. Número máximo de requisições simultâneas por réplica.
+	// 0 significa sem limite (padrão do Knative).
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000
+	ContainerConcurrency *int64 `json:"containerConcurrency,omitempty"`
+
+	// Opcional. Tempo máximo, em segundos, para responder a uma requisição.
+	// Limitado pelo 'max-revision-timeout-seconds' do Knative. Default do Knative: 300.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+
+	// Opcional. Tempo máximo, em segundos, até o início da resposta (primeiro byte).
+	// Não pode ser maior que 'timeoutSeconds'.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ResponseStartTimeoutSeconds *int64 `json:"responseStartTimeoutSeconds,omitempty"`
+
+	// Opcional. Tempo máximo, em segundos, que uma requisição pode ficar sem trafegar dados.
+	// 0 desabilita o limite.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	IdleTimeoutSeconds *int64 `json:"idleTimeoutSeconds,omitempty"`
+
+	// Opcional. Probes de readiness, liveness e startup do container da função.
+	// Sem readiness, vale o padrão do Knative (TCP na porta da função). Sem startup, o operator
+	// aplica um padrão conforme o runtime detectado no build (ex: JVM, que inicia devagar).
+	// +kubebuilder:validation:Optional
+	Probes *ProbesSpec `json:"probes,omitempty"`
+
+	// Opcional. Volumes montados no container da função: Secrets, ConfigMaps, emptyDir,
+	// volumes projetados, PersistentVolumeClaims (se habilitados no Knative) ou arquivos inline.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=20
+	// +listType=map
+	// +listMapKey=name
+	Volumes []FunctionVolume `json:"volumes,omitempty"`
+
+	// Opcional. Containers que rodam ao lado da função no mesmo pod (ex: proxy do Cloud SQL, coletor de logs).
+	// Compartilham a rede e os volumes de 'volumes' com a função. Um sidecar com 'port' passa a
+	// receber o tráfego no lugar da função (ex: proxy de autenticação).
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	// +listType=map
+	// +listMapKey=name
+	Sidecars []FunctionContainer `json:"sidecars,omitempty"`
+
+	// Opcional. Containers executados em ordem, até o fim, antes da função iniciar (ex: migrações).
+	// Exige a flag 'kubernetes.podspec-init-containers' habilitada no Knative (ConfigMap config-features).
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	// +listType=map
+	// +listMapKey=name
+	InitContainers []FunctionContainer `json:"initContainers,omitempty"`
+
+	// Opcional. Agendamento e segurança do pod das revisões (nós dedicados, Pod Security Admission).
+	// Cada campo exige a flag correspondente habilitada no Knative (ConfigMap config-features).
+	// +kubebuilder:validation:Optional
+	Pod *FunctionPodSpec `json:"pod,omitempty"`
+
+	// Opcional. O ServiceAccount de runtime ('<nome>-runtime') com que as revisões rodam.
+	// Suas annotations configuram a identidade de workload na nuvem
+	// (ex: 'iam.gke.io/gcp-service-account', 'eks.amazonaws.com/role-arn').
+	// +kubebuilder:validation:Optional
+	ServiceAccount *RuntimeServiceAccountSpec `json:"serviceAccount,omitempty"`
+
+	// Opcional. Permissões na API do Kubernetes concedidas à função, no namespace dela.
+	// O operator gera um Role e um RoleBinding para o ServiceAccount de runtime, e só pode
+	// conceder permissões que ele mesmo tem. Secrets, pods/exec, pods/attach, pods/portforward,
+	// serviceaccounts/token e a escrita em subrecursos '/status' não são aceitos.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=20
+	Permissions []PermissionRule `json:"permissions,omitempty"`
+
 	// Opcional. Define a visibilidade de rede da função.
 	// - "cluster-local": A função só é acessível dentro do cluster (padrão).
 	// - "external": A função é acessível de fora do cluster via gateway externo.
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:default=cluster-local
 	Visibility FunctionVisibility `json:"visibility,omitempty"`
+
+	// Opcional. Domínios customizados da função, cada um reconciliado em um DomainMapping do Knative.
+	// Exige 'visibility: external'. O DNS de cada domínio deve apontar para o gateway do Knative.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=20
+	// +listType=map
+	// +listMapKey=name
+	Domains []FunctionDomain `json:"domains,omitempty"`
+
+	// Opcional. Divisão do tráfego entre a revisão mais recente e revisões anteriores.
+	// Quando omitido, 100% do tráfego vai para a revisão mais recente pronta.
+	// Os percentuais devem somar 100.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	Traffic []TrafficTarget `json:"traffic,omitempty"`
+
+	// Opcional. Tags nomeadas que expõem revisões específicas em URLs dedicadas, sem tráfego de produção.
+	// Cada tag vira um alvo da rota do Knative com 0% do tráfego (ex: "candidate" → "candidate-my-func.<namespace>...").
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	// +listType=map
+	// +listMapKey=name
+	Tags []RevisionTag `json:"tags,omitempty"`
+
+	// Opcional. Rollout canário automático de cada novo build, com análise de métricas e rollback.
+	// Não pode ser combinado com 'traffic'.
+	// +kubebuilder:validation:Optional
+	Rollout *RolloutSpec `json:"rollout,omitempty"`
+
+	// Opcional. Fixa a imagem servida em um digest anterior (rollback), sem novo build.
+	// Aceita a referência completa ("registry.io/org/func@sha256:...") ou apenas o digest ("sha256:...");
+	// um digest sozinho é procurado em 'status.revisions' e, se ausente, combinado com 'spec.build.image'.
+	// Enquanto definido, builds e rollouts ficam suspensos. Remova o campo para voltar ao último build.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`(^|@)sha256:[a-f0-9]{64}$`
+	PinnedImage string `json:"pinnedImage,omitempty"`
+}
+
+// RolloutSpec define os passos de um rollout canário
+type RolloutSpec struct {
+	// Os passos do rollout, em ordem crescente de percentual (ex: 10, 50, 100).
+	// Ao fim do último passo, a nova revisão recebe 100% do tráfego.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=10
+	Steps []RolloutStep `json:"steps"`
+
+	// Opcional. Critérios de sucesso avaliados ao fim de cada passo.
+	// Sem análise, o rollout avança após cada pausa.
+	// +kubebuilder:validation:Optional
+	Analysis *RolloutAnalysis `json:"analysis,omitempty"`
+}
+
+// RolloutStep define um passo do rollout canário
+type RolloutStep struct {
+	// O percentual do tráfego enviado à nova revisão neste passo.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Percent int64 `json:"percent"`
+
+	// Opcional. Quanto tempo manter o passo antes da análise (ex: "5m").
+	// Padrão: 1m.
+	// +kubebuilder:validation:Optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
+// RolloutAnalysis define as consultas que decidem se o rollout avança
+type RolloutAnalysis struct {
+	// Opcional. O endereço de uma API compatível com Prometheus (ex: "http://prometheus.monitoring:9090").
+	// Padrão: o Prometheus configurado no operator (--prometheus-url). Só é aceito quando o operator
+	// permite a substituição por Function (--allow-prometheus-url-override).
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^https?://`
+	PrometheusURL string `json:"prometheusURL,omitempty"`
+
+	// As métricas avaliadas; todas precisam passar para o rollout avançar.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=10
+	Metrics []RolloutMetric `json:"metrics"`
+}
+
+// RolloutMetric define um critério de sucesso do rollout
+type RolloutMetric struct {
+	// O nome da métrica (ex: "error-rate").
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// A consulta PromQL, que deve retornar um único valor.
+	// Os marcadores $(revision), $(function) e $(namespace) são substituídos pela revisão canário,
+	// pelo nome e pelo namespace da função.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Query string `json:"query"`
+
+	// O valor máximo aceito (ex: "0.01" para 1% de erros, "0.5" para 500ms de latência).
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^-?[0-9]+(\.[0-9]+)?$`
+	Max string `json:"max"`
+}
+
+// TrafficTarget define a fatia do tráfego destinada a uma revisão
+// +kubebuilder:validation:XValidation:rule="[has(self.latestRevision) && self.latestRevision, has(self.revisionName), has(self.imageDigest)].filter(x, x).size() == 1",message="defina exatamente um entre latestRevision, revisionName e imageDigest"
+type TrafficTarget struct {
+	// Se verdadeiro, o alvo acompanha a revisão mais recente pronta (a do último build).
+	// +kubebuilder:validation:Optional
+	LatestRevision bool `json:"latestRevision,omitempty"`
+
+	// O nome de uma revisão Knative da função (ex: "my-func-00003").
+	// +kubebuilder:validation:Optional
+	RevisionName string `json:"revisionName,omitempty"`
+
+	// O digest da imagem de um deploy anterior ("sha256:..." ou "imagem@sha256:...").
+	// O operator resolve o digest para a revisão Knative mais recente que serve essa imagem.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`(^|@)sha256:[a-f0-9]{64}$`
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// Opcional. Tag que expõe o alvo em uma URL dedicada (ex: "stable" → "stable-my-func.<namespace>...").
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Tag string `json:"tag,omitempty"`
+
+	// O percentual do tráfego (0 a 100) enviado ao alvo.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percent int64 `json:"percent"`
+}
+
+// RevisionTag define uma tag nomeada que aponta para uma revisão, sem receber tráfego
+// +kubebuilder:validation:XValidation:rule="[has(self.latestRevision) && self.latestRevision, has(self.revisionName), has(self.imageDigest)].filter(x, x).size() == 1",message="defina exatamente um entre latestRevision, revisionName e imageDigest"
+type RevisionTag struct {
+	// O nome da tag, que prefixa o host da URL dedicada (ex: "candidate", "preview").
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Se verdadeiro, a tag acompanha a revisão mais recente pronta (a do último build).
+	// +kubebuilder:validation:Optional
+	LatestRevision bool `json:"latestRevision,omitempty"`
+
+	// O nome de uma revisão Knative da função (ex: "my-func-00003").
+	// +kubebuilder:validation:Optional
+	RevisionName string `json:"revisionName,omitempty"`
+
+	// O digest da imagem de um build ("sha256:..." ou "imagem@sha256:...").
+	// O operator resolve o digest para a revisão Knative mais recente que serve essa imagem.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`(^|@)sha256:[a-f0-9]{64}$`
+	ImageDigest string `json:"imageDigest,omitempty"`
+}
+
+// FunctionDomain define um domínio customizado que aponta para a função
+type FunctionDomain struct {
+	// O nome do host (ex: "api.example.com"). Também é o nome do DomainMapping criado.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)+$`
+	Name string `json:"name"`
+
+	// Opcional. Como o TLS do domínio é terminado. Sem TLS, o domínio é servido apenas por HTTP
+	// (ou pelo auto-TLS padrão do cluster, se habilitado no Knative).
+	// +kubebuilder:validation:Optional
+	TLS *DomainTLS `json:"tls,omitempty"`
+}
+
+// DomainTLS define o certificado de um domínio customizado
+// +kubebuilder:validation:XValidation:rule="has(self.secretName) != has(self.certificateClass)",message="defina exatamente um entre secretName e certificateClass"
+type DomainTLS struct {
+	// Opcional. Um Secret do tipo kubernetes.io/tls, no namespace da função, com o certificado do domínio.
+	// +kubebuilder:validation:Optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Opcional. A classe de certificado do auto-TLS do Knative que emite o certificado
+	// (ex: "cert-manager.certificate.networking.knative.dev").
+	// +kubebuilder:validation:Optional
+	CertificateClass string `json:"certificateClass,omitempty"`
+}
+
+// FunctionVolume define um volume e onde ele é montado no container da função
+// +kubebuilder:validation:XValidation:rule="[has(self.secret), has(self.configMap), has(self.emptyDir), has(self.projected), has(self.persistentVolumeClaim), has(self.files)].filter(x, x).size() == 1",message="defina exatamente uma fonte para o volume"
+type FunctionVolume struct {
+	// O nome do volume, único na função.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// O caminho absoluto onde o volume é montado (ex: "/etc/certs").
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^/`
+	MountPath string `json:"mountPath"`
+
+	// Opcional. Monta apenas este caminho de dentro do volume.
+	// Arquivos montados com subPath não recebem atualizações do Secret/ConfigMap.
+	// +kubebuilder:validation:Optional
+	SubPath string `json:"subPath,omitempty"`
+
+	// Opcional. Um Secret do namespace da função.
+	// +kubebuilder:validation:Optional
+	Secret *corev1.SecretVolumeSource `json:"secret,omitempty"`
+
+	// Opcional. Um ConfigMap do namespace da função.
+	// +kubebuilder:validation:Optional
+	ConfigMap *corev1.ConfigMapVolumeSource `json:"configMap,omitempty"`
+
+	// Opcional. Um diretório temporário e gravável, descartado com a réplica.
+	// +kubebuilder:validation:Optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+
+	// Opcional. Combina Secrets, ConfigMaps, downward API e tokens de ServiceAccount em um diretório.
+	// +kubebuilder:validation:Optional
+	Projected *corev1.ProjectedVolumeSource `json:"projected,omitempty"`
+
+	// Opcional. Um PersistentVolumeClaim do namespace da função.
+	// Exige a feature 'kubernetes.podspec-persistent-volume-claim' do Knative
+	// (e 'kubernetes.podspec-persistent-volume-write' para montagens graváveis).
+	// +kubebuilder:validation:Optional
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
+
+	// Opcional. Mapa de caminho relativo (ex: "config/app.json") para o conteúdo do arquivo.
+	// O operator gera um ConfigMap com os arquivos, gerenciado junto com a função.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MinProperties=1
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// FunctionContainer define um container adicional do pod da função (sidecar ou init container)
+type FunctionContainer struct {
+	// O nome do container, único no pod. "queue-proxy" e "daprd" são reservados.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// A imagem do container (ex: "gcr.io/cloud-sql-connectors/cloud-sql-proxy:2.11.0").
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// Opcional. Substitui o ENTRYPOINT da imagem.
+	// +kubebuilder:validation:Optional
+	Command []string `json:"command,omitempty"`
+
+	// Opcional. Argumentos do comando (substitui o CMD da imagem).
+	// +kubebuilder:validation:Optional
+	Args []string `json:"args,omitempty"`
+
+	// Opcional. Variáveis de ambiente do container, com as mesmas regras de 'spec.deploy.env'.
+	// +kubebuilder:validation:Optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Opcional. Fontes de variáveis de ambiente (ConfigMaps e Secrets).
+	// +kubebuilder:validation:Optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// Opcional. Requests e limits de CPU/memória do container.
+	// +kubebuilder:validation:Optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Opcional. Volumes de 'spec.deploy.volumes' montados neste container.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=20
+	VolumeMounts []ContainerVolumeMount `json:"volumeMounts,omitempty"`
+
+	// Opcional. Apenas em sidecars: a porta em que o sidecar recebe o tráfego do Knative no lugar da função.
+	// Só um container do pod pode receber tráfego; com ele, a função não pode definir 'spec.deploy.probes'.
+	// +kubebuilder:validation:Optional
+	Port *PortSpec `json:"port,omitempty"`
+}
+
+// FunctionPodSpec define o agendamento e a segurança do pod da função
+type FunctionPodSpec struct {
+	// Opcional. Seletor de nós onde as réplicas podem ser agendadas.
+	// Exige 'kubernetes.podspec-nodeselector'.
+	// +kubebuilder:validation:Optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Opcional. Tolerations das réplicas (ex: para nós dedicados com taints).
+	// Exige 'kubernetes.podspec-tolerations'.
+	// +kubebuilder:validation:Optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Opcional. Regras de afinidade e anti-afinidade de nós e pods.
+	// Exige 'kubernetes.podspec-affinity'.
+	// +kubebuilder:validation:Optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Opcional. Como as réplicas são distribuídas entre zonas ou nós.
+	// Exige 'kubernetes.podspec-topologyspreadconstraints'.
+	// +kubebuilder:validation:Optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// Opcional. A PriorityClass das réplicas.
+	// Exige 'kubernetes.podspec-priorityclassname'.
+	// +kubebuilder:validation:Optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Opcional. A RuntimeClass das réplicas (ex: "gvisor", "kata").
+	// Exige 'kubernetes.podspec-runtimeclassname'.
+	// +kubebuilder:validation:Optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+
+	// Opcional. O securityContext do pod.
+	// Exige 'kubernetes.podspec-securitycontext', exceto quando só define seccompProfile
+	// e 'secure-pod-defaults' está habilitado.
+	// +kubebuilder:validation:Optional
+	SecurityContext *PodSecurityContext `json:"securityContext,omitempty"`
+
+	// Opcional. O securityContext aplicado a todos os containers: função, sidecars e init containers.
+	// Adicionar capabilities exige 'kubernetes.containerspec-addcapabilities'.
+	// +kubebuilder:validation:Optional
+	ContainerSecurityContext *ContainerSecurityContext `json:"containerSecurityContext,omitempty"`
+}
+
+// RuntimeServiceAccountSpec configura o ServiceAccount com que as revisões da função rodam
+type RuntimeServiceAccountSpec struct {
+	// Opcional. Annotations do ServiceAccount (ex: para Workload Identity do GKE ou IRSA do EKS).
+	// +kubebuilder:validation:Optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PermissionRule concede verbos sobre recursos da API do Kubernetes, como uma regra de Role
+type PermissionRule struct {
+	// Opcional. Grupos de API dos recursos (ex: "apps"). Sem grupos, vale o grupo core ("").
+	// +kubebuilder:validation:Optional
+	APIGroups []string `json:"apiGroups,omitempty"`
+
+	// Recursos da regra (ex: "configmaps", "pods/log").
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Resources []string `json:"resources"`
+
+	// Opcional. Restringe a regra aos objetos com estes nomes.
+	// +kubebuilder:validation:Optional
+	ResourceNames []string `json:"resourceNames,omitempty"`
+
+	// Verbos permitidos. Verbos de escalonamento (bind, escalate, impersonate) e '*' não são aceitos.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:items:Enum=get;list;watch;create;update;patch;delete;deletecollection
+	Verbs []string `json:"verbs"`
+}
+
+// PodSecurityContext contém os campos do securityContext do pod aceitos pelo Knative
+type PodSecurityContext struct {
+	// Opcional. O UID com que os containers rodam.
+	// +kubebuilder:validation:Optional
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+
+	// Opcional. O GID com que os containers rodam.
+	// +kubebuilder:validation:Optional
+	RunAsGroup *int64 `json:"runAsGroup,omitempty"`
+
+	// Opcional. Impede que os containers rodem como root.
+	// +kubebuilder:validation:Optional
+	RunAsNonRoot *bool `json:"runAsNonRoot,omitempty"`
+
+	// Opcional. GID dono dos volumes montados.
+	// +kubebuilder:validation:Optional
+	FSGroup *int64 `json:"fsGroup,omitempty"`
+
+	// Opcional. Grupos adicionais dos processos dos containers.
+	// +kubebuilder:validation:Optional
+	SupplementalGroups []int64 `json:"supplementalGroups,omitempty"`
+
+	// Opcional. O perfil seccomp dos containers (ex: RuntimeDefault).
+	// +kubebuilder:validation:Optional
+	SeccompProfile *corev1.SeccompProfile `json:"seccompProfile,omitempty"`
+}
+
+// ContainerSecurityContext contém os campos do securityContext de container aceitos pelo Knative
+type ContainerSecurityContext struct {
+	// Opcional. O UID com que o container roda.
+	// +kubebuilder:validation:Optional
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+
+	// Opcional. O GID com que o container roda.
+	// +kubebuilder:validation:Optional
+	RunAsGroup *int64 `json:"runAsGroup,omitempty"`
+
+	// Opcional. Impede que o container rode como root.
+	// +kubebuilder:validation:Optional
+	RunAsNonRoot *bool `json:"runAsNonRoot,omitempty"`
+
+	// Opcional. Se um processo pode ganhar mais privilégios que o pai (ex: via setuid).
+	// +kubebuilder:validation:Optional
+	AllowPrivilegeEscalation *bool `json:"allowPrivilegeEscalation,omitempty"`
+
+	// Opcional. Monta o sistema de arquivos raiz do container como somente leitura.
+	// +kubebuilder:validation:Optional
+	ReadOnlyRootFilesystem *bool `json:"readOnlyRootFilesystem,omitempty"`
+
+	// Opcional. Capabilities do Linux removidas (ex: ["ALL"]) ou adicionadas.
+	// +kubebuilder:validation:Optional
+	Capabilities *corev1.Capabilities `json:"capabilities,omitempty"`
+
+	// Opcional. O perfil seccomp do container (ex: RuntimeDefault).
+	// +kubebuilder:validation:Optional
+	SeccompProfile *corev1.SeccompProfile `json:"seccompProfile,omitempty"`
+}
+
+// ContainerVolumeMount monta um volume da função em um sidecar ou init container
+type ContainerVolumeMount struct {
+	// O nome de um volume de 'spec.deploy.volumes'.
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// O caminho absoluto onde o volume é montado.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^/`
+	MountPath string `json:"mountPath"`
+
+	// Opcional. Monta apenas este caminho de dentro do volume.
+	// +kubebuilder:validation:Optional
+	SubPath string `json:"subPath,omitempty"`
+
+	// Opcional. Monta o volume somente para leitura.
+	// +kubebuilder:validation:Optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// PortSpec define a porta de serviço do container da função
+type PortSpec struct {
+	// A porta em que a função escuta.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Number int32 `json:"number"`
+
+	// Opcional. O protocolo da porta:
+	// - "http1": HTTP/1.1 (padrão).
+	// - "h2c": HTTP/2 sem TLS, necessário para gRPC e streaming bidirecional.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=http1
+	Protocol PortProtocol `json:"protocol,omitempty"`
+}
+
+// PortProtocol defines the protocol Knative uses to reach the function port.
+// +kubebuilder:validation:Enum=http1;h2c
+type PortProtocol string
+
+const (
+	// PortProtocolHTTP1 serves HTTP/1.1.
+	PortProtocolHTTP1 PortProtocol = "http1"
+	// PortProtocolH2C serves HTTP/2 over cleartext, as used by gRPC.
+	PortProtocolH2C PortProtocol = "h2c"
+)
+
+// ProbesSpec define as verificações de saúde do container da função
+type ProbesSpec struct {
+	// Opcional. Decide quando a réplica passa a receber tráfego.
+	// +kubebuilder:validation:Optional
+	Readiness *ProbeSpec `json:"readiness,omitempty"`
+
+	// Opcional. Reinicia o container quando falha.
+	// +kubebuilder:validation:Optional
+	Liveness *ProbeSpec `json:"liveness,omitempty"`
+
+	// Opcional. Adia readiness e liveness até a aplicação terminar de iniciar.
+	// +kubebuilder:validation:Optional
+	Startup *ProbeSpec `json:"startup,omitempty"`
+}
+
+// ProbeType defines how a probe checks the function container.
+// +kubebuilder:validation:Enum=http;tcp;grpc
+type ProbeType string
+
+const (
+	// ProbeTypeHTTP sends an HTTP GET to the function port; 2xx and 3xx responses succeed.
+	ProbeTypeHTTP ProbeType = "http"
+	// ProbeTypeTCP opens a TCP connection to the function port.
+	ProbeTypeTCP ProbeType = "tcp"
+	// ProbeTypeGRPC calls the gRPC health checking protocol on the function port.
+	ProbeTypeGRPC ProbeType = "grpc"
+)
+
+// ProbeSpec define uma verificação de saúde na porta da função
+// +kubebuilder:validation:XValidation:rule="!has(self.path) || self.type == 'http'",message="path só é suportado com type: http"
+// +kubebuilder:validation:XValidation:rule="!has(self.service) || self.type == 'grpc'",message="service só é suportado com type: grpc"
+type ProbeSpec struct {
+	// O tipo da verificação: "http", "tcp" ou "grpc".
+	// +kubebuilder:validation:Required
+	Type ProbeType `json:"type"`
+
+	// Opcional. O caminho HTTP verificado (ex: "/healthz"). Padrão: "/".
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^/`
+	Path string `json:"path,omitempty"`
+
+	// Opcional. O serviço informado na verificação gRPC. Padrão: vazio (o servidor como um todo).
+	// +kubebuilder:validation:Optional
+	Service string `json:"service,omitempty"`
+
+	// Opcional. Segundos de espera antes da primeira verificação.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+
+	// Opcional. Intervalo entre verificações, em segundos.
+	// Para readiness, omitir usa a verificação agressiva do Knative (sub-segundo) durante o início.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// Opcional. Segundos até a verificação expirar. Padrão: 1.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// Opcional. Falhas consecutivas até a verificação ser considerada falha. Padrão: 3.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
 }
 
 // ScaleSpec define os parâmetros de autoscaling
@@ -123,8 +933,83 @@ type ScaleSpec struct {
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:Minimum=0
 	MaxScale *int32 `json:"maxScale,omitempty"`
+
+	// Opcional. A implementação do autoscaler.
+	// - "kpa": Knative Pod Autoscaler (padrão), escala por concurrency ou rps e suporta scale-to-zero.
+	// - "hpa": Horizontal Pod Autoscaler do Kubernetes, escala por cpu ou memory.
+	// +kubebuilder:validation:Optional
+	Class AutoscalingClass `json:"class,omitempty"`
+
+	// Opcional. A métrica observada pelo autoscaler.
+	// "concurrency" (padrão) e "rps" exigem a classe kpa; "cpu" e "memory" exigem a classe hpa.
+	// +kubebuilder:validation:Optional
+	Metric AutoscalingMetric `json:"metric,omitempty"`
+
+	// Opcional. O valor alvo da métrica por réplica: requisições simultâneas (concurrency),
+	// requisições por segundo (rps), millicores (cpu) ou MiB (memory).
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	Target *int32 `json:"target,omitempty"`
+
+	// Opcional. Percentual do alvo a partir do qual o autoscaler cria novas réplicas (1 a 100).
+	// Default do Knative: 70.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	TargetUtilizationPercentage *int32 `json:"targetUtilizationPercentage,omitempty"`
+
+	// Opcional. Número de réplicas criadas quando a revisão é implantada.
+	// 0 exige 'allow-zero-initial-scale' habilitado no config-autoscaler do Knative.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	InitialScale *int32 `json:"initialScale,omitempty"`
+
+	// Opcional. Quanto tempo esperar, com demanda menor, antes de remover réplicas (0 a 1h, ex: "5m").
+	// +kubebuilder:validation:Optional
+	ScaleDownDelay *metav1.Duration `json:"scaleDownDelay,omitempty"`
+
+	// Opcional. A janela estável, sobre a qual a métrica é calculada (6s a 1h, ex: "60s"). Apenas kpa.
+	// +kubebuilder:validation:Optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// Opcional. A janela de pânico, como percentual da janela estável (1 a 100). Apenas kpa.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	PanicWindowPercentage *int32 `json:"panicWindowPercentage,omitempty"`
+
+	// Opcional. Número mínimo de réplicas quando a revisão sai do zero. Apenas kpa.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ActivationScale *int32 `json:"activationScale,omitempty"`
 }
 
+// AutoscalingClass defines the Knative autoscaler implementation.
+// +kubebuilder:validation:Enum=kpa;hpa
+type AutoscalingClass string
+
+const (
+	// AutoscalingClassKPA is the Knative Pod Autoscaler.
+	AutoscalingClassKPA AutoscalingClass = "kpa"
+	// AutoscalingClassHPA is the Kubernetes Horizontal Pod Autoscaler.
+	AutoscalingClassHPA AutoscalingClass = "hpa"
+)
+
+// AutoscalingMetric defines the metric observed by the autoscaler.
+// +kubebuilder:validation:Enum=concurrency;rps;cpu;memory
+type AutoscalingMetric string
+
+const (
+	// AutoscalingMetricConcurrency scales on in-flight requests per replica.
+	AutoscalingMetricConcurrency AutoscalingMetric = "concurrency"
+	// AutoscalingMetricRPS scales on requests per second per replica.
+	AutoscalingMetricRPS AutoscalingMetric = "rps"
+	// AutoscalingMetricCPU scales on CPU usage (millicores).
+	AutoscalingMetricCPU AutoscalingMetric = "cpu"
+	// AutoscalingMetricMemory scales on memory usage (MiB).
+	AutoscalingMetricMemory AutoscalingMetric = "memory"
+)
+
 // DaprConfig define os parâmetros de injeção do Dapr
 type DaprConfig struct {
 	// Se verdadeiro, injeta o sidecar Dapr.
@@ -135,9 +1020,12 @@ type DaprConfig struct {
 	// +kubebuilder:validation:Required
 	AppID string `json:"appID"`
 
-	// A porta em que a aplicação (função) escuta.
-	// +kubebuilder:validation:Required
-	AppPort int `json:"appPort"`
+	// Opcional. A porta em que a aplicação (função) escuta.
+	// Padrão: 'spec.deploy.port.number' (ou 8080). Se ambos forem definidos, devem ser iguais.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	AppPort int `json:"appPort,omitempty"`
 }
 
 // EventingSpec define a subscrição de eventos
@@ -199,16 +1087,283 @@ type FunctionStatus struct {
 	// +kubebuilder:validation:Optional
 	ImageDigest string `json:"imageDigest,omitempty"`
 
+	// A regra de reescrita pull-through aplicada à imagem no deploy, se houver.
+	// Indica para qual endpoint de pull a referência de push em 'imageDigest' foi traduzida.
+	// +kubebuilder:validation:Optional
+	ImageRewrite *ImageRewrite `json:"imageRewrite,omitempty"`
+
+	// O andamento do build atual (ou do último build concluído).
+	// +kubebuilder:validation:Optional
+	Build *BuildStatus `json:"build,omitempty"`
+
+	// A última consulta à fonte remota ('spec.source.s3'), reaproveitada até o próximo 'pollInterval'.
+	// +kubebuilder:validation:Optional
+	Source *SourceStatus `json:"source,omitempty"`
+
+	// Os buildpacks e tipos de processo detectados no último build bem-sucedido.
+	// +kubebuilder:validation:Optional
+	Runtime *RuntimeStatus `json:"runtime,omitempty"`
+
 	// A URL publicamente acessível da função (do Knative Service).
 	// +kubebuilder:validation:Optional
 	URL string `json:"url,omitempty"`
 
+	// A divisão de tráfego efetiva, como reportada pela rota do Knative Service.
+	// +kubebuilder:validation:Optional
+	Traffic []TrafficTargetStatus `json:"traffic,omitempty"`
+
+	// As URLs dedicadas de cada tag em 'spec.deploy.tags', pelo nome da tag.
+	// +kubebuilder:validation:Optional
+	TagURLs map[string]string `json:"tagURLs,omitempty"`
+
+	// O estado dos domínios customizados ('spec.deploy.domains').
+	// +kubebuilder:validation:Optional
+	Domains []DomainStatus `json:"domains,omitempty"`
+
+	// O andamento do rollout canário atual (ou do último rollout).
+	// +kubebuilder:validation:Optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// Histórico das imagens implantadas, da mais recente para a mais antiga (no máximo 10 entradas).
+	// Use 'spec.deploy.pinnedImage' para voltar a uma delas.
+	// +kubebuilder:validation:Optional
+	Revisions []RevisionHistoryEntry `json:"revisions,omitempty"`
+
 	// O 'generation' observado do spec.
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
+// BuildStatus descreve o estado do build da função
+type BuildStatus struct {
+	// O andamento do PipelineRun de build, atualizado a partir dos TaskRuns filhos.
+	// +kubebuilder:validation:Optional
+	Progress *BuildProgress `json:"progress,omitempty"`
+
+	// A revisão da fonte do último build bem-sucedido (commit Git, digest OCI, versão S3...).
+	// +kubebuilder:validation:Optional
+	SourceRevision string `json:"sourceRevision,omitempty"`
+}
+
+// SourceStatus descreve a última consulta do operator à fonte remota da função
+type SourceStatus struct {
+	// A revisão encontrada na consulta (ex: "s3://bucket/app.tar.gz#etag").
+	// +kubebuilder:validation:Optional
+	Revision string `json:"revision,omitempty"`
+
+	// A chave do objeto S3 resolvida a partir de 'prefix'.
+	// +kubebuilder:validation:Optional
+	S3Key string `json:"s3Key,omitempty"`
+
+	// Quando a fonte foi consultada com sucesso pela última vez.
+	// +kubebuilder:validation:Optional
+	LastCheckedTime *metav1.Time `json:"lastCheckedTime,omitempty"`
+
+	// O 'generation' do spec usado na consulta; uma mudança no spec força uma nova consulta.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// RevisionHistoryEntry descreve uma imagem implantada pela função
+type RevisionHistoryEntry struct {
+	// A referência completa da imagem implantada (imagem@sha256:...).
+	ImageDigest string `json:"imageDigest"`
+
+	// A revisão Knative que serviu a imagem.
+	// +kubebuilder:validation:Optional
+	RevisionName string `json:"revisionName,omitempty"`
+
+	// A revisão da fonte construída (ex: o commit Git).
+	// +kubebuilder:validation:Optional
+	Commit string `json:"commit,omitempty"`
+
+	// Quando a revisão ficou pronta.
+	// +kubebuilder:validation:Optional
+	DeployedAt *metav1.Time `json:"deployedAt,omitempty"`
+}
+
+// BuildProgress descreve o andamento de um PipelineRun de build
+type BuildProgress struct {
+	// O nome do PipelineRun acompanhado.
+	PipelineRun string `json:"pipelineRun"`
+
+	// Resumo legível do andamento (ex: "exporting image (2m13s)").
+	// +kubebuilder:validation:Optional
+	Summary string `json:"summary,omitempty"`
+
+	// A task do pipeline em execução (ex: "build-and-push").
+	// +kubebuilder:validation:Optional
+	CurrentTask string `json:"currentTask,omitempty"`
+
+	// O step em execução dentro da task atual (ex: "export").
+	// +kubebuilder:validation:Optional
+	CurrentStep string `json:"currentStep,omitempty"`
+
+	// Quando o PipelineRun começou.
+	// +kubebuilder:validation:Optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Quando o PipelineRun terminou.
+	// +kubebuilder:validation:Optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Tempo decorrido desde o início do build (ex: "2m13s").
+	// +kubebuilder:validation:Optional
+	Elapsed string `json:"elapsed,omitempty"`
+
+	// O andamento de cada task do pipeline, na ordem do pipeline.
+	// +kubebuilder:validation:Optional
+	Tasks []BuildTaskProgress `json:"tasks,omitempty"`
+}
+
+// BuildTaskProgress descreve o andamento de uma task do pipeline de build
+type BuildTaskProgress struct {
+	// O nome da task no pipeline.
+	Name string `json:"name"`
+
+	// O estado da task: Pending, Running, Succeeded, Failed ou Skipped.
+	Status string `json:"status"`
+
+	// Quando a task começou.
+	// +kubebuilder:validation:Optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Quando a task terminou.
+	// +kubebuilder:validation:Optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Duração da task (ou tempo decorrido, se em execução).
+	// +kubebuilder:validation:Optional
+	Duration string `json:"duration,omitempty"`
+}
+
+// RuntimeStatus descreve o que o Cloud Native Buildpacks detectou e usou no build
+type RuntimeStatus struct {
+	// Os buildpacks que participaram do build, na ordem de execução (de 'group.toml').
+	// +kubebuilder:validation:Optional
+	Buildpacks []DetectedBuildpack `json:"buildpacks,omitempty"`
+
+	// Os tipos de processo exportados na imagem (ex: "web", "worker").
+	// +kubebuilder:validation:Optional
+	ProcessTypes []string `json:"processTypes,omitempty"`
+}
+
+// DetectedBuildpack identifica um buildpack e sua versão
+type DetectedBuildpack struct {
+	// O ID do buildpack (ex: "paketo-buildpacks/node-engine").
+	ID string `json:"id"`
+
+	// A versão do buildpack.
+	// +kubebuilder:validation:Optional
+	Version string `json:"version,omitempty"`
+}
+
+// TrafficTargetStatus descreve uma fatia do tráfego servida pela rota do Knative Service
+type TrafficTargetStatus struct {
+	// A revisão Knative que recebe a fatia.
+	// +kubebuilder:validation:Optional
+	RevisionName string `json:"revisionName,omitempty"`
+
+	// Se verdadeiro, a fatia acompanha a revisão mais recente pronta.
+	// +kubebuilder:validation:Optional
+	LatestRevision bool `json:"latestRevision,omitempty"`
+
+	// A tag da fatia, se houver.
+	// +kubebuilder:validation:Optional
+	Tag string `json:"tag,omitempty"`
+
+	// O percentual do tráfego enviado à revisão.
+	Percent int64 `json:"percent"`
+
+	// A URL dedicada da tag, se houver.
+	// +kubebuilder:validation:Optional
+	URL string `json:"url,omitempty"`
+}
+
+// DomainStatus descreve o estado de um domínio customizado
+type DomainStatus struct {
+	// O nome do host.
+	Name string `json:"name"`
+
+	// A URL do domínio, como reportada pelo DomainMapping.
+	// +kubebuilder:validation:Optional
+	URL string `json:"url,omitempty"`
+
+	// Se o DomainMapping está pronto para receber requisições.
+	Ready bool `json:"ready"`
+
+	// O motivo, em CamelCase, quando o domínio não está pronto.
+	// +kubebuilder:validation:Optional
+	Reason string `json:"reason,omitempty"`
+
+	// Detalhes legíveis do estado do domínio.
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+}
+
+// RolloutStatus descreve o andamento de um rollout canário
+type RolloutStatus struct {
+	// A fase do rollout: Progressing, Succeeded ou RolledBack.
+	Phase string `json:"phase"`
+
+	// A imagem (com digest) em rollout.
+	// +kubebuilder:validation:Optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// A revisão que servia o tráfego antes do rollout, e para a qual o rollback retorna.
+	// +kubebuilder:validation:Optional
+	StableRevision string `json:"stableRevision,omitempty"`
+
+	// A revisão nova, que recebe o tráfego do canário.
+	// +kubebuilder:validation:Optional
+	CanaryRevision string `json:"canaryRevision,omitempty"`
+
+	// O índice do passo atual em 'spec.deploy.rollout.steps'.
+	// +kubebuilder:validation:Optional
+	Step int32 `json:"step,omitempty"`
+
+	// O percentual do tráfego enviado à revisão canário.
+	// +kubebuilder:validation:Optional
+	CanaryPercent int64 `json:"canaryPercent,omitempty"`
+
+	// Quando o passo atual começou.
+	// +kubebuilder:validation:Optional
+	StepStartTime *metav1.Time `json:"stepStartTime,omitempty"`
+
+	// O resultado da última análise.
+	// +kubebuilder:validation:Optional
+	Analysis []RolloutMetricResult `json:"analysis,omitempty"`
+
+	// Detalhes sobre a fase, como o motivo de um rollback.
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+}
+
+// RolloutMetricResult descreve o resultado de uma métrica da análise
+type RolloutMetricResult struct {
+	// O nome da métrica.
+	Name string `json:"name"`
+
+	// O valor retornado pela consulta (vazio se não houve dados).
+	// +kubebuilder:validation:Optional
+	Value string `json:"value,omitempty"`
+
+	// Se o valor está dentro do máximo aceito.
+	Passed bool `json:"passed"`
+}
+
+// ImageRewrite descreve uma regra de reescrita pull-through de imagem
+type ImageRewrite struct {
+	// O prefixo da imagem usado no push (ex: "registry.registry.svc.cluster.local:5000").
+	From string `json:"from"`
+
+	// O prefixo pelo qual os nós do cluster fazem o pull (ex: "127.0.0.1:30500").
+	To string `json:"to"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Build",type=string,JSONPath=`.status.build.progress.summary`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Function is the Schema for the functions API.
 type Function struct {
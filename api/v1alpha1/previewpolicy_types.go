@@ -0,0 +1,177 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GitProvider identifies the API used to discover pull requests.
+// +kubebuilder:validation:Enum=github
+type GitProvider string
+
+const (
+	// GitProviderGitHub uses the GitHub REST API (github.com or GitHub Enterprise Server).
+	GitProviderGitHub GitProvider = "github"
+)
+
+// PreviewPolicySpec define como criar um preview de uma Function para cada pull request aberto
+type PreviewPolicySpec struct {
+	// A Function, no mesmo namespace, cujo spec é clonado para cada preview.
+	// Ela deve usar 'gitRepo' como fonte.
+	// +kubebuilder:validation:Required
+	FunctionRef corev1.LocalObjectReference `json:"functionRef"`
+
+	// Opcional. O provedor Git consultado para listar os pull requests abertos.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=github
+	Provider GitProvider `json:"provider,omitempty"`
+
+	// Opcional. O repositório no formato "owner/name".
+	// Padrão: derivado de 'spec.gitRepo' da Function.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`
+	Repository string `json:"repository,omitempty"`
+
+	// Opcional. A URL base da API do provedor (ex: "https://github.example.com/api/v3").
+	// Padrão: "https://api.github.com".
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^https?://`
+	APIURL string `json:"apiURL,omitempty"`
+
+	// Opcional. Secret no namespace da política com a chave 'token', usada para autenticar na API.
+	// Necessário para repositórios privados e para publicar a URL do preview no pull request.
+	// +kubebuilder:validation:Optional
+	TokenSecretRef *corev1.LocalObjectReference `json:"tokenSecretRef,omitempty"`
+
+	// Opcional. Só cria previews para pull requests com este branch de destino (ex: "main").
+	// +kubebuilder:validation:Optional
+	BaseBranch string `json:"baseBranch,omitempty"`
+
+	// Opcional. Label que autoriza o preview de um pull request vindo de um fork (ex: "safe-to-preview").
+	// Sem ele, pull requests de forks não recebem preview, pois qualquer pessoa pode abri-los.
+	// Só quem tem acesso de escrita no repositório pode aplicar labels.
+	// +kubebuilder:validation:Optional
+	ForkLabel string `json:"forkLabel,omitempty"`
+
+	// Opcional. Por quanto tempo um preview fica no ar após o último commit do pull request.
+	// Um novo commit recria o preview expirado. Sem TTL, o preview dura até o pull request ser fechado.
+	// +kubebuilder:validation:Optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// Opcional. Intervalo entre as consultas ao provedor.
+	// Padrão: 1m.
+	// +kubebuilder:validation:Optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+
+	// Opcional. Número máximo de previews simultâneos; os pull requests mais recentes têm prioridade.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=50
+	MaxPreviews int32 `json:"maxPreviews,omitempty"`
+}
+
+// PreviewStatus descreve o preview de um pull request
+type PreviewStatus struct {
+	// O número do pull request.
+	Number int64 `json:"number"`
+
+	// O branch de origem do pull request.
+	// +kubebuilder:validation:Optional
+	HeadRef string `json:"headRef,omitempty"`
+
+	// O commit implantado no preview.
+	HeadSHA string `json:"headSHA"`
+
+	// A Function criada para o preview.
+	FunctionName string `json:"functionName"`
+
+	// A URL do preview, quando a Function está pronta.
+	// +kubebuilder:validation:Optional
+	URL string `json:"url,omitempty"`
+
+	// Se a Function do preview está pronta.
+	Ready bool `json:"ready"`
+
+	// Opcional. Motivo de o preview não ter sido criado (ex: "FunctionConflict").
+	// +kubebuilder:validation:Optional
+	Reason string `json:"reason,omitempty"`
+
+	// Opcional. Detalhes do motivo.
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+
+	// Quando o commit atual foi implantado no preview; base do TTL.
+	DeployedAt metav1.Time `json:"deployedAt"`
+
+	// Se o preview foi removido por ter excedido o TTL.
+	// +kubebuilder:validation:Optional
+	Expired bool `json:"expired,omitempty"`
+
+	// O commit cuja URL já foi publicada no provedor.
+	// +kubebuilder:validation:Optional
+	PublishedSHA string `json:"publishedSHA,omitempty"`
+}
+
+// PreviewPolicyStatus define o estado observado de PreviewPolicy
+type PreviewPolicyStatus struct {
+	// Condições da política, seguindo as convenções de API do Kubernetes.
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// Os previews dos pull requests abertos.
+	// +kubebuilder:validation:Optional
+	Previews []PreviewStatus `json:"previews,omitempty"`
+
+	// Quando o provedor foi consultado com sucesso pela última vez.
+	// +kubebuilder:validation:Optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// O 'generation' observado do spec.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Function",type=string,JSONPath=`.spec.functionRef.name`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PreviewPolicy is the Schema for the previewpolicies API.
+// It deploys a temporary copy of a Function for each open pull request of its repository.
+type PreviewPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PreviewPolicySpec   `json:"spec,omitempty"`
+	Status PreviewPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PreviewPolicyList contains a list of PreviewPolicy.
+type PreviewPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PreviewPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PreviewPolicy{}, &PreviewPolicyList{})
+}
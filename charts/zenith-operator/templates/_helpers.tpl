@@ -60,3 +60,14 @@ Create the name of the service account to use
 {{- default "default" .Values.operator.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Render the image pull-through rewrite rules as "from=to,from=to"
+*/}}
+{{- define "zenith-operator.imagePullRewrites" -}}
+{{- $rules := list }}
+{{- range .Values.operator.controller.imagePullRewrites }}
+{{- $rules = append $rules (printf "%s=%s" .from .to) }}
+{{- end }}
+{{- join "," $rules }}
+{{- end }}
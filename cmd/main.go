@@ -17,10 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -42,6 +44,7 @@ import (
 	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	kneventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
 	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	knservingv1beta1 "knative.dev/serving/pkg/apis/serving/v1beta1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -54,6 +57,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(tektonv1.AddToScheme(scheme))
 	utilruntime.Must(knservingv1.AddToScheme(scheme))
+	utilruntime.Must(knservingv1beta1.AddToScheme(scheme))
 	utilruntime.Must(kneventingv1.AddToScheme(scheme))
 	utilruntime.Must(functionsv1alpha1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
@@ -68,6 +72,11 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var maxConcurrentBuilds, maxConcurrentBuildsPerNamespace int
+	var prometheusURL string
+	var allowPrometheusURLOverride bool
+	var runtimePermissionsAllowlist string
+	var allowedS3Endpoints string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -86,6 +95,21 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.IntVar(&maxConcurrentBuilds, "max-concurrent-builds", 0,
+		"Maximum number of Function builds (PipelineRuns) running at the same time across the cluster. 0 means unlimited.")
+	flag.IntVar(&maxConcurrentBuildsPerNamespace, "max-concurrent-builds-per-namespace", 0,
+		"Maximum number of Function builds (PipelineRuns) running at the same time in a single namespace. 0 means unlimited.")
+	flag.StringVar(&prometheusURL, "prometheus-url", "",
+		"Prometheus-compatible API queried by the analysis of canary rollouts (spec.deploy.rollout.analysis).")
+	flag.BoolVar(&allowPrometheusURLOverride, "allow-prometheus-url-override", false,
+		"If set, Functions may query another Prometheus through spec.deploy.rollout.analysis.prometheusURL.")
+	flag.StringVar(&allowedS3Endpoints, "allowed-s3-endpoints", "",
+		"Comma-separated S3-compatible endpoints (scheme, host and port) Functions may use in spec.source.s3.endpoint. "+
+			"Empty allows only AWS S3.")
+	flag.StringVar(&runtimePermissionsAllowlist, "runtime-permissions-allowlist", "",
+		"Resources and verbs Functions may grant through spec.deploy.permissions, as comma-separated "+
+			"resource[.group]=verb|verb entries (e.g. configmaps=get|list,jobs.batch=get|create). "+
+			"Empty keeps the default read-only allowlist.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -94,6 +118,16 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	var permissionAllowlist controller.PermissionAllowlist
+	if runtimePermissionsAllowlist != "" {
+		allowlist, err := controller.ParsePermissionAllowlist(runtimePermissionsAllowlist)
+		if err != nil {
+			setupLog.Error(err, "invalid --runtime-permissions-allowlist")
+			os.Exit(1)
+		}
+		permissionAllowlist = allowlist
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -207,11 +241,42 @@ func main() {
 		os.Exit(1)
 	}
 
+	var buildQueue *controller.BuildQueue
+	var s3Endpoints []string
+	if allowedS3Endpoints != "" {
+		s3Endpoints = strings.Split(allowedS3Endpoints, ",")
+	}
+
+	if maxConcurrentBuilds > 0 || maxConcurrentBuildsPerNamespace > 0 {
+		setupLog.Info("Build admission queue enabled",
+			"max-concurrent-builds", maxConcurrentBuilds,
+			"max-concurrent-builds-per-namespace", maxConcurrentBuildsPerNamespace)
+		buildQueue = controller.NewBuildQueue(maxConcurrentBuilds, maxConcurrentBuildsPerNamespace)
+		if err := buildQueue.Restore(context.Background(), mgr.GetAPIReader()); err != nil {
+			setupLog.Error(err, "unable to restore the running builds, they will be registered on their next reconcile")
+		} else {
+			setupLog.Info("Running builds restored", "running", buildQueue.Running())
+		}
+	}
+
 	if err := (&controller.FunctionReconciler{
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		BuildQueue: buildQueue,
+
+		PrometheusURL:              prometheusURL,
+		AllowPrometheusURLOverride: allowPrometheusURLOverride,
+		AllowedS3Endpoints:         s3Endpoints,
+		PermissionAllowlist:        permissionAllowlist,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Function")
+		os.Exit(1)
+	}
+	if err := (&controller.PreviewPolicyReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Function")
+		setupLog.Error(err, "unable to create controller", "controller", "PreviewPolicy")
 		os.Exit(1)
 	}
 	// +kubebuilder:scaffold:builder
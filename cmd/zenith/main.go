@@ -0,0 +1,185 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command zenith is a client-side companion of the operator.
+//
+//	zenith render -f function.yaml [--image-digest sha256:...] [--namespace default]
+//
+// prints the Tekton Tasks, PipelineRun, Knative Service and Trigger the operator would create
+// for the Functions in the file, without contacting a cluster.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+	"github.com/lucasgois1/zenith-operator/internal/controller"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func usage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, `Usage:
+  zenith render -f <function.yaml|-> [--image-digest sha256:...] [--namespace <namespace>]
+               [--runtime-permissions-allowlist <resource[.group]=verb|verb,...>]
+
+Commands:
+  render   Print the resources the operator generates for a Function, without contacting a cluster`)
+}
+
+// run executes the command line and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	switch args[0] {
+	case "render":
+		if err := render(args[1:], stdin, stdout, stderr); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			_, _ = fmt.Fprintln(stderr, "error:", err)
+			return 1
+		}
+		return 0
+	default:
+		_, _ = fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+}
+
+func render(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var file, namespace, imageDigest, permissionsAllowlist string
+	flags.StringVar(&file, "f", "", "Function manifest to render (\"-\" reads from stdin)")
+	flags.StringVar(&namespace, "namespace", "", "Namespace for Functions without metadata.namespace (default \"default\")")
+	flags.StringVar(&imageDigest, "image-digest", controller.DefaultRenderImageDigest,
+		"Image digest used in place of a build result")
+	flags.StringVar(&permissionsAllowlist, "runtime-permissions-allowlist", "",
+		"Operator allowlist for spec.deploy.permissions (default: the operator's read-only allowlist)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if file == "" {
+		return fmt.Errorf("-f is required")
+	}
+
+	var allowlist controller.PermissionAllowlist
+	if permissionsAllowlist != "" {
+		parsed, err := controller.ParsePermissionAllowlist(permissionsAllowlist)
+		if err != nil {
+			return fmt.Errorf("--runtime-permissions-allowlist: %w", err)
+		}
+		allowlist = parsed
+	}
+
+	var input io.Reader = stdin
+	if file != "-" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		input = bytes.NewReader(content)
+	}
+
+	functions, err := readFunctions(input, stderr)
+	if err != nil {
+		return err
+	}
+	if len(functions) == 0 {
+		return fmt.Errorf("no Function found in %s", file)
+	}
+
+	var objects []client.Object
+	for i := range functions {
+		rendered, err := controller.RenderFunction(&functions[i], controller.RenderOptions{
+			ImageDigest:         imageDigest,
+			Namespace:           namespace,
+			PermissionAllowlist: allowlist,
+		})
+		if err != nil {
+			return err
+		}
+		objects = append(objects, rendered...)
+	}
+
+	output, err := controller.RenderYAML(objects)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(output)
+	return err
+}
+
+// readFunctions decodes the Functions of a multi-document YAML stream. Unknown fields are rejected,
+// and documents of other kinds are skipped with a warning.
+func readFunctions(input io.Reader, stderr io.Writer) ([]functionsv1alpha1.Function, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(input))
+
+	var functions []functionsv1alpha1.Function
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return functions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(strings.TrimSpace(string(document))) == 0 {
+			continue
+		}
+
+		var typeMeta struct {
+			Kind string `json:"kind"`
+		}
+		if err := yaml.Unmarshal(document, &typeMeta); err != nil {
+			return nil, err
+		}
+		if typeMeta.Kind == "" {
+			continue
+		}
+		if typeMeta.Kind != "Function" {
+			_, _ = fmt.Fprintf(stderr, "warning: skipping %s document\n", typeMeta.Kind)
+			continue
+		}
+
+		var function functionsv1alpha1.Function
+		if err := yaml.UnmarshalStrict(document, &function); err != nil {
+			return nil, fmt.Errorf("invalid Function: %w", err)
+		}
+		functions = append(functions, function)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// clearOperatorEnv removes the operator settings read from the environment, so the output only
+// depends on the input manifest.
+func clearOperatorEnv(t *testing.T) {
+	for _, name := range []string{"INSECURE_REGISTRIES", "IMAGE_PULL_REWRITES", "BUILD_POD_TEMPLATE"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	clearOperatorEnv(t)
+
+	for _, name := range []string{"git-function", "inline-eventing"} {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"render", "-f", filepath.Join("testdata", name+".yaml"),
+				"--image-digest", "sha256:1111111111111111111111111111111111111111111111111111111111111111"},
+				nil, &stdout, &stderr)
+			g.Expect(code).To(Equal(0), stderr.String())
+
+			golden := filepath.Join("testdata", name+".golden.yaml")
+			if *update {
+				g.Expect(os.WriteFile(golden, stdout.Bytes(), 0o644)).To(Succeed())
+			}
+			expected, err := os.ReadFile(golden)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(stdout.String()).To(Equal(string(expected)), "run 'go test ./cmd/zenith -update' to refresh %s", golden)
+		})
+	}
+}
+
+func TestRenderFromStdin(t *testing.T) {
+	clearOperatorEnv(t)
+	g := NewWithT(t)
+
+	input, err := os.ReadFile(filepath.Join("testdata", "git-function.yaml"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"render", "-f", "-"}, bytes.NewReader(input), &stdout, &stderr)
+	g.Expect(code).To(Equal(0), stderr.String())
+	g.Expect(stdout.String()).To(ContainSubstring("image: registry.example.com/hello@sha256:0000000000000000"))
+	g.Expect(strings.Count(stdout.String(), "\n---\n")).To(Equal(4), "git-clone Task, buildpacks Task, runtime ServiceAccount, PipelineRun and Service")
+}
+
+func TestRenderErrors(t *testing.T) {
+	clearOperatorEnv(t)
+
+	tests := []struct {
+		name    string
+		args    []string
+		input   string
+		code    int
+		message string
+	}{
+		{
+			name:    "missing file flag",
+			args:    []string{"render"},
+			code:    1,
+			message: "-f is required",
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"deploy"},
+			code:    2,
+			message: `unknown command "deploy"`,
+		},
+		{
+			name:    "no Function in input",
+			args:    []string{"render", "-f", "-"},
+			input:   "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n",
+			code:    1,
+			message: "no Function found",
+		},
+		{
+			name:    "unknown field",
+			args:    []string{"render", "-f", "-"},
+			input:   "apiVersion: functions.zenith.com/v1alpha1\nkind: Function\nmetadata:\n  name: fn\nspec:\n  gitRepo: https://github.com/example/fn\n  build:\n    imagee: registry.example.com/fn\n",
+			code:    1,
+			message: `unknown field "imagee"`,
+		},
+		{
+			name:    "invalid digest",
+			args:    []string{"render", "-f", "-", "--image-digest", "latest"},
+			input:   "apiVersion: functions.zenith.com/v1alpha1\nkind: Function\nmetadata:\n  name: fn\nspec:\n  gitRepo: https://github.com/example/fn\n  build:\n    image: registry.example.com/fn\n",
+			code:    1,
+			message: "digest de imagem inválido",
+		},
+		{
+			name:    "OCI source without a digest",
+			args:    []string{"render", "-f", "-"},
+			input:   "apiVersion: functions.zenith.com/v1alpha1\nkind: Function\nmetadata:\n  name: fn\nspec:\n  source:\n    oci:\n      image: registry.example.com/fn-src:dev\n  build:\n    image: registry.example.com/fn\n",
+			code:    1,
+			message: "precisa ser fixada por digest",
+		},
+		{
+			name:    "permission outside the allowlist",
+			args:    []string{"render", "-f", "-"},
+			input:   "apiVersion: functions.zenith.com/v1alpha1\nkind: Function\nmetadata:\n  name: fn\nspec:\n  gitRepo: https://github.com/example/fn\n  build:\n    image: registry.example.com/fn\n  deploy:\n    permissions:\n      - apiGroups: [batch]\n        resources: [jobs]\n        verbs: [create]\n",
+			code:    1,
+			message: `o operator não permite conceder "create" em "jobs.batch"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.input), &stdout, &stderr)
+			g.Expect(code).To(Equal(tt.code))
+			g.Expect(stderr.String()).To(ContainSubstring(tt.message))
+			g.Expect(stdout.String()).To(BeEmpty())
+		})
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// autoscalingAnnotationPrefix is the prefix of the Knative autoscaling revision annotations
+const autoscalingAnnotationPrefix = "autoscaling.knative.dev/"
+
+// Bounds Knative accepts for the autoscaling windows
+const (
+	minAutoscalingWindow     = 6 * time.Second
+	maxAutoscalingWindow     = time.Hour
+	maxAutoscalingScaleDelay = time.Hour
+)
+
+// autoscalingClasses maps the Function classes to the Knative class annotation values
+var autoscalingClasses = map[functionsv1alpha1.AutoscalingClass]string{
+	functionsv1alpha1.AutoscalingClassKPA: "kpa.autoscaling.knative.dev",
+	functionsv1alpha1.AutoscalingClassHPA: "hpa.autoscaling.knative.dev",
+}
+
+/*
+validateScale verifica as regras de 'spec.deploy.scale' que a validação do CRD não expressa:
+a combinação de classe e métrica, as opções exclusivas do KPA e a coerência entre os limites de réplicas.
+*/
+func validateScale(function *functionsv1alpha1.Function) error {
+	scale := function.Spec.Deploy.Scale
+	if scale == nil {
+		return nil
+	}
+
+	hpa := scale.Class == functionsv1alpha1.AutoscalingClassHPA
+	switch scale.Metric {
+	case functionsv1alpha1.AutoscalingMetricCPU, functionsv1alpha1.AutoscalingMetricMemory:
+		if !hpa {
+			return fmt.Errorf("spec.deploy.scale: a métrica %q exige class: hpa", scale.Metric)
+		}
+	case functionsv1alpha1.AutoscalingMetricConcurrency, functionsv1alpha1.AutoscalingMetricRPS:
+		if hpa {
+			return fmt.Errorf("spec.deploy.scale: a métrica %q exige class: kpa", scale.Metric)
+		}
+	case "":
+		if hpa {
+			return fmt.Errorf("spec.deploy.scale: class hpa exige metric cpu ou memory")
+		}
+	}
+
+	if hpa {
+		if scale.Window != nil || scale.PanicWindowPercentage != nil || scale.ActivationScale != nil {
+			return fmt.Errorf("spec.deploy.scale: window, panicWindowPercentage e activationScale só são suportados com class kpa")
+		}
+		if scale.MinScale != nil && *scale.MinScale == 0 {
+			return fmt.Errorf("spec.deploy.scale: class hpa não suporta scale-to-zero (minScale: 0)")
+		}
+	}
+
+	if scale.Window != nil && (scale.Window.Duration < minAutoscalingWindow || scale.Window.Duration > maxAutoscalingWindow) {
+		return fmt.Errorf("spec.deploy.scale.window: %s fora do intervalo permitido (%s a %s)", scale.Window.Duration, minAutoscalingWindow, maxAutoscalingWindow)
+	}
+	if scale.ScaleDownDelay != nil && (scale.ScaleDownDelay.Duration < 0 || scale.ScaleDownDelay.Duration > maxAutoscalingScaleDelay) {
+		return fmt.Errorf("spec.deploy.scale.scaleDownDelay: %s fora do intervalo permitido (0s a %s)", scale.ScaleDownDelay.Duration, maxAutoscalingScaleDelay)
+	}
+
+	if scale.MaxScale != nil && *scale.MaxScale > 0 {
+		maxScale := *scale.MaxScale
+		for _, bound := range []struct {
+			field string
+			value *int32
+		}{
+			{"minScale", scale.MinScale},
+			{"initialScale", scale.InitialScale},
+			{"activationScale", scale.ActivationScale},
+		} {
+			if bound.value != nil && *bound.value > maxScale {
+				return fmt.Errorf("spec.deploy.scale: %s (%d) não pode ser maior que maxScale (%d)", bound.field, *bound.value, maxScale)
+			}
+		}
+	}
+	return nil
+}
+
+// autoscalingAnnotations translates spec.deploy.scale into the autoscaling.knative.dev/* revision annotations.
+func autoscalingAnnotations(scale *functionsv1alpha1.ScaleSpec) map[string]string {
+	annotations := map[string]string{}
+	if scale == nil {
+		return annotations
+	}
+
+	setInt := func(name string, value *int32) {
+		if value != nil {
+			annotations[autoscalingAnnotationPrefix+name] = strconv.Itoa(int(*value))
+		}
+	}
+	setInt("min-scale", scale.MinScale)
+	setInt("max-scale", scale.MaxScale)
+	setInt("target", scale.Target)
+	setInt("target-utilization-percentage", scale.TargetUtilizationPercentage)
+	setInt("initial-scale", scale.InitialScale)
+	setInt("panic-window-percentage", scale.PanicWindowPercentage)
+	setInt("activation-scale", scale.ActivationScale)
+
+	if class, found := autoscalingClasses[scale.Class]; found {
+		annotations[autoscalingAnnotationPrefix+"class"] = class
+	}
+	if scale.Metric != "" {
+		annotations[autoscalingAnnotationPrefix+"metric"] = string(scale.Metric)
+	}
+	if scale.ScaleDownDelay != nil {
+		annotations[autoscalingAnnotationPrefix+"scale-down-delay"] = scale.ScaleDownDelay.Duration.String()
+	}
+	if scale.Window != nil {
+		annotations[autoscalingAnnotationPrefix+"window"] = scale.Window.Duration.String()
+	}
+	return annotations
+}
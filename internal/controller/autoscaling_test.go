@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestValidateScale(t *testing.T) {
+	tests := []struct {
+		name    string
+		scale   *functionsv1alpha1.ScaleSpec
+		wantErr string
+	}{
+		{name: "no scale"},
+		{
+			name: "kpa on rps",
+			scale: &functionsv1alpha1.ScaleSpec{
+				Metric: functionsv1alpha1.AutoscalingMetricRPS, Target: int32Ptr(150),
+				Window: &metav1.Duration{Duration: 30 * time.Second}, PanicWindowPercentage: int32Ptr(10),
+			},
+		},
+		{
+			name: "hpa on cpu",
+			scale: &functionsv1alpha1.ScaleSpec{
+				Class: functionsv1alpha1.AutoscalingClassHPA, Metric: functionsv1alpha1.AutoscalingMetricCPU,
+				Target: int32Ptr(500), MinScale: int32Ptr(1),
+			},
+		},
+		{
+			name:    "cpu without hpa",
+			scale:   &functionsv1alpha1.ScaleSpec{Metric: functionsv1alpha1.AutoscalingMetricCPU},
+			wantErr: `"cpu" exige class: hpa`,
+		},
+		{
+			name:    "hpa on concurrency",
+			scale:   &functionsv1alpha1.ScaleSpec{Class: functionsv1alpha1.AutoscalingClassHPA, Metric: functionsv1alpha1.AutoscalingMetricConcurrency},
+			wantErr: `"concurrency" exige class: kpa`,
+		},
+		{
+			name:    "hpa without metric",
+			scale:   &functionsv1alpha1.ScaleSpec{Class: functionsv1alpha1.AutoscalingClassHPA},
+			wantErr: "exige metric cpu ou memory",
+		},
+		{
+			name: "hpa with kpa-only window",
+			scale: &functionsv1alpha1.ScaleSpec{
+				Class: functionsv1alpha1.AutoscalingClassHPA, Metric: functionsv1alpha1.AutoscalingMetricMemory,
+				Window: &metav1.Duration{Duration: time.Minute},
+			},
+			wantErr: "só são suportados com class kpa",
+		},
+		{
+			name: "hpa scale to zero",
+			scale: &functionsv1alpha1.ScaleSpec{
+				Class: functionsv1alpha1.AutoscalingClassHPA, Metric: functionsv1alpha1.AutoscalingMetricMemory, MinScale: int32Ptr(0),
+			},
+			wantErr: "não suporta scale-to-zero",
+		},
+		{
+			name:    "window too short",
+			scale:   &functionsv1alpha1.ScaleSpec{Window: &metav1.Duration{Duration: time.Second}},
+			wantErr: "window: 1s fora do intervalo",
+		},
+		{
+			name:    "scale down delay too long",
+			scale:   &functionsv1alpha1.ScaleSpec{ScaleDownDelay: &metav1.Duration{Duration: 2 * time.Hour}},
+			wantErr: "scaleDownDelay: 2h0m0s fora do intervalo",
+		},
+		{
+			name:    "min above max",
+			scale:   &functionsv1alpha1.ScaleSpec{MinScale: int32Ptr(5), MaxScale: int32Ptr(3)},
+			wantErr: "minScale (5) não pode ser maior que maxScale (3)",
+		},
+		{
+			name:  "unbounded max",
+			scale: &functionsv1alpha1.ScaleSpec{InitialScale: int32Ptr(5), MaxScale: int32Ptr(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{
+				Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Scale: tt.scale}},
+			}
+			err := validateScale(function)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestAutoscalingAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(autoscalingAnnotations(nil)).To(BeEmpty())
+	g.Expect(autoscalingAnnotations(&functionsv1alpha1.ScaleSpec{
+		MinScale:                    int32Ptr(1),
+		MaxScale:                    int32Ptr(20),
+		Class:                       functionsv1alpha1.AutoscalingClassKPA,
+		Metric:                      functionsv1alpha1.AutoscalingMetricConcurrency,
+		Target:                      int32Ptr(10),
+		TargetUtilizationPercentage: int32Ptr(80),
+		InitialScale:                int32Ptr(2),
+		ScaleDownDelay:              &metav1.Duration{Duration: 5 * time.Minute},
+		Window:                      &metav1.Duration{Duration: 30 * time.Second},
+		PanicWindowPercentage:       int32Ptr(20),
+		ActivationScale:             int32Ptr(3),
+	})).To(Equal(map[string]string{
+		"autoscaling.knative.dev/min-scale":                     "1",
+		"autoscaling.knative.dev/max-scale":                     "20",
+		"autoscaling.knative.dev/class":                         "kpa.autoscaling.knative.dev",
+		"autoscaling.knative.dev/metric":                        "concurrency",
+		"autoscaling.knative.dev/target":                        "10",
+		"autoscaling.knative.dev/target-utilization-percentage": "80",
+		"autoscaling.knative.dev/initial-scale":                 "2",
+		"autoscaling.knative.dev/scale-down-delay":              "5m0s",
+		"autoscaling.knative.dev/window":                        "30s",
+		"autoscaling.knative.dev/panic-window-percentage":       "20",
+		"autoscaling.knative.dev/activation-scale":              "3",
+	}))
+}
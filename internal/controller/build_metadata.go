@@ -0,0 +1,106 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+const (
+	// BuildMetadataResult is the buildpacks-phases result holding the detected buildpacks and process types (JSON)
+	BuildMetadataResult = "BUILD_METADATA"
+
+	// buildPipelineTaskName is the name of the Buildpacks task in the embedded pipeline
+	buildPipelineTaskName = "build-and-push"
+)
+
+// buildMetadata is the payload written by the "results" step of the buildpacks-phases Task.
+type buildMetadata struct {
+	Buildpacks []struct {
+		ID      string `json:"id"`
+		Version string `json:"version"`
+	} `json:"buildpacks"`
+	Processes []string `json:"processes"`
+}
+
+// parseBuildMetadata converts the BUILD_METADATA result into status.runtime.
+// Returns nil for an empty or malformed payload.
+func parseBuildMetadata(raw string) *functionsv1alpha1.RuntimeStatus {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+
+	var metadata buildMetadata
+	if err := json.Unmarshal([]byte(raw), &metadata); err != nil {
+		return nil
+	}
+	if len(metadata.Buildpacks) == 0 && len(metadata.Processes) == 0 {
+		return nil
+	}
+
+	runtime := &functionsv1alpha1.RuntimeStatus{}
+	for _, buildpack := range metadata.Buildpacks {
+		if buildpack.ID == "" {
+			continue
+		}
+		runtime.Buildpacks = append(runtime.Buildpacks, functionsv1alpha1.DetectedBuildpack{
+			ID:      buildpack.ID,
+			Version: buildpack.Version,
+		})
+	}
+	for _, process := range metadata.Processes {
+		if process != "" {
+			runtime.ProcessTypes = append(runtime.ProcessTypes, process)
+		}
+	}
+	return runtime
+}
+
+/*
+buildRuntimeStatus lê o resultado BUILD_METADATA do TaskRun 'build-and-push' de um PipelineRun concluído.
+Retorna nil quando o TaskRun não existe (ex: Pipelines do usuário via 'pipelineRef')
+ou foi criado por uma versão da Task que não publica o resultado.
+*/
+func (r *FunctionReconciler) buildRuntimeStatus(ctx context.Context, pipelineRun *tektonv1.PipelineRun) *functionsv1alpha1.RuntimeStatus {
+	for _, childRef := range pipelineRun.Status.ChildReferences {
+		if childRef.Kind != "TaskRun" || childRef.PipelineTaskName != buildPipelineTaskName {
+			continue
+		}
+
+		taskRun := &tektonv1.TaskRun{}
+		if err := r.Get(ctx, types.NamespacedName{Name: childRef.Name, Namespace: pipelineRun.Namespace}, taskRun); err != nil {
+			logf.FromContext(ctx).V(1).Info("TaskRun de build não encontrado para extrair metadados", "TaskRun.Name", childRef.Name, "error", err.Error())
+			return nil
+		}
+		for _, result := range taskRun.Status.Results {
+			if result.Name == BuildMetadataResult {
+				return parseBuildMetadata(result.Value.StringVal)
+			}
+		}
+		return nil
+	}
+	return nil
+}
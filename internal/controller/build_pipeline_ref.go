@@ -0,0 +1,205 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// Contract between the operator and a user-provided Pipeline (spec.build.pipelineRef).
+const (
+	// PipelineParamURL is the source repository URL
+	PipelineParamURL = "url"
+	// PipelineParamRevision is the source revision (branch, tag or commit)
+	PipelineParamRevision = "revision"
+	// PipelineParamImage is the target image, without digest
+	PipelineParamImage = "image"
+	// PipelineParamSubpath is the directory of the function inside the source (optional for the Pipeline)
+	PipelineParamSubpath = "subpath"
+	// PipelineWorkspaceSource is the workspace bound by the operator for the source
+	PipelineWorkspaceSource = "source"
+	// PipelineResultImageDigest is the result holding the digest of the pushed image
+	PipelineResultImageDigest = "APP_IMAGE_DIGEST"
+)
+
+// requiredPipelineParams are the contract params a Pipeline must declare
+var requiredPipelineParams = []string{PipelineParamURL, PipelineParamRevision, PipelineParamImage}
+
+/*
+validatePipelineContract verifica se o Pipeline declarado pelo usuário segue o contrato do operator:
+  - parâmetros 'url', 'revision' e 'image' declarados como string ('subpath' é opcional);
+  - parâmetros extras de 'pipelineRef.params' declarados pelo Pipeline;
+  - resultado 'APP_IMAGE_DIGEST' declarado;
+  - nenhum workspace obrigatório além de 'source', que é o único vinculado pelo operator.
+*/
+func validatePipelineContract(spec *tektonv1.PipelineSpec, ref *functionsv1alpha1.BuildPipelineRef) error {
+	declared := make(map[string]tektonv1.ParamType, len(spec.Params))
+	for _, param := range spec.Params {
+		declared[param.Name] = param.Type
+	}
+
+	var problems []string
+	for _, name := range requiredPipelineParams {
+		paramType, ok := declared[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("parâmetro '%s' não declarado", name))
+			continue
+		}
+		if paramType != "" && paramType != tektonv1.ParamTypeString {
+			problems = append(problems, fmt.Sprintf("parâmetro '%s' deve ser do tipo string", name))
+		}
+	}
+	if paramType, ok := declared[PipelineParamSubpath]; ok && paramType != "" && paramType != tektonv1.ParamTypeString {
+		problems = append(problems, fmt.Sprintf("parâmetro '%s' deve ser do tipo string", PipelineParamSubpath))
+	}
+	for _, param := range ref.Params {
+		if _, ok := declared[param.Name]; !ok {
+			problems = append(problems, fmt.Sprintf("parâmetro '%s' de pipelineRef.params não declarado", param.Name))
+		}
+	}
+
+	hasDigestResult := false
+	for _, result := range spec.Results {
+		if result.Name == PipelineResultImageDigest {
+			hasDigestResult = true
+			break
+		}
+	}
+	if !hasDigestResult {
+		problems = append(problems, fmt.Sprintf("resultado '%s' não declarado", PipelineResultImageDigest))
+	}
+
+	for _, workspace := range spec.Workspaces {
+		if workspace.Name != PipelineWorkspaceSource && !workspace.Optional {
+			problems = append(problems, fmt.Sprintf("workspace obrigatório '%s' não é suportado (apenas '%s')", workspace.Name, PipelineWorkspaceSource))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("o Pipeline não segue o contrato do operator: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// pipelineRefParams returns the contract params followed by the user's extra params.
+func pipelineRefParams(function *functionsv1alpha1.Function, gitRevision string) []tektonv1.Param {
+	stringParam := func(name, value string) tektonv1.Param {
+		return tektonv1.Param{Name: name, Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: value}}
+	}
+
+	params := []tektonv1.Param{
+		stringParam(PipelineParamURL, function.Spec.GitRepo),
+		stringParam(PipelineParamRevision, gitRevision),
+		stringParam(PipelineParamImage, function.Spec.Build.Image),
+		stringParam(PipelineParamSubpath, function.Spec.Build.SourceSubpath),
+	}
+	for _, param := range function.Spec.Build.PipelineRef.Params {
+		params = append(params, stringParam(param.Name, param.Value))
+	}
+	return params
+}
+
+// tektonPipelineRef converts spec.build.pipelineRef into a Tekton PipelineRef.
+func tektonPipelineRef(ref *functionsv1alpha1.BuildPipelineRef) *tektonv1.PipelineRef {
+	if ref.Resolver == "" {
+		return &tektonv1.PipelineRef{Name: ref.Name}
+	}
+
+	params := make(tektonv1.Params, 0, len(ref.ResolverParams))
+	for _, param := range ref.ResolverParams {
+		params = append(params, tektonv1.Param{
+			Name:  param.Name,
+			Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: param.Value},
+		})
+	}
+	return &tektonv1.PipelineRef{
+		ResolverRef: tektonv1.ResolverRef{
+			Resolver: tektonv1.ResolverName(ref.Resolver),
+			Params:   params,
+		},
+	}
+}
+
+/*
+validatePipelineRef valida um 'spec.build.pipelineRef' local (por nome) antes de criar o PipelineRun.
+Referências via resolver só podem ser validadas depois que o Tekton resolve o Pipeline;
+nesse caso a validação acontece em checkResolvedPipeline.
+Como mudanças no Pipeline não disparam reconciliação da Function, falhas são reavaliadas a cada 30s.
+*/
+func (r *FunctionReconciler) validatePipelineRef(ctx context.Context, function *functionsv1alpha1.Function) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	ref := function.Spec.Build.PipelineRef
+	if ref == nil || ref.Name == "" {
+		return ctrl.Result{}, nil
+	}
+
+	pipeline := &tektonv1.Pipeline{}
+	err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: function.Namespace}, pipeline)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Falha ao verificar Pipeline")
+		return ctrl.Result{}, err
+	}
+
+	var message string
+	if errors.IsNotFound(err) {
+		message = fmt.Sprintf("Pipeline não encontrado: %s", ref.Name)
+	} else if contractErr := validatePipelineContract(&pipeline.Spec, ref); contractErr != nil {
+		message = fmt.Sprintf("Pipeline '%s' inválido: %v", ref.Name, contractErr)
+	} else {
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("Pipeline de build inválido", "Pipeline.Name", ref.Name, "Message", message)
+	condition := metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  "InvalidPipeline",
+		Message: message,
+	}
+	meta.SetStatusCondition(&function.Status.Conditions, condition)
+	function.Status.ObservedGeneration = function.Generation
+	if err := r.Status().Update(ctx, function); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+}
+
+/*
+checkResolvedPipeline valida o Pipeline resolvido pelo Tekton (registrado em status.pipelineSpec)
+para PipelineRuns criados a partir de um 'spec.build.pipelineRef'.
+Retorna o erro de contrato, ou nil quando o Pipeline é válido ou ainda não foi resolvido.
+*/
+func checkResolvedPipeline(function *functionsv1alpha1.Function, pipelineRun *tektonv1.PipelineRun) error {
+	if function.Spec.Build.PipelineRef == nil || pipelineRun.Status.PipelineSpec == nil {
+		return nil
+	}
+	return validatePipelineContract(pipelineRun.Status.PipelineSpec, function.Spec.Build.PipelineRef)
+}
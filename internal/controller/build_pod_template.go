@@ -0,0 +1,96 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/pod"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// buildPodTemplateEnv is the environment variable holding the cluster default pod template
+// for build pods, as JSON using the same schema as spec.build.podTemplate.
+// Example: {"nodeSelector":{"node-role/build":"true"},"priorityClassName":"builds"}
+const buildPodTemplateEnv = "BUILD_POD_TEMPLATE"
+
+// defaultBuildPodTemplate reads the cluster default pod template for build pods.
+// A malformed value is logged and ignored so a bad configuration never blocks builds.
+func defaultBuildPodTemplate() *functionsv1alpha1.BuildPodTemplate {
+	raw := os.Getenv(buildPodTemplateEnv)
+	if raw == "" {
+		return nil
+	}
+
+	template := &functionsv1alpha1.BuildPodTemplate{}
+	if err := json.Unmarshal([]byte(raw), template); err != nil {
+		logf.Log.WithName("build-pod-template").Error(err, "Ignorando template padrão de pod de build inválido", "env", buildPodTemplateEnv)
+		return nil
+	}
+	return template
+}
+
+/*
+buildPipelinePodTemplate combina o template padrão do cluster com o 'spec.build.podTemplate'
+da Function e o converte para o PodTemplate do Tekton.
+Cada campo definido na Function substitui por inteiro o campo correspondente do padrão.
+Retorna nil quando nenhum dos dois define controles de agendamento.
+*/
+func buildPipelinePodTemplate(function *functionsv1alpha1.Function) *pod.PodTemplate {
+	merged := functionsv1alpha1.BuildPodTemplate{}
+	if defaults := defaultBuildPodTemplate(); defaults != nil {
+		merged = *defaults
+	}
+
+	if override := function.Spec.Build.PodTemplate; override != nil {
+		if override.NodeSelector != nil {
+			merged.NodeSelector = override.NodeSelector
+		}
+		if override.Tolerations != nil {
+			merged.Tolerations = override.Tolerations
+		}
+		if override.Affinity != nil {
+			merged.Affinity = override.Affinity
+		}
+		if override.PriorityClassName != "" {
+			merged.PriorityClassName = override.PriorityClassName
+		}
+		if override.SecurityContext != nil {
+			merged.SecurityContext = override.SecurityContext
+		}
+	}
+
+	if merged.NodeSelector == nil && merged.Tolerations == nil && merged.Affinity == nil &&
+		merged.PriorityClassName == "" && merged.SecurityContext == nil {
+		return nil
+	}
+
+	merged = *merged.DeepCopy()
+	podTemplate := &pod.PodTemplate{
+		NodeSelector:    merged.NodeSelector,
+		Tolerations:     merged.Tolerations,
+		Affinity:        merged.Affinity,
+		SecurityContext: merged.SecurityContext,
+	}
+	if merged.PriorityClassName != "" {
+		podTemplate.PriorityClassName = &merged.PriorityClassName
+	}
+	return podTemplate
+}
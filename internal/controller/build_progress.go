@@ -0,0 +1,256 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// Task states reported in status.build.progress.tasks
+const (
+	buildTaskPending   = "Pending"
+	buildTaskRunning   = "Running"
+	buildTaskSucceeded = "Succeeded"
+	buildTaskFailed    = "Failed"
+	buildTaskSkipped   = "Skipped"
+)
+
+// buildStepLabels are the human-readable activities of well-known steps, used in the progress summary
+var buildStepLabels = map[string]string{
+	"clone":              "cloning source",
+	"inline":             "copying inline source",
+	"oci":                "pulling source artifact",
+	"s3":                 "downloading source archive",
+	"get-labels-and-env": "inspecting builder",
+	"prepare":            "preparing build",
+	"analyze":            "analyzing previous image",
+	"detect":             "detecting buildpacks",
+	"restore":            "restoring cache",
+	"extender":           "building with extensions",
+	"build":              "building",
+	"export":             "exporting image",
+	"results":            "collecting results",
+}
+
+// formatBuildDuration renders a duration rounded to the second (e.g. "2m13s").
+func formatBuildDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return d.Round(time.Second).String()
+}
+
+/*
+buildProgress calcula o andamento de um PipelineRun a partir dos seus TaskRuns filhos.
+As tasks seguem a ordem do pipeline resolvido (status.pipelineSpec); tasks ainda não iniciadas
+aparecem como Pending. Para builds em execução, o tempo decorrido é medido até 'now'.
+*/
+func buildProgress(pipelineRun *tektonv1.PipelineRun, taskRuns map[string]*tektonv1.TaskRun, now time.Time) *functionsv1alpha1.BuildProgress {
+	progress := &functionsv1alpha1.BuildProgress{
+		PipelineRun:    pipelineRun.Name,
+		StartTime:      pipelineRun.Status.StartTime,
+		CompletionTime: pipelineRun.Status.CompletionTime,
+	}
+
+	end := now
+	if progress.CompletionTime != nil {
+		end = progress.CompletionTime.Time
+	}
+	if progress.StartTime != nil {
+		progress.Elapsed = formatBuildDuration(end.Sub(progress.StartTime.Time))
+	}
+
+	// Ordem das tasks: a do pipeline resolvido, seguida de filhos desconhecidos (ex: tasks de 'finally')
+	var order []string
+	seen := map[string]bool{}
+	if spec := pipelineRun.Status.PipelineSpec; spec != nil {
+		for _, task := range append(append([]tektonv1.PipelineTask{}, spec.Tasks...), spec.Finally...) {
+			order = append(order, task.Name)
+			seen[task.Name] = true
+		}
+	}
+	for _, childRef := range pipelineRun.Status.ChildReferences {
+		if !seen[childRef.PipelineTaskName] {
+			order = append(order, childRef.PipelineTaskName)
+			seen[childRef.PipelineTaskName] = true
+		}
+	}
+	skipped := map[string]bool{}
+	for _, skippedTask := range pipelineRun.Status.SkippedTasks {
+		skipped[skippedTask.Name] = true
+	}
+
+	for _, name := range order {
+		task := functionsv1alpha1.BuildTaskProgress{Name: name, Status: buildTaskPending}
+		taskRun := taskRuns[name]
+		switch {
+		case taskRun != nil:
+			task.StartTime = taskRun.Status.StartTime
+			task.CompletionTime = taskRun.Status.CompletionTime
+			task.Status = taskRunState(taskRun)
+			if task.StartTime != nil {
+				taskEnd := now
+				if task.CompletionTime != nil {
+					taskEnd = task.CompletionTime.Time
+				}
+				task.Duration = formatBuildDuration(taskEnd.Sub(task.StartTime.Time))
+			}
+		case skipped[name]:
+			task.Status = buildTaskSkipped
+		}
+
+		if task.Status == buildTaskRunning && progress.CurrentTask == "" && progress.CompletionTime == nil {
+			progress.CurrentTask = name
+			progress.CurrentStep = runningStep(taskRun)
+		}
+		progress.Tasks = append(progress.Tasks, task)
+	}
+
+	progress.Summary = buildProgressSummary(pipelineRun, progress)
+	return progress
+}
+
+// taskRunState maps the Succeeded condition of a TaskRun to a task state.
+func taskRunState(taskRun *tektonv1.TaskRun) string {
+	condition := taskRun.Status.GetCondition(apis.ConditionSucceeded)
+	switch {
+	case condition == nil:
+		return buildTaskPending
+	case condition.IsTrue():
+		return buildTaskSucceeded
+	case condition.IsFalse():
+		return buildTaskFailed
+	case taskRun.Status.StartTime != nil:
+		return buildTaskRunning
+	}
+	return buildTaskPending
+}
+
+// runningStep returns the name of the step currently running in a TaskRun, if any.
+func runningStep(taskRun *tektonv1.TaskRun) string {
+	for _, step := range taskRun.Status.Steps {
+		if step.Running != nil {
+			return step.Name
+		}
+	}
+	return ""
+}
+
+// buildProgressSummary renders the one-line summary shown by 'kubectl get functions -o wide'.
+func buildProgressSummary(pipelineRun *tektonv1.PipelineRun, progress *functionsv1alpha1.BuildProgress) string {
+	var activity string
+	switch {
+	case progress.CompletionTime != nil && pipelineRun.IsSuccessful():
+		activity = "succeeded"
+	case progress.CompletionTime != nil:
+		activity = "failed"
+	case progress.CurrentStep != "":
+		activity = buildStepLabels[progress.CurrentStep]
+		if activity == "" {
+			activity = progress.CurrentTask + "/" + progress.CurrentStep
+		}
+	case progress.CurrentTask != "":
+		activity = progress.CurrentTask
+	default:
+		activity = "pending"
+	}
+
+	if progress.Elapsed == "" {
+		return activity
+	}
+	return fmt.Sprintf("%s (%s)", activity, progress.Elapsed)
+}
+
+/*
+updateBuildProgress atualiza 'status.build.progress' a partir do PipelineRun e dos seus TaskRuns.
+Retorna true quando o andamento mudou e o status precisa ser persistido.
+Builds concluídos já registrados não são recalculados.
+*/
+func (r *FunctionReconciler) updateBuildProgress(ctx context.Context, function *functionsv1alpha1.Function, pipelineRun *tektonv1.PipelineRun) bool {
+	var current *functionsv1alpha1.BuildProgress
+	if function.Status.Build != nil {
+		current = function.Status.Build.Progress
+	}
+	if current != nil && current.CompletionTime != nil && pipelineRun.IsDone() &&
+		current.PipelineRun == pipelineRun.Name && current.StartTime.Equal(pipelineRun.Status.StartTime) {
+		return false
+	}
+
+	taskRuns := map[string]*tektonv1.TaskRun{}
+	for _, childRef := range pipelineRun.Status.ChildReferences {
+		if childRef.Kind != "TaskRun" {
+			continue
+		}
+		taskRun := &tektonv1.TaskRun{}
+		if err := r.Get(ctx, types.NamespacedName{Name: childRef.Name, Namespace: pipelineRun.Namespace}, taskRun); err != nil {
+			continue
+		}
+		taskRuns[childRef.PipelineTaskName] = taskRun
+	}
+
+	progress := buildProgress(pipelineRun, taskRuns, time.Now())
+	if current != nil && buildProgressEqual(current, progress) {
+		return false
+	}
+	if progress.CompletionTime != nil {
+		// Primeira observação do build concluído: alimentar as métricas de build
+		tektonReason := ""
+		if condition := pipelineRun.Status.GetCondition(apis.ConditionSucceeded); condition != nil {
+			tektonReason = condition.Reason
+		}
+		recordBuildCompletion(function, progress, pipelineRun.IsSuccessful(), tektonReason)
+	}
+	if function.Status.Build == nil {
+		function.Status.Build = &functionsv1alpha1.BuildStatus{}
+	}
+	function.Status.Build.Progress = progress
+	return true
+}
+
+// buildProgressEqual compares two progress snapshots, ignoring sub-second differences in timestamps
+// (metav1.Time is serialized with second precision).
+func buildProgressEqual(a, b *functionsv1alpha1.BuildProgress) bool {
+	return equality.Semantic.DeepEqual(truncateBuildProgress(a), truncateBuildProgress(b))
+}
+
+func truncateBuildProgress(progress *functionsv1alpha1.BuildProgress) *functionsv1alpha1.BuildProgress {
+	truncate := func(t *metav1.Time) *metav1.Time {
+		if t == nil {
+			return nil
+		}
+		return &metav1.Time{Time: t.Truncate(time.Second)}
+	}
+
+	progress = progress.DeepCopy()
+	progress.StartTime = truncate(progress.StartTime)
+	progress.CompletionTime = truncate(progress.CompletionTime)
+	for i := range progress.Tasks {
+		progress.Tasks[i].StartTime = truncate(progress.Tasks[i].StartTime)
+		progress.Tasks[i].CompletionTime = truncate(progress.Tasks[i].CompletionTime)
+	}
+	return progress
+}
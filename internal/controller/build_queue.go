@@ -0,0 +1,188 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// BuildQueue is the admission queue that limits how many PipelineRuns run at the same time.
+//
+// Functions wait in a single FIFO list. A waiting Function is admitted when, walking the
+// list from the head, a slot is still available both globally and in its namespace after
+// reserving slots for the Functions ahead of it. A namespace at its limit therefore never
+// blocks Functions from other namespaces, while Functions of the same namespace keep their
+// arrival order.
+//
+// The queue lives in memory. Restore rebuilds the running set from the in-flight PipelineRuns
+// when the operator starts, and running builds are also re-registered through MarkRunning on
+// every reconcile of an in-flight PipelineRun.
+type BuildQueue struct {
+	mu sync.Mutex
+
+	// maxConcurrent is the cluster-wide limit of running builds (0 = unlimited)
+	maxConcurrent int
+	// maxConcurrentPerNamespace is the per-namespace limit of running builds (0 = unlimited)
+	maxConcurrentPerNamespace int
+
+	running map[types.NamespacedName]struct{}
+	waiting []types.NamespacedName
+}
+
+// NewBuildQueue creates a BuildQueue with the given global and per-namespace limits.
+// A limit of 0 disables the corresponding check.
+func NewBuildQueue(maxConcurrent, maxConcurrentPerNamespace int) *BuildQueue {
+	return &BuildQueue{
+		maxConcurrent:             maxConcurrent,
+		maxConcurrentPerNamespace: maxConcurrentPerNamespace,
+		running:                   make(map[types.NamespacedName]struct{}),
+	}
+}
+
+// Admit asks for a build slot for the given Function.
+// It returns true when the build may start. Otherwise the Function is kept in the queue
+// and its 1-based position is returned.
+func (q *BuildQueue) Admit(key types.NamespacedName) (bool, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.updateMetrics()
+
+	if _, ok := q.running[key]; ok {
+		return true, 0
+	}
+
+	if q.indexOf(key) < 0 {
+		q.waiting = append(q.waiting, key)
+	}
+
+	// Simulate the admission of the queue in FIFO order, reserving slots for
+	// every eligible Function ahead of the requester.
+	globalRunning := len(q.running)
+	namespaceRunning := make(map[string]int)
+	for running := range q.running {
+		namespaceRunning[running.Namespace]++
+	}
+
+	position := 0
+	for i, waiting := range q.waiting {
+		globalFull := q.maxConcurrent > 0 && globalRunning >= q.maxConcurrent
+		namespaceFull := q.maxConcurrentPerNamespace > 0 && namespaceRunning[waiting.Namespace] >= q.maxConcurrentPerNamespace
+
+		if waiting == key {
+			if !globalFull && !namespaceFull {
+				q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+				q.running[key] = struct{}{}
+				return true, 0
+			}
+			position = i + 1
+			break
+		}
+
+		if !globalFull && !namespaceFull {
+			globalRunning++
+			namespaceRunning[waiting.Namespace]++
+		}
+	}
+
+	return false, position
+}
+
+// MarkRunning registers a build that is already running (e.g. found after an operator restart),
+// removing it from the waiting list if needed.
+func (q *BuildQueue) MarkRunning(key types.NamespacedName) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.updateMetrics()
+
+	if i := q.indexOf(key); i >= 0 {
+		q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+	}
+	q.running[key] = struct{}{}
+}
+
+// Release frees the slot (or the queue entry) held by the given Function.
+// It is safe to call for Functions that are not in the queue.
+func (q *BuildQueue) Release(key types.NamespacedName) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.updateMetrics()
+
+	delete(q.running, key)
+	if i := q.indexOf(key); i >= 0 {
+		q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+	}
+}
+
+// Restore registers as running every unfinished PipelineRun controlled by a Function,
+// so the limits hold right after an operator restart instead of only once each Function
+// has been reconciled again.
+func (q *BuildQueue) Restore(ctx context.Context, reader client.Reader) error {
+	pipelineRuns := &tektonv1.PipelineRunList{}
+	if err := reader.List(ctx, pipelineRuns); err != nil {
+		return err
+	}
+	for i := range pipelineRuns.Items {
+		pipelineRun := &pipelineRuns.Items[i]
+		if pipelineRun.IsDone() {
+			continue
+		}
+		owner := metav1.GetControllerOf(pipelineRun)
+		if owner == nil || owner.Kind != "Function" || owner.APIVersion != functionsv1alpha1.GroupVersion.String() {
+			continue
+		}
+		q.MarkRunning(types.NamespacedName{Namespace: pipelineRun.Namespace, Name: owner.Name})
+	}
+	return nil
+}
+
+// Depth returns the number of Functions waiting for a build slot.
+func (q *BuildQueue) Depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
+
+// Running returns the number of builds currently holding a slot.
+func (q *BuildQueue) Running() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.running)
+}
+
+// indexOf returns the position of key in the waiting list, or -1. Callers must hold q.mu.
+func (q *BuildQueue) indexOf(key types.NamespacedName) int {
+	for i, waiting := range q.waiting {
+		if waiting == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateMetrics publishes the queue state. Callers must hold q.mu.
+func (q *BuildQueue) updateMetrics() {
+	buildQueueDepth.Set(float64(len(q.waiting)))
+	buildsRunning.Set(float64(len(q.running)))
+}
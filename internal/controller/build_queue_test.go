@@ -0,0 +1,183 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func fnKey(namespace, name string) types.NamespacedName {
+	return types.NamespacedName{Namespace: namespace, Name: name}
+}
+
+func TestBuildQueue(t *testing.T) {
+	t.Run("global limit queues builds in FIFO order", func(t *testing.T) {
+		g := NewWithT(t)
+		q := NewBuildQueue(1, 0)
+
+		admitted, _ := q.Admit(fnKey("ns1", "a"))
+		g.Expect(admitted).To(BeTrue())
+
+		admitted, position := q.Admit(fnKey("ns2", "b"))
+		g.Expect(admitted).To(BeFalse())
+		g.Expect(position).To(Equal(1))
+
+		admitted, position = q.Admit(fnKey("ns3", "c"))
+		g.Expect(admitted).To(BeFalse())
+		g.Expect(position).To(Equal(2))
+		g.Expect(q.Depth()).To(Equal(2))
+
+		q.Release(fnKey("ns1", "a"))
+
+		// c is behind b, so it keeps waiting even after a slot is freed
+		admitted, position = q.Admit(fnKey("ns3", "c"))
+		g.Expect(admitted).To(BeFalse())
+		g.Expect(position).To(Equal(2))
+
+		admitted, _ = q.Admit(fnKey("ns2", "b"))
+		g.Expect(admitted).To(BeTrue())
+		g.Expect(q.Running()).To(Equal(1))
+		g.Expect(q.Depth()).To(Equal(1))
+	})
+
+	t.Run("saturated namespace does not block other namespaces", func(t *testing.T) {
+		g := NewWithT(t)
+		q := NewBuildQueue(0, 1)
+
+		admitted, _ := q.Admit(fnKey("busy", "a"))
+		g.Expect(admitted).To(BeTrue())
+
+		admitted, position := q.Admit(fnKey("busy", "b"))
+		g.Expect(admitted).To(BeFalse())
+		g.Expect(position).To(Equal(1))
+
+		admitted, _ = q.Admit(fnKey("quiet", "c"))
+		g.Expect(admitted).To(BeTrue())
+	})
+
+	t.Run("per-namespace and global limits combined", func(t *testing.T) {
+		g := NewWithT(t)
+		q := NewBuildQueue(2, 1)
+
+		admitted, _ := q.Admit(fnKey("ns1", "a"))
+		g.Expect(admitted).To(BeTrue())
+
+		// ns1 is saturated: b waits without reserving a global slot
+		admitted, _ = q.Admit(fnKey("ns1", "b"))
+		g.Expect(admitted).To(BeFalse())
+
+		admitted, _ = q.Admit(fnKey("ns2", "c"))
+		g.Expect(admitted).To(BeTrue())
+
+		// global limit reached
+		admitted, position := q.Admit(fnKey("ns3", "d"))
+		g.Expect(admitted).To(BeFalse())
+		g.Expect(position).To(Equal(2))
+	})
+
+	t.Run("admitting a running build is idempotent", func(t *testing.T) {
+		g := NewWithT(t)
+		q := NewBuildQueue(1, 0)
+
+		admitted, _ := q.Admit(fnKey("ns", "a"))
+		g.Expect(admitted).To(BeTrue())
+		admitted, _ = q.Admit(fnKey("ns", "a"))
+		g.Expect(admitted).To(BeTrue())
+		g.Expect(q.Running()).To(Equal(1))
+	})
+
+	t.Run("MarkRunning recovers builds found after a restart", func(t *testing.T) {
+		g := NewWithT(t)
+		q := NewBuildQueue(1, 0)
+
+		admitted, _ := q.Admit(fnKey("ns", "b"))
+		g.Expect(admitted).To(BeTrue())
+		q.Release(fnKey("ns", "b"))
+
+		q.MarkRunning(fnKey("ns", "a"))
+		g.Expect(q.Running()).To(Equal(1))
+
+		admitted, position := q.Admit(fnKey("ns", "b"))
+		g.Expect(admitted).To(BeFalse())
+		g.Expect(position).To(Equal(1))
+	})
+
+	t.Run("Release removes waiting entries", func(t *testing.T) {
+		g := NewWithT(t)
+		q := NewBuildQueue(1, 0)
+
+		q.Admit(fnKey("ns", "a"))
+		q.Admit(fnKey("ns", "b"))
+		q.Admit(fnKey("ns", "c"))
+		q.Release(fnKey("ns", "b"))
+
+		g.Expect(q.Depth()).To(Equal(1))
+		_, position := q.Admit(fnKey("ns", "c"))
+		g.Expect(position).To(Equal(1))
+	})
+
+	t.Run("Restore registers the unfinished PipelineRuns of Functions", func(t *testing.T) {
+		g := NewWithT(t)
+		scheme := runtime.NewScheme()
+		_ = tektonv1.AddToScheme(scheme)
+
+		pipelineRun := func(namespace, name, owner string, done bool) *tektonv1.PipelineRun {
+			pr := &tektonv1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+			if owner != "" {
+				pr.OwnerReferences = []metav1.OwnerReference{{
+					APIVersion: functionsv1alpha1.GroupVersion.String(),
+					Kind:       "Function",
+					Name:       owner,
+					UID:        types.UID(owner + "-uid"),
+					Controller: boolPtr(true),
+				}}
+			}
+			if done {
+				pr.Status.Conditions = []apis.Condition{{Type: apis.ConditionSucceeded, Status: v1.ConditionTrue}}
+			}
+			return pr
+		}
+		reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			pipelineRun("ns1", "a-build", "a", false),
+			pipelineRun("ns2", "b-build", "b", true),
+			pipelineRun("ns2", "manual", "", false),
+		).Build()
+
+		q := NewBuildQueue(1, 0)
+		g.Expect(q.Restore(context.Background(), reader)).To(Succeed())
+		g.Expect(q.Running()).To(Equal(1))
+
+		admitted, position := q.Admit(fnKey("ns2", "b"))
+		g.Expect(admitted).To(BeFalse())
+		g.Expect(position).To(Equal(1))
+
+		admitted, _ = q.Admit(fnKey("ns1", "a"))
+		g.Expect(admitted).To(BeTrue())
+	})
+}
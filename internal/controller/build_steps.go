@@ -0,0 +1,196 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+const (
+	// BuildStepsConfigMapName is the per-namespace ConfigMap holding the default build steps.
+	// Keys "preBuild" and "postBuild" contain YAML lists using the spec.build.preBuild schema.
+	BuildStepsConfigMapName = "zenith-build-steps"
+
+	// preBuildTaskPrefix and postBuildTaskPrefix namespace user steps in the pipeline
+	preBuildTaskPrefix  = "zenith-pre-"
+	postBuildTaskPrefix = "zenith-post-"
+
+	// TestsFailedCondition reports a failed pre/post build step, next to the Ready condition
+	TestsFailedCondition = "TestsFailed"
+
+	// defaultBuildStepWorkspace is the workspace name user Tasks are expected to declare for the source
+	defaultBuildStepWorkspace = "source"
+)
+
+/*
+applyNamespaceBuildSteps preenche 'preBuild' e 'postBuild' a partir do ConfigMap
+'zenith-build-steps' do namespace quando a Function não os define (e não usa 'pipelineRef').
+Cada lista é tratada separadamente: uma Function que define apenas 'preBuild'
+continua herdando o 'postBuild' padrão.
+Deve ser chamada sobre uma cópia da Function, nunca sobre o objeto que será persistido.
+*/
+func (r *FunctionReconciler) applyNamespaceBuildSteps(ctx context.Context, function *functionsv1alpha1.Function) error {
+	// Passos de build só se aplicam ao pipeline embutido
+	if function.Spec.Build.PipelineRef != nil {
+		return nil
+	}
+	if len(function.Spec.Build.PreBuild) > 0 && len(function.Spec.Build.PostBuild) > 0 {
+		return nil
+	}
+
+	configMap := &v1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: BuildStepsConfigMapName, Namespace: function.Namespace}, configMap)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if len(function.Spec.Build.PreBuild) == 0 {
+		steps, err := parseBuildSteps(configMap.Data["preBuild"])
+		if err != nil {
+			return fmt.Errorf("ConfigMap '%s' chave 'preBuild' inválida: %w", BuildStepsConfigMapName, err)
+		}
+		function.Spec.Build.PreBuild = steps
+	}
+	if len(function.Spec.Build.PostBuild) == 0 {
+		steps, err := parseBuildSteps(configMap.Data["postBuild"])
+		if err != nil {
+			return fmt.Errorf("ConfigMap '%s' chave 'postBuild' inválida: %w", BuildStepsConfigMapName, err)
+		}
+		function.Spec.Build.PostBuild = steps
+	}
+	return nil
+}
+
+// parseBuildSteps decodes a YAML list of build steps. An empty document yields no steps.
+func parseBuildSteps(raw string) ([]functionsv1alpha1.BuildStep, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var steps []functionsv1alpha1.BuildStep
+	if err := yaml.UnmarshalStrict([]byte(raw), &steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+/*
+validateBuildSteps verifica os passos de build antes de criar o PipelineRun:
+  - nomes e Tasks obrigatórios, sem nomes duplicados na mesma lista;
+  - passos de 'preBuild' não podem usar resultados do 'build-and-push',
+    que só existem depois do push (o Tekton rejeitaria o ciclo).
+*/
+func validateBuildSteps(function *functionsv1alpha1.Function) error {
+	check := func(field string, steps []functionsv1alpha1.BuildStep, forbiddenRef string) error {
+		seen := make(map[string]bool, len(steps))
+		for _, step := range steps {
+			if step.Name == "" || step.TaskRef.Name == "" {
+				return fmt.Errorf("%s: todo passo precisa de 'name' e 'taskRef.name'", field)
+			}
+			if seen[step.Name] {
+				return fmt.Errorf("%s: passo '%s' duplicado", field, step.Name)
+			}
+			seen[step.Name] = true
+
+			if forbiddenRef == "" {
+				continue
+			}
+			for _, param := range step.Params {
+				if strings.Contains(param.Value, forbiddenRef) {
+					return fmt.Errorf("%s: passo '%s' referencia '%s', que só está disponível em postBuild", field, step.Name, forbiddenRef)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := check("spec.build.preBuild", function.Spec.Build.PreBuild, "tasks.build-and-push."); err != nil {
+		return err
+	}
+	return check("spec.build.postBuild", function.Spec.Build.PostBuild, "")
+}
+
+// buildStepPipelineTasks converts user build steps into pipeline tasks sharing the source workspace.
+func buildStepPipelineTasks(steps []functionsv1alpha1.BuildStep, prefix string, runAfter []string, sharedWorkspaceName string) []tektonv1.PipelineTask {
+	tasks := make([]tektonv1.PipelineTask, 0, len(steps))
+	for _, step := range steps {
+		workspace := step.Workspace
+		if workspace == "" {
+			workspace = defaultBuildStepWorkspace
+		}
+
+		params := make([]tektonv1.Param, 0, len(step.Params))
+		for _, param := range step.Params {
+			params = append(params, tektonv1.Param{
+				Name:  param.Name,
+				Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: param.Value},
+			})
+		}
+
+		tasks = append(tasks, tektonv1.PipelineTask{
+			Name:     prefix + step.Name,
+			TaskRef:  &tektonv1.TaskRef{Name: step.TaskRef.Name},
+			RunAfter: runAfter,
+			Workspaces: []tektonv1.WorkspacePipelineTaskBinding{
+				{Name: workspace, Workspace: sharedWorkspaceName},
+			},
+			Params: params,
+		})
+	}
+	return tasks
+}
+
+// isBuildStepTask reports whether a pipeline task of the function's build belongs to a user pre/post build step.
+// Pipelines from spec.build.pipelineRef name their own tasks and are never classified.
+func isBuildStepTask(function *functionsv1alpha1.Function, pipelineTaskName string) bool {
+	if function.Spec.Build.PipelineRef != nil {
+		return false
+	}
+	return strings.HasPrefix(pipelineTaskName, preBuildTaskPrefix) || strings.HasPrefix(pipelineTaskName, postBuildTaskPrefix)
+}
+
+/*
+failedBuildStep retorna o nome da task de usuário (pre/post build) que falhou no PipelineRun, se houver.
+Usado para diferenciar falhas de testes/linters ('TestsFailed') de falhas do build em si.
+*/
+func (r *FunctionReconciler) failedBuildStep(ctx context.Context, function *functionsv1alpha1.Function, pipelineRun *tektonv1.PipelineRun) (string, bool) {
+	for _, childRef := range pipelineRun.Status.ChildReferences {
+		if childRef.Kind != "TaskRun" || !isBuildStepTask(function, childRef.PipelineTaskName) {
+			continue
+		}
+
+		taskRun := &tektonv1.TaskRun{}
+		if err := r.Get(ctx, types.NamespacedName{Name: childRef.Name, Namespace: pipelineRun.Namespace}, taskRun); err != nil {
+			continue
+		}
+		if taskRun.IsFailure() {
+			return childRef.PipelineTaskName, true
+		}
+	}
+	return "", false
+}
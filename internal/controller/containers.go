@@ -0,0 +1,230 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// reservedContainerNames are the containers added to the pod by Knative and Dapr
+var reservedContainerNames = map[string]bool{"queue-proxy": true, "daprd": true}
+
+// servingSidecar returns the sidecar that receives the traffic in place of the function, if any.
+func servingSidecar(function *functionsv1alpha1.Function) *functionsv1alpha1.FunctionContainer {
+	for i := range function.Spec.Deploy.Sidecars {
+		if function.Spec.Deploy.Sidecars[i].Port != nil {
+			return &function.Spec.Deploy.Sidecars[i]
+		}
+	}
+	return nil
+}
+
+/*
+validateContainers verifica as regras de 'spec.deploy.sidecars' e 'spec.deploy.initContainers'
+que a validação do CRD não expressa: nomes únicos no pod, montagens de volumes existentes e
+um único container recebendo o tráfego (a função, ou o sidecar com 'port').
+*/
+func validateContainers(function *functionsv1alpha1.Function) error {
+	deploy := function.Spec.Deploy
+	volumes := map[string]bool{}
+	for _, volume := range deploy.Volumes {
+		volumes[volume.Name] = true
+	}
+
+	names := map[string]bool{}
+	check := func(field string, container functionsv1alpha1.FunctionContainer) error {
+		if reservedContainerNames[container.Name] {
+			return fmt.Errorf("%s: o nome %q é reservado", field, container.Name)
+		}
+		if names[container.Name] {
+			return fmt.Errorf("%s: o nome %q já é usado por outro container", field, container.Name)
+		}
+		names[container.Name] = true
+		for _, mount := range container.VolumeMounts {
+			if !volumes[mount.Name] {
+				return fmt.Errorf("%s: o volume %q não está definido em spec.deploy.volumes", field, mount.Name)
+			}
+		}
+		return nil
+	}
+
+	for i, container := range deploy.InitContainers {
+		field := fmt.Sprintf("spec.deploy.initContainers[%d]", i)
+		if err := check(field, container); err != nil {
+			return err
+		}
+		if container.Port != nil {
+			return fmt.Errorf("%s: init containers não recebem tráfego e não podem definir port", field)
+		}
+	}
+
+	serving := ""
+	for i, container := range deploy.Sidecars {
+		field := fmt.Sprintf("spec.deploy.sidecars[%d]", i)
+		if err := check(field, container); err != nil {
+			return err
+		}
+		if container.Port == nil {
+			continue
+		}
+		if serving != "" {
+			return fmt.Errorf("%s: só um container pode receber o tráfego, e o sidecar %q já define port", field, serving)
+		}
+		serving = container.Name
+
+		port := container.Port.Number
+		if knativeReservedPorts[port] {
+			return fmt.Errorf("%s.port: a porta %d é reservada pelo Knative", field, port)
+		}
+		if deploy.Dapr.Enabled && daprReservedPorts[port] {
+			return fmt.Errorf("%s.port: a porta %d é reservada pelo sidecar do Dapr", field, port)
+		}
+		if port == functionContainerPort(function).ContainerPort {
+			return fmt.Errorf("%s.port: a porta %d já é usada pela função", field, port)
+		}
+		if deploy.Probes != nil {
+			return fmt.Errorf("spec.deploy.probes: o Knative só verifica o container que recebe o tráfego (sidecar %q)", serving)
+		}
+	}
+	return nil
+}
+
+/*
+buildContainer traduz um sidecar ou init container para o PodSpec do Knative.
+As variáveis de ambiente passam pela mesma resolução de fieldRef do container da função, e as
+montagens de Secrets, ConfigMaps e volumes projetados são somente leitura, como o Knative exige.
+*/
+func buildContainer(function *functionsv1alpha1.Function, spec functionsv1alpha1.FunctionContainer) v1.Container {
+	container := v1.Container{
+		Name:    spec.Name,
+		Image:   spec.Image,
+		Command: spec.Command,
+		Args:    spec.Args,
+		EnvFrom: spec.EnvFrom,
+	}
+	for _, envVar := range spec.Env {
+		container.Env = append(container.Env, resolveEnvVar(function, envVar))
+	}
+	if spec.Resources != nil {
+		container.Resources = *spec.Resources
+	}
+
+	writable := map[string]bool{}
+	for _, volume := range function.Spec.Deploy.Volumes {
+		writable[volume.Name] = volume.EmptyDir != nil ||
+			(volume.PersistentVolumeClaim != nil && !volume.PersistentVolumeClaim.ReadOnly)
+	}
+	for _, mount := range spec.VolumeMounts {
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+			Name:      mount.Name,
+			MountPath: mount.MountPath,
+			SubPath:   mount.SubPath,
+			ReadOnly:  mount.ReadOnly || !writable[mount.Name],
+		})
+	}
+
+	if spec.Port != nil {
+		port := namedContainerPort(spec.Port)
+		container.Ports = []v1.ContainerPort{port}
+		// Readiness padrão do Knative (TCP na porta de serviço), explícita para a comparação ser estável
+		container.ReadinessProbe = readinessProbe(nil, port.ContainerPort)
+	}
+	return container
+}
+
+// sidecarContainers builds the sidecars of the function pod, after the function container.
+func sidecarContainers(function *functionsv1alpha1.Function) []v1.Container {
+	var containers []v1.Container
+	for _, sidecar := range function.Spec.Deploy.Sidecars {
+		containers = append(containers, buildContainer(function, sidecar))
+	}
+	return containers
+}
+
+// initContainers builds the init containers of the function pod.
+func initContainers(function *functionsv1alpha1.Function) []v1.Container {
+	var containers []v1.Container
+	for _, initContainer := range function.Spec.Deploy.InitContainers {
+		containers = append(containers, buildContainer(function, initContainer))
+	}
+	return containers
+}
+
+/*
+containerPairs emparelha, por posição, os init containers e os containers (função e sidecars)
+do Knative Service no cluster com os desejados. Retorna false se a quantidade mudou, o que
+por si só exige uma atualização.
+*/
+func containerPairs(current, desired *knservingv1.Service) ([][2]v1.Container, bool) {
+	currentSpec, desiredSpec := current.Spec.Template.Spec, desired.Spec.Template.Spec
+	if len(currentSpec.Containers) != len(desiredSpec.Containers) ||
+		len(currentSpec.InitContainers) != len(desiredSpec.InitContainers) {
+		return nil, false
+	}
+
+	pairs := make([][2]v1.Container, 0, len(desiredSpec.InitContainers)+len(desiredSpec.Containers))
+	for i := range desiredSpec.InitContainers {
+		pairs = append(pairs, [2]v1.Container{currentSpec.InitContainers[i], desiredSpec.InitContainers[i]})
+	}
+	for i := range desiredSpec.Containers {
+		pairs = append(pairs, [2]v1.Container{currentSpec.Containers[i], desiredSpec.Containers[i]})
+	}
+	return pairs, true
+}
+
+// containerImages returns the images of the init containers and containers of a Knative Service, in order.
+func containerImages(ksvc *knservingv1.Service) []string {
+	var images []string
+	for _, container := range ksvc.Spec.Template.Spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range ksvc.Spec.Template.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+/*
+containersChanged compara todos os containers (função, sidecars e init containers) do Knative Service
+no cluster com os desejados: quantidade, nomes, comando, argumentos e variáveis de ambiente.
+O container da função não tem nome no spec desejado (o Knative atribui um), então nomes vazios não são comparados.
+*/
+func containersChanged(current, desired *knservingv1.Service) bool {
+	pairs, ok := containerPairs(current, desired)
+	if !ok {
+		return true
+	}
+	for _, pair := range pairs {
+		currentContainer, desiredContainer := pair[0], pair[1]
+		if desiredContainer.Name != "" && currentContainer.Name != desiredContainer.Name {
+			return true
+		}
+		if !equality.Semantic.DeepEqual(currentContainer.Command, desiredContainer.Command) ||
+			!equality.Semantic.DeepEqual(currentContainer.Args, desiredContainer.Args) ||
+			!equality.Semantic.DeepEqual(currentContainer.Env, desiredContainer.Env) ||
+			!equality.Semantic.DeepEqual(currentContainer.EnvFrom, desiredContainer.EnvFrom) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestValidateContainers(t *testing.T) {
+	base := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Volumes: []functionsv1alpha1.FunctionVolume{
+					{Name: "sql-credentials", MountPath: "/secrets", Secret: &v1.SecretVolumeSource{SecretName: "sql-sa"}},
+					{Name: "logs", MountPath: "/var/log/app", EmptyDir: &v1.EmptyDirVolumeSource{}},
+				},
+				Sidecars: []functionsv1alpha1.FunctionContainer{
+					{
+						Name:  "cloud-sql-proxy",
+						Image: "gcr.io/cloud-sql-connectors/cloud-sql-proxy:2.11.0",
+						Args:  []string{"--credentials-file=/secrets/key.json", "acme:us-central1:orders"},
+						Resources: &v1.ResourceRequirements{
+							Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Mi")},
+						},
+						VolumeMounts: []functionsv1alpha1.ContainerVolumeMount{{Name: "sql-credentials", MountPath: "/secrets"}},
+					},
+					{
+						Name:         "log-shipper",
+						Image:        "fluent/fluent-bit:3.0",
+						Env:          []v1.EnvVar{{Name: "POD_NAMESPACE", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}}},
+						VolumeMounts: []functionsv1alpha1.ContainerVolumeMount{{Name: "logs", MountPath: "/logs", ReadOnly: true}},
+					},
+				},
+				InitContainers: []functionsv1alpha1.FunctionContainer{
+					{Name: "migrate", Image: "registry.example.com/orders-migrations:v3", Command: []string{"/migrate", "up"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*functionsv1alpha1.Function)
+		wantErr string
+	}{
+		{name: "sidecars and init containers", mutate: func(*functionsv1alpha1.Function) {}},
+		{
+			name: "serving sidecar",
+			mutate: func(f *functionsv1alpha1.Function) {
+				f.Spec.Deploy.Sidecars[0].Port = &functionsv1alpha1.PortSpec{Number: 4180}
+			},
+		},
+		{
+			name: "duplicate name across init containers and sidecars",
+			mutate: func(f *functionsv1alpha1.Function) {
+				f.Spec.Deploy.InitContainers[0].Name = "log-shipper"
+			},
+			wantErr: `spec.deploy.sidecars[1]: o nome "log-shipper" já é usado`,
+		},
+		{
+			name: "reserved name",
+			mutate: func(f *functionsv1alpha1.Function) {
+				f.Spec.Deploy.Sidecars[0].Name = "queue-proxy"
+			},
+			wantErr: `o nome "queue-proxy" é reservado`,
+		},
+		{
+			name: "unknown volume",
+			mutate: func(f *functionsv1alpha1.Function) {
+				f.Spec.Deploy.Sidecars[1].VolumeMounts[0].Name = "missing"
+			},
+			wantErr: `o volume "missing" não está definido`,
+		},
+		{
+			name: "init container with a port",
+			mutate: func(f *functionsv1alpha1.Function) {
+				f.Spec.Deploy.InitContainers[0].Port = &functionsv1alpha1.PortSpec{Number: 9000}
+			},
+			wantErr: "init containers não recebem tráfego",
+		},
+		{
+			name: "two serving sidecars",
+			mutate: func(f *functionsv1alpha1.Function) {
+				f.Spec.Deploy.Sidecars[0].Port = &functionsv1alpha1.PortSpec{Number: 4180}
+				f.Spec.Deploy.Sidecars[1].Port = &functionsv1alpha1.PortSpec{Number: 2020}
+			},
+			wantErr: `só um container pode receber o tráfego, e o sidecar "cloud-sql-proxy" já define port`,
+		},
+		{
+			name: "serving sidecar on the function port",
+			mutate: func(f *functionsv1alpha1.Function) {
+				f.Spec.Deploy.Sidecars[0].Port = &functionsv1alpha1.PortSpec{Number: 8080}
+			},
+			wantErr: "a porta 8080 já é usada pela função",
+		},
+		{
+			name: "serving sidecar on a Knative port",
+			mutate: func(f *functionsv1alpha1.Function) {
+				f.Spec.Deploy.Sidecars[0].Port = &functionsv1alpha1.PortSpec{Number: 8012}
+			},
+			wantErr: "reservada pelo Knative",
+		},
+		{
+			name: "function probes with a serving sidecar",
+			mutate: func(f *functionsv1alpha1.Function) {
+				f.Spec.Deploy.Sidecars[0].Port = &functionsv1alpha1.PortSpec{Number: 4180}
+				f.Spec.Deploy.Probes = &functionsv1alpha1.ProbesSpec{}
+			},
+			wantErr: "spec.deploy.probes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := base.DeepCopy()
+			tt.mutate(function)
+
+			err := validateContainers(function)
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			}
+		})
+	}
+}
+
+func TestBuildKnativeServiceContainers(t *testing.T) {
+	g := NewWithT(t)
+	r := &FunctionReconciler{}
+	base := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Volumes: []functionsv1alpha1.FunctionVolume{
+					{Name: "sql-credentials", MountPath: "/secrets", Secret: &v1.SecretVolumeSource{SecretName: "sql-sa"}},
+					{Name: "logs", MountPath: "/var/log/app", EmptyDir: &v1.EmptyDirVolumeSource{}},
+				},
+				Sidecars: []functionsv1alpha1.FunctionContainer{
+					{
+						Name:  "cloud-sql-proxy",
+						Image: "gcr.io/cloud-sql-connectors/cloud-sql-proxy:2.11.0",
+						Args:  []string{"--credentials-file=/secrets/key.json", "acme:us-central1:orders"},
+						Resources: &v1.ResourceRequirements{
+							Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Mi")},
+						},
+						VolumeMounts: []functionsv1alpha1.ContainerVolumeMount{{Name: "sql-credentials", MountPath: "/secrets"}},
+					},
+					{
+						Name:         "log-shipper",
+						Image:        "fluent/fluent-bit:3.0",
+						Env:          []v1.EnvVar{{Name: "POD_NAMESPACE", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}}},
+						VolumeMounts: []functionsv1alpha1.ContainerVolumeMount{{Name: "logs", MountPath: "/logs", ReadOnly: true}},
+					},
+				},
+				InitContainers: []functionsv1alpha1.FunctionContainer{
+					{Name: "migrate", Image: "registry.example.com/orders-migrations:v3", Command: []string{"/migrate", "up"}},
+				},
+			},
+		},
+	}
+
+	spec := r.buildKnativeService(base).Spec.Template.Spec
+	g.Expect(spec.Containers).To(HaveLen(3))
+	g.Expect(spec.Containers[0].Name).To(BeEmpty())
+	g.Expect(spec.Containers[0].Ports).To(HaveLen(1))
+	g.Expect(spec.Containers[0].ReadinessProbe).NotTo(BeNil())
+
+	proxy := spec.Containers[1]
+	g.Expect(proxy.Name).To(Equal("cloud-sql-proxy"))
+	g.Expect(proxy.Args).To(HaveLen(2))
+	g.Expect(proxy.Ports).To(BeEmpty())
+	g.Expect(proxy.Resources.Requests.Memory().String()).To(Equal("64Mi"))
+	g.Expect(proxy.VolumeMounts).To(Equal([]v1.VolumeMount{{Name: "sql-credentials", MountPath: "/secrets", ReadOnly: true}}))
+
+	shipper := spec.Containers[2]
+	g.Expect(shipper.Env).To(Equal([]v1.EnvVar{{Name: "POD_NAMESPACE", Value: "default"}}))
+	g.Expect(shipper.VolumeMounts[0].ReadOnly).To(BeTrue())
+
+	g.Expect(spec.InitContainers).To(HaveLen(1))
+	g.Expect(spec.InitContainers[0].Command).To(Equal([]string{"/migrate", "up"}))
+
+	t.Run("a serving sidecar takes the port and the readiness probe", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Deploy.Sidecars[0].Port = &functionsv1alpha1.PortSpec{Number: 4180}
+
+		spec := r.buildKnativeService(function).Spec.Template.Spec
+		g.Expect(spec.Containers[0].Ports).To(BeEmpty())
+		g.Expect(spec.Containers[0].ReadinessProbe).To(BeNil())
+		g.Expect(spec.Containers[1].Ports).To(Equal([]v1.ContainerPort{{Name: "http1", ContainerPort: 4180}}))
+		g.Expect(spec.Containers[1].ReadinessProbe).NotTo(BeNil())
+	})
+}
+
+func TestContainersDrift(t *testing.T) {
+	r := &FunctionReconciler{}
+	base := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Volumes: []functionsv1alpha1.FunctionVolume{
+					{Name: "sql-credentials", MountPath: "/secrets", Secret: &v1.SecretVolumeSource{SecretName: "sql-sa"}},
+					{Name: "logs", MountPath: "/var/log/app", EmptyDir: &v1.EmptyDirVolumeSource{}},
+				},
+				Sidecars: []functionsv1alpha1.FunctionContainer{
+					{
+						Name:  "cloud-sql-proxy",
+						Image: "gcr.io/cloud-sql-connectors/cloud-sql-proxy:2.11.0",
+						Args:  []string{"--credentials-file=/secrets/key.json", "acme:us-central1:orders"},
+						Resources: &v1.ResourceRequirements{
+							Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Mi")},
+						},
+						VolumeMounts: []functionsv1alpha1.ContainerVolumeMount{{Name: "sql-credentials", MountPath: "/secrets"}},
+					},
+					{
+						Name:         "log-shipper",
+						Image:        "fluent/fluent-bit:3.0",
+						Env:          []v1.EnvVar{{Name: "POD_NAMESPACE", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}}},
+						VolumeMounts: []functionsv1alpha1.ContainerVolumeMount{{Name: "logs", MountPath: "/logs", ReadOnly: true}},
+					},
+				},
+				InitContainers: []functionsv1alpha1.FunctionContainer{
+					{Name: "migrate", Image: "registry.example.com/orders-migrations:v3", Command: []string{"/migrate", "up"}},
+				},
+			},
+		},
+	}
+	desired := r.buildKnativeService(base)
+
+	// O webhook do Knative dá nome ao container da função
+	current := desired.DeepCopy()
+	current.Spec.Template.Spec.Containers[0].Name = "user-container-0"
+
+	t.Run("in sync", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(containersChanged(current, desired)).To(BeFalse())
+		g.Expect(revisionSettingsChanged(current, desired)).To(BeFalse())
+		g.Expect(probesChanged(current, desired)).To(BeFalse())
+		g.Expect(volumesChanged(current, desired)).To(BeFalse())
+		g.Expect(containerImages(current)).To(Equal(containerImages(desired)))
+	})
+
+	t.Run("sidecar image", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Deploy.Sidecars[1].Image = "fluent/fluent-bit:3.1"
+		g.Expect(containerImages(r.buildKnativeService(function))).NotTo(Equal(containerImages(current)))
+	})
+
+	t.Run("sidecar args and env", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Deploy.Sidecars[0].Args = []string{"acme:us-central1:orders-replica"}
+		g.Expect(containersChanged(current, r.buildKnativeService(function))).To(BeTrue())
+
+		function = base.DeepCopy()
+		function.Spec.Deploy.InitContainers[0].Env = []v1.EnvVar{{Name: "DRY_RUN", Value: "true"}}
+		g.Expect(containersChanged(current, r.buildKnativeService(function))).To(BeTrue())
+	})
+
+	t.Run("sidecar resources and mounts", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Deploy.Sidecars[0].Resources.Requests[v1.ResourceMemory] = resource.MustParse("128Mi")
+		g.Expect(revisionSettingsChanged(current, r.buildKnativeService(function))).To(BeTrue())
+
+		function = base.DeepCopy()
+		function.Spec.Deploy.Sidecars[1].VolumeMounts[0].MountPath = "/fluent-bit/logs"
+		g.Expect(volumesChanged(current, r.buildKnativeService(function))).To(BeTrue())
+	})
+
+	t.Run("sidecar removed", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Deploy.Sidecars = function.Spec.Deploy.Sidecars[:1]
+		g.Expect(containersChanged(current, r.buildKnativeService(function))).To(BeTrue())
+	})
+}
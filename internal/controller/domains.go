@@ -0,0 +1,185 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	knservingv1beta1 "knative.dev/serving/pkg/apis/serving/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// FunctionNameLabel records the owning Function on generated resources that are listed per function
+const FunctionNameLabel = "functions.zenith.com/function"
+
+// certificateClassAnnotation selects the Knative auto-TLS certificate class of a DomainMapping
+const certificateClassAnnotation = "networking.knative.dev/certificate.class"
+
+/*
+validateDomains verifica as regras de 'spec.deploy.domains' que a validação do CRD não expressa:
+um domínio customizado publica a função fora do cluster, então exige 'visibility: external'.
+*/
+func validateDomains(function *functionsv1alpha1.Function) error {
+	if len(function.Spec.Deploy.Domains) == 0 {
+		return nil
+	}
+	if function.Spec.Deploy.Visibility != functionsv1alpha1.VisibilityExternal {
+		return fmt.Errorf("spec.deploy.domains: domínios customizados exigem spec.deploy.visibility: external")
+	}
+	return nil
+}
+
+// buildDomainMapping builds the DomainMapping routing a custom domain to the Knative Service of the function.
+func buildDomainMapping(function *functionsv1alpha1.Function, domain functionsv1alpha1.FunctionDomain) *knservingv1beta1.DomainMapping {
+	domainMapping := &knservingv1beta1.DomainMapping{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      domain.Name,
+			Namespace: function.Namespace,
+			Labels: map[string]string{
+				"functions.zenith.com/managed-by": "zenith-operator",
+				FunctionNameLabel:                 function.Name,
+			},
+		},
+		Spec: knservingv1beta1.DomainMappingSpec{
+			// O namespace é explícito porque o webhook do Knative o preencheria, gerando atualizações em loop
+			Ref: duckv1.KReference{
+				APIVersion: knservingv1.SchemeGroupVersion.String(),
+				Kind:       "Service",
+				Name:       function.Name,
+				Namespace:  function.Namespace,
+			},
+		},
+	}
+	if tls := domain.TLS; tls != nil {
+		if tls.SecretName != "" {
+			domainMapping.Spec.TLS = &knservingv1beta1.SecretTLS{SecretName: tls.SecretName}
+		}
+		if tls.CertificateClass != "" {
+			domainMapping.Annotations = map[string]string{certificateClassAnnotation: tls.CertificateClass}
+		}
+	}
+	return domainMapping
+}
+
+// domainStatus reports the readiness of a custom domain from the Ready condition of its DomainMapping.
+func domainStatus(domainMapping *knservingv1beta1.DomainMapping) functionsv1alpha1.DomainStatus {
+	status := functionsv1alpha1.DomainStatus{Name: domainMapping.Name}
+	if domainMapping.Status.URL != nil {
+		status.URL = domainMapping.Status.URL.String()
+	}
+
+	ready := domainMapping.Status.GetCondition(apis.ConditionReady)
+	switch {
+	case ready == nil || domainMapping.Status.ObservedGeneration != domainMapping.Generation:
+		status.Reason = "Pending"
+		status.Message = "Aguardando o DomainMapping reportar o estado"
+	case ready.IsTrue():
+		status.Ready = true
+	default:
+		status.Reason = ready.Reason
+		status.Message = ready.Message
+	}
+	return status
+}
+
+/*
+reconcileDomains cria ou atualiza um DomainMapping por domínio em 'spec.deploy.domains' e remove
+os DomainMappings da função cujos domínios saíram do spec. Um DomainMapping de mesmo nome que não
+pertence à função não é alterado: o domínio é reportado como em conflito.
+Retorna o estado de cada domínio, na ordem do spec, para 'status.domains'.
+*/
+func (r *FunctionReconciler) reconcileDomains(ctx context.Context, function *functionsv1alpha1.Function) ([]functionsv1alpha1.DomainStatus, error) {
+	logger := logf.FromContext(ctx)
+
+	var statuses []functionsv1alpha1.DomainStatus
+	desiredNames := map[string]bool{}
+	for _, domain := range function.Spec.Deploy.Domains {
+		desiredNames[domain.Name] = true
+		desired := buildDomainMapping(function, domain)
+
+		existing := &knservingv1beta1.DomainMapping{}
+		err := r.Get(ctx, types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, existing)
+		if err == nil && !metav1.IsControlledBy(existing, function) {
+			logger.Info("DomainMapping já existe e não pertence à função", "DomainMapping.Name", desired.Name)
+			statuses = append(statuses, functionsv1alpha1.DomainStatus{
+				Name:    domain.Name,
+				Reason:  "DomainConflict",
+				Message: fmt.Sprintf("O DomainMapping %s já existe no namespace %s e não pertence a esta função", desired.Name, desired.Namespace),
+			})
+			continue
+		} else if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+
+		domainMapping := &knservingv1beta1.DomainMapping{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+		op, err := controllerutil.CreateOrUpdate(ctx, r.Client, domainMapping, func() error {
+			if domainMapping.Labels == nil {
+				domainMapping.Labels = map[string]string{}
+			}
+			for key, value := range desired.Labels {
+				domainMapping.Labels[key] = value
+			}
+			if class, ok := desired.Annotations[certificateClassAnnotation]; ok {
+				if domainMapping.Annotations == nil {
+					domainMapping.Annotations = map[string]string{}
+				}
+				domainMapping.Annotations[certificateClassAnnotation] = class
+			} else {
+				delete(domainMapping.Annotations, certificateClassAnnotation)
+			}
+			domainMapping.Spec = desired.Spec
+			return controllerutil.SetControllerReference(function, domainMapping, r.Scheme)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if op != controllerutil.OperationResultNone {
+			logger.Info("DomainMapping sincronizado", "DomainMapping.Name", domainMapping.Name, "Operation", op)
+		}
+		statuses = append(statuses, domainStatus(domainMapping))
+	}
+
+	// Remover os DomainMappings de domínios que saíram do spec
+	domainMappings := &knservingv1beta1.DomainMappingList{}
+	if err := r.List(ctx, domainMappings, client.InNamespace(function.Namespace),
+		client.MatchingLabels{FunctionNameLabel: function.Name}); err != nil {
+		return nil, err
+	}
+	for i := range domainMappings.Items {
+		domainMapping := &domainMappings.Items[i]
+		if desiredNames[domainMapping.Name] || !metav1.IsControlledBy(domainMapping, function) {
+			continue
+		}
+		logger.Info("Domínio removido do spec, deletando DomainMapping", "DomainMapping.Name", domainMapping.Name)
+		if err := r.Delete(ctx, domainMapping); client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+	}
+
+	return statuses, nil
+}
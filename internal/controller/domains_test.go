@@ -0,0 +1,184 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	knservingv1beta1 "knative.dev/serving/pkg/apis/serving/v1beta1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestValidateDomains(t *testing.T) {
+	g := NewWithT(t)
+
+	domains := []functionsv1alpha1.FunctionDomain{{Name: "api.example.com"}}
+	g.Expect(validateDomains(&functionsv1alpha1.Function{})).To(Succeed())
+	g.Expect(validateDomains(&functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{
+		Visibility: functionsv1alpha1.VisibilityExternal,
+		Domains:    domains,
+	}}})).To(Succeed())
+
+	clusterLocal := &functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Domains: domains}}}
+	g.Expect(validateDomains(clusterLocal)).To(MatchError(ContainSubstring("visibility: external")))
+}
+
+func TestBuildDomainMapping(t *testing.T) {
+	g := NewWithT(t)
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", UID: "api-uid"},
+		Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{
+			Visibility: functionsv1alpha1.VisibilityExternal,
+			Domains: []functionsv1alpha1.FunctionDomain{
+				{Name: "api.example.com", TLS: &functionsv1alpha1.DomainTLS{SecretName: "api-example-tls"}},
+				{Name: "www.example.com", TLS: &functionsv1alpha1.DomainTLS{CertificateClass: "cert-manager.certificate.networking.knative.dev"}},
+			},
+		}},
+	}
+
+	withSecret := buildDomainMapping(function, function.Spec.Deploy.Domains[0])
+	g.Expect(withSecret.Name).To(Equal("api.example.com"))
+	g.Expect(withSecret.Labels).To(HaveKeyWithValue(FunctionNameLabel, "api"))
+	g.Expect(withSecret.Spec.Ref).To(Equal(duckv1.KReference{
+		APIVersion: "serving.knative.dev/v1",
+		Kind:       "Service",
+		Name:       "api",
+		Namespace:  "default",
+	}))
+	g.Expect(withSecret.Spec.TLS).To(Equal(&knservingv1beta1.SecretTLS{SecretName: "api-example-tls"}))
+	g.Expect(withSecret.Annotations).To(BeEmpty())
+
+	withClass := buildDomainMapping(function, function.Spec.Deploy.Domains[1])
+	g.Expect(withClass.Spec.TLS).To(BeNil())
+	g.Expect(withClass.Annotations).To(HaveKeyWithValue(certificateClassAnnotation, "cert-manager.certificate.networking.knative.dev"))
+}
+
+func TestDomainStatus(t *testing.T) {
+	g := NewWithT(t)
+
+	domainMapping := &knservingv1beta1.DomainMapping{ObjectMeta: metav1.ObjectMeta{Name: "api.example.com", Generation: 1}}
+	g.Expect(domainStatus(domainMapping)).To(Equal(functionsv1alpha1.DomainStatus{
+		Name:    "api.example.com",
+		Reason:  "Pending",
+		Message: "Aguardando o DomainMapping reportar o estado",
+	}))
+
+	domainMapping.Status.ObservedGeneration = 1
+	domainMapping.Status.URL = &apis.URL{Scheme: "https", Host: "api.example.com"}
+	domainMapping.Status.Conditions = duckv1.Conditions{{
+		Type:    apis.ConditionReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  "CertificateNotReady",
+		Message: "Certificate api.example.com is not ready",
+	}}
+	g.Expect(domainStatus(domainMapping)).To(Equal(functionsv1alpha1.DomainStatus{
+		Name:    "api.example.com",
+		URL:     "https://api.example.com",
+		Reason:  "CertificateNotReady",
+		Message: "Certificate api.example.com is not ready",
+	}))
+
+	domainMapping.Status.Conditions[0].Status = corev1.ConditionTrue
+	g.Expect(domainStatus(domainMapping).Ready).To(BeTrue())
+}
+
+func TestReconcileDomains(t *testing.T) {
+	ctx := context.Background()
+	addToScheme := []func(*runtime.Scheme) error{knservingv1beta1.AddToScheme}
+	base := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", UID: "api-uid"},
+		Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{
+			Visibility: functionsv1alpha1.VisibilityExternal,
+			Domains: []functionsv1alpha1.FunctionDomain{
+				{Name: "api.example.com", TLS: &functionsv1alpha1.DomainTLS{SecretName: "api-example-tls"}},
+				{Name: "www.example.com", TLS: &functionsv1alpha1.DomainTLS{CertificateClass: "cert-manager.certificate.networking.knative.dev"}},
+			},
+		}},
+	}
+
+	t.Run("creates a mapping per domain", func(t *testing.T) {
+		g := NewWithT(t)
+		r := newFakeReconciler(addToScheme)
+		function := base.DeepCopy()
+
+		statuses, err := r.reconcileDomains(ctx, function)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(statuses).To(HaveLen(2))
+		g.Expect(statuses[0].Name).To(Equal("api.example.com"))
+		g.Expect(statuses[0].Reason).To(Equal("Pending"))
+
+		domainMapping := &knservingv1beta1.DomainMapping{}
+		g.Expect(r.Get(ctx, types.NamespacedName{Name: "www.example.com", Namespace: "default"}, domainMapping)).To(Succeed())
+		g.Expect(metav1.IsControlledBy(domainMapping, function)).To(BeTrue())
+		g.Expect(domainMapping.Annotations).To(HaveKey(certificateClassAnnotation))
+	})
+
+	t.Run("removed domains are cleaned up", func(t *testing.T) {
+		g := NewWithT(t)
+		r := newFakeReconciler(addToScheme)
+		function := base.DeepCopy()
+		_, err := r.reconcileDomains(ctx, function)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		function.Spec.Deploy.Domains = function.Spec.Deploy.Domains[:1]
+		statuses, err := r.reconcileDomains(ctx, function)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(statuses).To(HaveLen(1))
+
+		domainMappings := &knservingv1beta1.DomainMappingList{}
+		g.Expect(r.List(ctx, domainMappings)).To(Succeed())
+		g.Expect(domainMappings.Items).To(HaveLen(1))
+		g.Expect(domainMappings.Items[0].Name).To(Equal("api.example.com"))
+
+		function.Spec.Deploy.Domains = nil
+		statuses, err = r.reconcileDomains(ctx, function)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(statuses).To(BeEmpty())
+		g.Expect(r.List(ctx, domainMappings)).To(Succeed())
+		g.Expect(domainMappings.Items).To(BeEmpty())
+	})
+
+	t.Run("mappings owned by someone else are left alone", func(t *testing.T) {
+		g := NewWithT(t)
+		foreign := &knservingv1beta1.DomainMapping{
+			ObjectMeta: metav1.ObjectMeta{Name: "api.example.com", Namespace: "default"},
+			Spec: knservingv1beta1.DomainMappingSpec{
+				Ref: duckv1.KReference{APIVersion: "serving.knative.dev/v1", Kind: "Service", Name: "legacy-api"},
+			},
+		}
+		r := newFakeReconciler(addToScheme, foreign)
+		function := base.DeepCopy()
+
+		statuses, err := r.reconcileDomains(ctx, function)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(statuses[0].Reason).To(Equal("DomainConflict"))
+		g.Expect(statuses[0].Ready).To(BeFalse())
+
+		domainMapping := &knservingv1beta1.DomainMapping{}
+		g.Expect(r.Get(ctx, types.NamespacedName{Name: "api.example.com", Namespace: "default"}, domainMapping)).To(Succeed())
+		g.Expect(domainMapping.Spec.Ref.Name).To(Equal("legacy-api"))
+	})
+}
@@ -18,14 +18,18 @@ package controller
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -38,9 +42,11 @@ import (
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	knservingv1beta1 "knative.dev/serving/pkg/apis/serving/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
@@ -50,6 +56,22 @@ import (
 type FunctionReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// BuildQueue limita os builds concorrentes (global e por namespace).
+	// Quando nil, os PipelineRuns são criados sem limite.
+	BuildQueue *BuildQueue
+
+	// PrometheusURL é a API compatível com Prometheus consultada pela análise dos rollouts canário.
+	PrometheusURL string
+	// AllowPrometheusURLOverride permite que uma Function use outro endereço em
+	// 'spec.deploy.rollout.analysis.prometheusURL'.
+	AllowPrometheusURLOverride bool
+
+	// AllowedS3Endpoints são os endpoints que 'spec.source.s3.endpoint' pode usar (esquema, host e porta).
+	AllowedS3Endpoints []string
+
+	// PermissionAllowlist limita o que 'spec.deploy.permissions' pode conceder; nil usa DefaultPermissionAllowlist.
+	PermissionAllowlist PermissionAllowlist
 }
 
 const (
@@ -67,13 +89,19 @@ const (
 // +kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=tekton.dev,resources=taskruns,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=tekton.dev,resources=tasks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=tekton.dev,resources=pipelines,verbs=get;list;watch
 // +kubebuilder:rbac:groups=serving.knative.dev,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=serving.knative.dev,resources=revisions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=serving.knative.dev,resources=domainmappings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=eventing.knative.dev,resources=triggers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=eventing.knative.dev,resources=brokers,verbs=get;list;watch
 // +kubebuilder:rbac:groups=opentelemetry.io,resources=instrumentations,verbs=get;list;watch;create;update;patch
 // +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
-// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -84,14 +112,50 @@ const (
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.21.0/pkg/reconcile
+func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.reconcileFunction(ctx, req)
+
+	var function functionsv1alpha1.Function
+	if getErr := r.Get(ctx, req.NamespacedName, &function); getErr != nil {
+		if errors.IsNotFound(getErr) {
+			readiness.forget(req.NamespacedName)
+		}
+		return result, err
+	}
+
+	// Medir o tempo entre a mudança do spec e a Function ficar Ready
+	if elapsed, ready := readiness.observe(&function, time.Now()); ready {
+		timeToReady.WithLabelValues(function.Namespace).Observe(elapsed.Seconds())
+	}
+
+	if !result.IsZero() || err != nil {
+		return result, err
+	}
+
+	// Rollouts canário avançam com o tempo (pausas entre os passos), sem eventos no cluster
+	now := time.Now()
+	requeueAfter := rolloutRequeueAfter(&function, now)
+
+	// Fontes S3 não geram eventos no cluster: voltar quando a próxima consulta ao bucket estiver prevista
+	if poll := sourcePollRequeueAfter(&function, now); poll > 0 && (requeueAfter == 0 || poll < requeueAfter) {
+		requeueAfter = poll
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// reconcileFunction holds the reconciliation phases of a Function.
 //
 //nolint:gocyclo // Monolithic reconcile function; to be refactored into phases in a follow-up
-func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *FunctionReconciler) reconcileFunction(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := logf.FromContext(ctx)
 
 	// 1. Obter o recurso 'Function' que acionou esta reconciliação
 	var function functionsv1alpha1.Function
 	if err := r.Get(ctx, req.NamespacedName, &function); err != nil {
+		if errors.IsNotFound(err) {
+			// Function removida: liberar a vaga (ou a posição na fila) de build
+			r.releaseBuildSlot(req.NamespacedName)
+		}
 		logger.Error(err, "Não foi possível buscar o recurso Function")
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
@@ -199,6 +263,20 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{RequeueAfter: time.Second}, nil
 	}
 
+	// Imagem fixada (rollback): servir a imagem sem passar pelo build
+	if function.Spec.Deploy.PinnedImage != "" {
+		logger.Info("Imagem fixada em spec.deploy.pinnedImage, ignorando o build", "PinnedImage", function.Spec.Deploy.PinnedImage)
+		// Um build aguardando na fila (ou em execução) não será mais acompanhado: liberar a vaga
+		r.releaseBuildSlot(req.NamespacedName)
+		return r.reconcileDeployment(ctx, &function)
+	}
+
+	// Preparar fontes alternativas ao Git (inline / OCI) e obter a revisão atual da fonte
+	resolved, result, err := r.reconcileSource(ctx, &function)
+	if err != nil || !result.IsZero() {
+		return result, err
+	}
+
 	pipelineRunName := function.Name + "-build"
 	pipelineRun := &tektonv1.PipelineRun{}
 
@@ -224,16 +302,67 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			if statusErr := r.Status().Update(ctx, &function); statusErr != nil {
 				logger.Error(statusErr, "Failed to update status after Task setup failure")
 			}
+			r.releaseBuildSlot(req.NamespacedName)
 			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 		}
 
 		// Validate environment variable references before creating PipelineRun
 		if result, err := r.validateEnvReferences(ctx, &function); err != nil || !result.IsZero() {
+			r.releaseBuildSlot(req.NamespacedName)
 			return result, err
 		}
 
+		// Resolver os passos de pre/post build (padrão do namespace) em uma cópia da Função
+		buildFunction := function.DeepCopy()
+		if resolved.S3Key != "" {
+			// Fixar o objeto resolvido (a partir de 'prefix') para que o build use a versão detectada
+			buildFunction.Spec.Source.S3.Key = resolved.S3Key
+			buildFunction.Spec.Source.S3.Prefix = ""
+		}
+		err := r.applyNamespaceBuildSteps(ctx, buildFunction)
+		if err == nil {
+			err = validateBuildSteps(buildFunction)
+		}
+		if err != nil {
+			logger.Error(err, "Passos de build inválidos")
+			r.releaseBuildSlot(req.NamespacedName)
+			// Os passos podem vir do ConfigMap do namespace, cuja correção não gera reconciliação da Function
+			return r.setSourceCondition(ctx, &function, "InvalidBuildSteps", err.Error(), 30*time.Second)
+		}
+
+		// Validar o Pipeline do usuário (spec.build.pipelineRef) antes de criar o PipelineRun
+		if result, err := r.validatePipelineRef(ctx, &function); err != nil || !result.IsZero() {
+			r.releaseBuildSlot(req.NamespacedName)
+			return result, err
+		}
+
+		// Aguardar uma vaga na fila de admissão de builds
+		if r.BuildQueue != nil {
+			admitted, position := r.BuildQueue.Admit(req.NamespacedName)
+			if !admitted {
+				logger.Info("Build aguardando vaga na fila de admissão", "QueuePosition", position)
+				queuedCondition := metav1.Condition{
+					Type:    "Ready",
+					Status:  metav1.ConditionFalse,
+					Reason:  "BuildQueued",
+					Message: fmt.Sprintf("Build aguardando vaga na fila (posição %d)", position),
+				}
+				meta.SetStatusCondition(&function.Status.Conditions, queuedCondition)
+				function.Status.ObservedGeneration = function.Generation
+				if err := r.Status().Update(ctx, &function); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			}
+		}
+
 		// 1. Construir o objeto PipelineRun em Go
-		newPipelineRun := r.buildPipelineRun(&function)
+		newPipelineRun := r.buildPipelineRun(buildFunction)
+
+		if resolved.Revision != "" {
+			// Registrar a revisão da fonte construída, para detectar mudanças posteriores
+			newPipelineRun.Annotations = map[string]string{SourceRevisionAnnotation: resolved.Revision}
+		}
 
 		// 2. Definir o OwnerReference [2]
 		// Isso torna o 'Function' dono do 'PipelineRun'.
@@ -245,6 +374,7 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		// 3. Criar o PipelineRun no cluster
 		if err := r.Create(ctx, newPipelineRun); err != nil {
 			logger.Error(err, "Falha ao criar PipelineRun")
+			r.releaseBuildSlot(req.NamespacedName)
 			return ctrl.Result{}, err
 		}
 
@@ -282,11 +412,66 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	// --- FIM DA LÓGICA DO PASSO 3.2.2 ---
 
+	// Pipelines via resolver só podem ser validados depois de resolvidos pelo Tekton
+	if contractErr := checkResolvedPipeline(&function, pipelineRun); contractErr != nil {
+		logger.Info("Pipeline resolvido não segue o contrato do operator", "PipelineRun.Name", pipelineRun.Name, "Error", contractErr.Error())
+		if !pipelineRun.IsDone() && pipelineRun.Spec.Status != tektonv1.PipelineRunSpecStatusCancelled {
+			pipelineRun.Spec.Status = tektonv1.PipelineRunSpecStatusCancelled
+			if err := r.Update(ctx, pipelineRun); err != nil {
+				logger.Error(err, "Falha ao cancelar PipelineRun com Pipeline inválido")
+				return ctrl.Result{}, err
+			}
+		}
+		r.releaseBuildSlot(req.NamespacedName)
+		invalidPipelineCondition := metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidPipeline",
+			Message: fmt.Sprintf("Pipeline inválido: %v", contractErr),
+		}
+		meta.SetStatusCondition(&function.Status.Conditions, invalidPipelineCondition)
+		function.Status.ObservedGeneration = function.Generation
+		if err := r.Status().Update(ctx, &function); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Acompanhar o andamento do build (task/step atual e durações) em status.build.progress.
+	// Para builds concluídos, o andamento final é persistido junto com as próximas atualizações de status.
+	progressChanged := r.updateBuildProgress(ctx, &function, pipelineRun)
+
 	// 1. Verificar se o PipelineRun terminou
 	if !pipelineRun.IsDone() {
 		logger.Info("PipelineRun is still running", "PipelineRun.Name", pipelineRun.Name)
-		// Ainda em execução, verificar novamente em 30 segundos
-		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		if r.BuildQueue != nil {
+			// Re-registrar o build em execução (recupera o estado da fila após restart do operator)
+			r.BuildQueue.MarkRunning(req.NamespacedName)
+		}
+		if progressChanged {
+			if err := r.Status().Update(ctx, &function); err != nil {
+				logger.Error(err, "Falha ao atualizar o andamento do build")
+				return ctrl.Result{}, err
+			}
+		}
+		// Ainda em execução, verificar novamente em 15 segundos (atualiza o tempo decorrido)
+		return ctrl.Result{RequeueAfter: 15 * time.Second}, nil
+	}
+
+	// O build terminou: liberar a vaga na fila de admissão
+	r.releaseBuildSlot(req.NamespacedName)
+
+	// A fonte mudou desde o último build (ex: arquivos inline editados): substituir o PipelineRun
+	if resolved.Revision != "" && pipelineRun.Annotations[SourceRevisionAnnotation] != resolved.Revision {
+		logger.Info("Fonte alterada desde o último build, iniciando novo build",
+			"PipelineRun.Name", pipelineRun.Name,
+			"Previous", pipelineRun.Annotations[SourceRevisionAnnotation],
+			"Current", resolved.Revision)
+		if err := r.Delete(ctx, pipelineRun, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Falha ao remover PipelineRun desatualizado")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Second}, nil
 	}
 
 	// 2. Verificar se falhou
@@ -306,6 +491,19 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			Reason:  "BuildFailed",
 			Message: failureMessage,
 		}
+		// Falhas em passos de usuário (testes, linters) são reportadas separadamente
+		if stepName, failed := r.failedBuildStep(ctx, &function, pipelineRun); failed {
+			logger.Info("Build step failed", "PipelineTask", stepName)
+			buildFailedCondition.Reason = "TestsFailed"
+			meta.SetStatusCondition(&function.Status.Conditions, metav1.Condition{
+				Type:    TestsFailedCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  "BuildStepFailed",
+				Message: fmt.Sprintf("O passo de build '%s' falhou", stepName),
+			})
+		} else {
+			meta.RemoveStatusCondition(&function.Status.Conditions, TestsFailedCondition)
+		}
 		meta.SetStatusCondition(&function.Status.Conditions, buildFailedCondition)
 		function.Status.ObservedGeneration = function.Generation
 		if err := r.Status().Update(ctx, &function); err != nil {
@@ -322,6 +520,10 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		if result.Name == "APP_IMAGE_DIGEST" { //
 			// Trim whitespace/newlines that may be present in the result
 			imageDigest = strings.TrimSpace(result.Value.StringVal)
+			// Pipelines do usuário podem publicar a referência completa (imagem@sha256:...)
+			if _, digest, found := strings.Cut(imageDigest, "@"); found {
+				imageDigest = digest
+			}
 			break
 		}
 	}
@@ -347,6 +549,11 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	// Construir a referência completa da imagem com o digest
 	imageWithDigest := function.Spec.Build.Image + "@" + imageDigest
 	function.Status.ImageDigest = imageWithDigest
+	function.Status.Runtime = r.buildRuntimeStatus(ctx, pipelineRun)
+	if function.Status.Build == nil {
+		function.Status.Build = &functionsv1alpha1.BuildStatus{}
+	}
+	function.Status.Build.SourceRevision = r.buildSourceRevision(ctx, pipelineRun)
 	deployingCondition := metav1.Condition{
 		Type:    "Ready",
 		Status:  metav1.ConditionUnknown,
@@ -354,19 +561,44 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		Message: "Build succeeded, deploying to Knative Service",
 	}
 	meta.SetStatusCondition(&function.Status.Conditions, deployingCondition)
+	meta.RemoveStatusCondition(&function.Status.Conditions, TestsFailedCondition)
 	function.Status.ObservedGeneration = function.Generation
 
 	if err := r.Status().Update(ctx, &function); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	return r.reconcileDeployment(ctx, &function)
+}
+
+// reconcileDeployment holds the deploy phases of a Function: the Knative Service (image, route, rollout)
+// and the Trigger. The image served comes from the last build or from spec.deploy.pinnedImage.
+//
+//nolint:gocyclo // Sequential deploy phases, kept together as in reconcileFunction
+func (r *FunctionReconciler) reconcileDeployment(ctx context.Context, function *functionsv1alpha1.Function) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+	var err error
+
 	logger.Info("Iniciando Fase 3.4: Reconciliação do Knative Service")
 
+	// A regra de reescrita acompanha a imagem implantada, que pode ser a fixada em 'spec.deploy.pinnedImage'
+	// ou ter sido construída antes de uma mudança em IMAGE_PULL_REWRITES
+	_, function.Status.ImageRewrite = rewriteImageForPull(deployedImage(function))
+
 	// Validar referências a Secrets/ConfigMaps antes de criar/atualizar o Knative Service
-	if validationResult, err := r.validateEnvReferences(ctx, &function); err != nil || validationResult.RequeueAfter > 0 {
+	if validationResult, err := r.validateEnvReferences(ctx, function); err != nil || validationResult.RequeueAfter > 0 {
+		return validationResult, err
+	}
+	if validationResult, err := r.validateVolumeReferences(ctx, function); err != nil || validationResult.RequeueAfter > 0 {
 		return validationResult, err
 	}
 
+	// Sincronizar o ConfigMap dos arquivos inline montados como volume
+	if err := r.reconcileVolumeFiles(ctx, function); err != nil {
+		logger.Error(err, "Falha ao sincronizar ConfigMap dos arquivos de volume")
+		return ctrl.Result{}, err
+	}
+
 	// Validar se o Broker existe ANTES de criar/atualizar o Knative Service
 	// Isso evita criar um KService que nunca poderá ser usado devido a broker ausente
 	if function.Spec.Eventing.Broker != "" {
@@ -384,7 +616,7 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 				}
 				meta.SetStatusCondition(&function.Status.Conditions, brokerNotFoundCondition)
 				function.Status.ObservedGeneration = function.Generation
-				if err := r.Status().Update(ctx, &function); err != nil {
+				if err := r.Status().Update(ctx, function); err != nil {
 					return ctrl.Result{}, err
 				}
 				return ctrl.Result{RequeueAfter: time.Second * 30}, nil
@@ -410,12 +642,114 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	}
 	// ------------------------------------
 
+	// Validar e resolver a divisão de tráfego (spec.deploy.traffic) e as tags (spec.deploy.tags)
+	// Alvos por digest de imagem são traduzidos para o nome da revisão Knative correspondente
+	deployFunction := function
+	if len(function.Spec.Deploy.Traffic) > 0 || len(function.Spec.Deploy.Tags) > 0 {
+		if err := validateTraffic(function); err != nil {
+			return r.setInvalidSpecCondition(ctx, function, "InvalidTraffic", err)
+		}
+
+		traffic, tags, err := r.resolveTraffic(ctx, function)
+		var notFound *revisionNotFoundError
+		if stderrors.As(err, &notFound) {
+			logger.Info("Revisão do alvo de tráfego não encontrada", "Digest", notFound.digest)
+			revisionNotFoundCondition := metav1.Condition{
+				Type:    "Ready",
+				Status:  metav1.ConditionFalse,
+				Reason:  "RevisionNotFound",
+				Message: err.Error(),
+			}
+			meta.SetStatusCondition(&function.Status.Conditions, revisionNotFoundCondition)
+			function.Status.ObservedGeneration = function.Generation
+			if err := r.Status().Update(ctx, function); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: time.Second * 30}, nil
+		} else if err != nil {
+			logger.Error(err, "Falha ao listar revisões do Knative Service")
+			return ctrl.Result{}, err
+		}
+		deployFunction = function.DeepCopy()
+		deployFunction.Spec.Deploy.Traffic = traffic
+		deployFunction.Spec.Deploy.Tags = tags
+	}
+
+	// Validar o rollout canário (spec.deploy.rollout)
+	if err := validateRollout(function); err != nil {
+		return r.setInvalidSpecCondition(ctx, function, "InvalidRollout", err)
+	}
+	if function.Spec.Deploy.Rollout == nil {
+		function.Status.Rollout = nil
+	} else if function.Spec.Deploy.Rollout.Analysis != nil {
+		if _, err := r.rolloutPrometheusURL(function); err != nil {
+			return r.setInvalidSpecCondition(ctx, function, "InvalidRollout", err)
+		}
+	}
+
+	// Validar a porta de serviço (spec.deploy.port) e a coerência com o Dapr
+	if err := validatePort(function); err != nil {
+		return r.setInvalidSpecCondition(ctx, function, "InvalidPort", err)
+	}
+
+	// Validar a configuração de autoscaling (spec.deploy.scale)
+	if err := validateScale(function); err != nil {
+		return r.setInvalidSpecCondition(ctx, function, "InvalidScale", err)
+	}
+
+	// Validar os domínios customizados (spec.deploy.domains)
+	if err := validateDomains(function); err != nil {
+		return r.setInvalidSpecCondition(ctx, function, "InvalidDomains", err)
+	}
+
+	// Validar os sidecars e init containers (spec.deploy.sidecars, spec.deploy.initContainers)
+	if err := validateContainers(function); err != nil {
+		return r.setInvalidSpecCondition(ctx, function, "InvalidContainers", err)
+	}
+
+	// Ler as feature flags do Knative uma única vez: valem para a validação e para os padrões da revisão
+	features, err := r.knativeFeatures(ctx)
+	if err != nil {
+		logger.Error(err, "Falha ao ler as feature flags do Knative")
+		return ctrl.Result{}, err
+	}
+
+	// Validar o agendamento e a segurança do pod (spec.deploy.pod) contra as feature flags do Knative
+	if err := validatePodSettings(function, features); err != nil {
+		return r.setInvalidSpecCondition(ctx, function, "InvalidPodSettings", err)
+	}
+
+	// Validar os volumes (spec.deploy.volumes) contra as feature flags do Knative
+	if err := validateVolumeFeatures(function, features); err != nil {
+		return r.setInvalidSpecCondition(ctx, function, "InvalidVolumes", err)
+	}
+
+	// Validar as permissões na API do Kubernetes (spec.deploy.permissions)
+	if err := validatePermissions(function, r.permissionAllowlist()); err != nil {
+		return r.setInvalidSpecCondition(ctx, function, "InvalidPermissions", err)
+	}
+
+	// Sincronizar o ServiceAccount de runtime e o RBAC gerado antes da revisão que os usa
+	var identityConflict *runtimeIdentityConflictError
+	if err := r.reconcileRuntimeIdentity(ctx, function); stderrors.As(err, &identityConflict) {
+		// Sem watch nos objetos de terceiros: verificar de novo até que sejam removidos ou renomeados
+		logger.Info("Identidade de runtime em conflito com um objeto existente", "Kind", identityConflict.kind, "Name", identityConflict.key.Name)
+		return r.setSourceCondition(ctx, function, "RuntimeIdentityConflict", err.Error(), time.Minute)
+	} else if errors.IsForbidden(err) {
+		// O API server recusa Roles com permissões que o próprio operator não tem
+		return r.setSourceCondition(ctx, function, "InvalidPermissions",
+			"O operator não tem as permissões de spec.deploy.permissions para concedê-las: "+err.Error(), time.Minute)
+	} else if err != nil {
+		logger.Error(err, "Falha ao sincronizar o ServiceAccount de runtime e suas permissões")
+		return ctrl.Result{}, err
+	}
+
 	knativeServiceName := function.Name
 	knativeService := &knservingv1.Service{}
 
 	// 1. Construir o estado DESEJADO do Knative Service
 	// Fazemos isso primeiro para que possamos usá-lo tanto para criar quanto para comparar/atualizar.
-	desiredKsvc := r.buildKnativeService(&function)
+	desiredKsvc := r.buildKnativeService(deployFunction)
 
 	// 2. Tentar obter o estado ATUAL do Knative Service no cluster
 	err = r.Get(ctx, types.NamespacedName{Name: knativeServiceName, Namespace: function.Namespace}, knativeService)
@@ -426,7 +760,7 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 		// 3. Definir o OwnerReference
 		// Isso é CRÍTICO para que o 'Function' gerencie o ciclo de vida do 'KnativeService' [1, 2]
-		if err := controllerutil.SetControllerReference(&function, desiredKsvc, r.Scheme); err != nil {
+		if err := controllerutil.SetControllerReference(function, desiredKsvc, r.Scheme); err != nil {
 			logger.Error(err, "Falha ao definir OwnerReference no Knative Service")
 			return ctrl.Result{}, err
 		}
@@ -452,19 +786,25 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	// Se chegamos aqui, o Knative Service FOI encontrado.
 	logger.Info("Knative Service encontrado. Verificando se há atualizações...")
 
-	needsUpdate = false
-
-	// 1. Verificar se a imagem está desatualizada
-	// Compara a imagem no cluster com a imagem que acabamos de construir
-	// NOTA: O Knative Service cria um PodSpec com UM contêiner.
-	currentImage := ""
-	if len(knativeService.Spec.Template.Spec.Containers) > 0 {
-		currentImage = knativeService.Spec.Template.Spec.Containers[0].Image
+	// Com rollout canário, a rota é definida pelo andamento do rollout (passos, análise e rollback)
+	// Uma imagem fixada suspende o rollout
+	if function.Spec.Deploy.Rollout != nil && function.Spec.Deploy.PinnedImage == "" {
+		traffic, err := r.reconcileRollout(ctx, function, knativeService, desiredKsvc.Spec.Template.Spec.Containers[0].Image)
+		if err != nil {
+			logger.Error(err, "Falha ao atualizar o rollout")
+			return ctrl.Result{}, err
+		}
+		desiredKsvc.Spec.Traffic = append(traffic, knativeTagTargets(deployFunction.Spec.Deploy.Tags)...)
 	}
-	desiredImage := desiredKsvc.Spec.Template.Spec.Containers[0].Image
 
-	if currentImage != desiredImage {
-		logger.Info("ImageDigest desatualizado, marcando para atualização.", "Atual", currentImage, "Desejado", desiredImage)
+	needsUpdate := false
+
+	// 1. Verificar se as imagens estão desatualizadas
+	// Compara as imagens de todos os containers no cluster (função, sidecars e init containers)
+	// com as desejadas; a da função é a que acabamos de construir.
+	currentImages, desiredImages := containerImages(knativeService), containerImages(desiredKsvc)
+	if !slices.Equal(currentImages, desiredImages) {
+		logger.Info("ImageDigest desatualizado, marcando para atualização.", "Atual", currentImages, "Desejado", desiredImages)
 		needsUpdate = true
 	}
 
@@ -482,49 +822,55 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 	}
 
-	// 3. Verificar se as variáveis de ambiente mudaram
-	if len(knativeService.Spec.Template.Spec.Containers) > 0 && len(desiredKsvc.Spec.Template.Spec.Containers) > 0 {
-		currentEnv := knativeService.Spec.Template.Spec.Containers[0].Env
-		desiredEnv := desiredKsvc.Spec.Template.Spec.Containers[0].Env
-		currentEnvFrom := knativeService.Spec.Template.Spec.Containers[0].EnvFrom
-		desiredEnvFrom := desiredKsvc.Spec.Template.Spec.Containers[0].EnvFrom
+	// 3. Verificar se os containers mudaram (sidecars adicionados ou removidos, comandos, variáveis de ambiente)
+	if !needsUpdate && containersChanged(knativeService, desiredKsvc) {
+		logger.Info("Containers ou variáveis de ambiente mudaram, marcando para atualização.")
+		needsUpdate = true
+	}
 
-		// Comparar Env
-		if len(currentEnv) != len(desiredEnv) {
-			logger.Info("Variáveis de ambiente mudaram (tamanho diferente), marcando para atualização.")
-			needsUpdate = true
-		} else {
-			// Comparação simples: serializar para string e comparar
-			// Isso funciona porque a ordem importa e queremos detectar qualquer mudança
-			currentEnvStr := fmt.Sprintf("%+v", currentEnv)
-			desiredEnvStr := fmt.Sprintf("%+v", desiredEnv)
-			if currentEnvStr != desiredEnvStr {
-				logger.Info("Variáveis de ambiente mudaram, marcando para atualização.")
-				needsUpdate = true
-			}
-		}
+	// Os padrões do Knative completam o Service desejado: campos omitidos não geram atualizações,
+	// e campos removidos da Function voltam ao padrão
+	defaultedKsvc, err := r.withKnativeDefaults(ctx, desiredKsvc, features)
+	if err != nil {
+		logger.Error(err, "Falha ao ler os padrões do Knative")
+		return ctrl.Result{}, err
+	}
 
-		// Comparar EnvFrom
-		if !needsUpdate {
-			if len(currentEnvFrom) != len(desiredEnvFrom) {
-				logger.Info("EnvFrom mudou (tamanho diferente), marcando para atualização.")
-				needsUpdate = true
-			} else {
-				currentEnvFromStr := fmt.Sprintf("%+v", currentEnvFrom)
-				desiredEnvFromStr := fmt.Sprintf("%+v", desiredEnvFrom)
-				if currentEnvFromStr != desiredEnvFromStr {
-					logger.Info("EnvFrom mudou, marcando para atualização.")
-					needsUpdate = true
-				}
-			}
-		}
+	// 4. Verificar se porta, recursos, concorrência ou timeouts mudaram
+	if !needsUpdate && revisionSettingsChanged(knativeService, defaultedKsvc) {
+		logger.Info("Porta, recursos, concorrência ou timeouts mudaram, marcando para atualização.")
+		needsUpdate = true
+	}
+
+	// 5. Verificar se as probes mudaram
+	if !needsUpdate && probesChanged(knativeService, desiredKsvc) {
+		logger.Info("Probes mudaram, marcando para atualização.")
+		needsUpdate = true
+	}
+
+	// 6. Verificar se os volumes mudaram
+	if !needsUpdate && volumesChanged(knativeService, desiredKsvc) {
+		logger.Info("Volumes mudaram, marcando para atualização.")
+		needsUpdate = true
+	}
+
+	// 7. Verificar se o ServiceAccount, o agendamento ou o securityContext do pod mudaram
+	if !needsUpdate && podSettingsChanged(knativeService, defaultedKsvc) {
+		logger.Info("ServiceAccount, agendamento ou securityContext do pod mudaram, marcando para atualização.")
+		needsUpdate = true
+	}
+
+	// 8. Verificar se a divisão de tráfego mudou
+	if !needsUpdate && !equality.Semantic.DeepEqual(knativeService.Spec.Traffic, desiredKsvc.Spec.Traffic) {
+		logger.Info("Divisão de tráfego mudou, marcando para atualização.")
+		needsUpdate = true
 	}
 
-	// 4. Executar a atualização se necessário
+	// 9. Executar a atualização se necessário
 	if needsUpdate {
 		logger.Info("Atualizando Knative Service...")
-		// Atualiza o spec do objeto existente com o spec desejado
-		knativeService.Spec = desiredKsvc.Spec
+		// Atualiza o spec do objeto existente com o spec desejado (já com os padrões do Knative)
+		knativeService.Spec = defaultedKsvc.Spec
 		if err := r.Update(ctx, knativeService); err != nil {
 			logger.Error(err, "Falha ao atualizar Knative Service")
 			return ctrl.Result{}, err
@@ -540,6 +886,16 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	if knativeService.Status.URL != nil {
 		function.Status.URL = knativeService.Status.URL.String()
 	}
+	function.Status.Traffic = trafficStatus(knativeService)
+	function.Status.TagURLs = tagURLs(function, knativeService)
+
+	// Sincronizar os DomainMappings dos domínios customizados
+	domains, err := r.reconcileDomains(ctx, function)
+	if err != nil {
+		logger.Error(err, "Falha ao sincronizar DomainMappings")
+		return ctrl.Result{}, err
+	}
+	function.Status.Domains = domains
 
 	ksvcReady := knativeService.Status.GetCondition("Ready")
 	if ksvcReady == nil {
@@ -551,7 +907,7 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 		meta.SetStatusCondition(&function.Status.Conditions, deployingCondition)
 		function.Status.ObservedGeneration = function.Generation
-		if err := r.Status().Update(ctx, &function); err != nil {
+		if err := r.Status().Update(ctx, function); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
@@ -566,7 +922,7 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 		meta.SetStatusCondition(&function.Status.Conditions, notReadyCondition)
 		function.Status.ObservedGeneration = function.Generation
-		if err := r.Status().Update(ctx, &function); err != nil {
+		if err := r.Status().Update(ctx, function); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
@@ -581,13 +937,14 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 		meta.SetStatusCondition(&function.Status.Conditions, deployingCondition)
 		function.Status.ObservedGeneration = function.Generation
-		if err := r.Status().Update(ctx, &function); err != nil {
+		if err := r.Status().Update(ctx, function); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
 	logger.Info("Knative Service is ready")
+	updateRevisionHistory(function, knativeService, metav1.Now())
 
 	// Se 'eventing' não estiver configurado, limpar qualquer Trigger existente e marcar como Ready.
 	if function.Spec.Eventing.Broker == "" {
@@ -608,15 +965,10 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			return ctrl.Result{}, err
 		}
 
-		readyCondition := metav1.Condition{
-			Type:    "Ready",
-			Status:  metav1.ConditionTrue,
-			Reason:  "Ready",
-			Message: "Function deployed and ready to accept requests",
-		}
+		readyCondition := deployedCondition(function, "Function deployed and ready to accept requests")
 		meta.SetStatusCondition(&function.Status.Conditions, readyCondition)
 		function.Status.ObservedGeneration = function.Generation
-		if err := r.Status().Update(ctx, &function); err != nil {
+		if err := r.Status().Update(ctx, function); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{}, nil
@@ -631,10 +983,10 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		// Trigger não encontrado, vamos criar.
 
 		// 1. Construir o Trigger
-		newTrigger := r.buildKnativeTrigger(&function) // Função helper (ver abaixo)
+		newTrigger := r.buildKnativeTrigger(function) // Função helper (ver abaixo)
 
 		// 2. Definir OwnerReference
-		if err := controllerutil.SetControllerReference(&function, newTrigger, r.Scheme); err != nil {
+		if err := controllerutil.SetControllerReference(function, newTrigger, r.Scheme); err != nil {
 			return ctrl.Result{}, err
 		}
 
@@ -644,16 +996,11 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			return ctrl.Result{}, err
 		}
 
-		readyCondition := metav1.Condition{
-			Type:    "Ready",
-			Status:  metav1.ConditionTrue,
-			Reason:  "Ready",
-			Message: "Function deployed with eventing and ready to accept requests",
-		}
+		readyCondition := deployedCondition(function, "Function deployed with eventing and ready to accept requests")
 		// 4. Atualizar Status para "Ready"
 		meta.SetStatusCondition(&function.Status.Conditions, readyCondition)
 		function.Status.ObservedGeneration = function.Generation
-		if err := r.Status().Update(ctx, &function); err != nil {
+		if err := r.Status().Update(ctx, function); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{}, nil // Fim!
@@ -668,7 +1015,7 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	logger.Info("Knative Trigger encontrado. Verificando se há atualizações...")
 
 	// Construir o Trigger desejado
-	desiredTrigger := r.buildKnativeTrigger(&function)
+	desiredTrigger := r.buildKnativeTrigger(function)
 
 	needsUpdate = false
 
@@ -717,20 +1064,14 @@ func (r *FunctionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	logger.Info("Knative Trigger está sincronizado.")
 
-	readyCondition := metav1.Condition{
-		Type:    "Ready",
-		Status:  metav1.ConditionTrue,
-		Reason:  "Ready",
-		Message: "Function deployed with eventing and ready to accept requests",
-	}
+	readyCondition := deployedCondition(function, "Function deployed with eventing and ready to accept requests")
 	meta.SetStatusCondition(&function.Status.Conditions, readyCondition)
 	function.Status.ObservedGeneration = function.Generation
-	if err := r.Status().Update(ctx, &function); err != nil {
+	if err := r.Status().Update(ctx, function); err != nil {
 		return ctrl.Result{}, err
 	}
 
 	return ctrl.Result{}, nil // Tudo pronto.
-
 }
 
 /*
@@ -918,6 +1259,13 @@ func (r *FunctionReconciler) buildPipelineParams(function *functionsv1alpha1.Fun
 		{Name: "CNB_PROCESS_TYPE", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: ""}},
 	}
 
+	if function.Spec.Build.SourceSubpath != "" {
+		params = append(params, tektonv1.Param{
+			Name:  "SOURCE_SUBPATH",
+			Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: function.Spec.Build.SourceSubpath},
+		})
+	}
+
 	// Determinar registries inseguros usando lógica inteligente
 	insecureRegistries := r.detectInsecureRegistries(function.Spec.Build.Image)
 	if insecureRegistries != "" {
@@ -984,7 +1332,7 @@ func (r *FunctionReconciler) detectInsecureRegistries(imageURL string) string {
 /*
 buildPipelineRun constrói um *tektonv1.PipelineRun em memória.
 Este PipelineRun é projetado para:
- 1. Clonar um repositório Git usando a Task 'git-clone'.
+ 1. Clonar um repositório Git usando a Task 'git-clone' (ou materializar a fonte inline/OCI com 'zenith-source-fetch').
  2. Construir uma imagem de contêiner usando Cloud Native Buildpacks com a Task 'buildpacks-phases'.
  3. Enviar a imagem para o registry especificado.
 */
@@ -1000,7 +1348,19 @@ func (r *FunctionReconciler) buildPipelineRun(function *functionsv1alpha1.Functi
 	serviceAccountName := function.Name + "-sa"
 	const sharedWorkspaceName = "source-workspace"
 
-	return &tektonv1.PipelineRun{
+	// Passos de usuário: preBuild roda após o clone, postBuild após o push.
+	// Todos compartilham o workspace do código-fonte.
+	preBuildTasks := buildStepPipelineTasks(function.Spec.Build.PreBuild, preBuildTaskPrefix, []string{"fetch-source"}, sharedWorkspaceName)
+	postBuildTasks := buildStepPipelineTasks(function.Spec.Build.PostBuild, postBuildTaskPrefix, []string{"build-and-push"}, sharedWorkspaceName)
+	buildRunAfter := []string{"fetch-source"}
+	for _, task := range preBuildTasks {
+		buildRunAfter = append(buildRunAfter, task.Name)
+	}
+
+	// A task 'fetch-source' materializa o código no workspace compartilhado
+	fetchSourceTask, sourceWorkspaces, sourceBindings := r.sourceFetchPipelineTask(function, gitRevision, sharedWorkspaceName)
+
+	pipelineRun := &tektonv1.PipelineRun{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      pipelineRunName,
 			Namespace: function.Namespace,
@@ -1009,6 +1369,8 @@ func (r *FunctionReconciler) buildPipelineRun(function *functionsv1alpha1.Functi
 			// Vincula o PipelineRun ao ServiceAccount dedicado que tem as credenciais
 			TaskRunTemplate: tektonv1.PipelineTaskRunTemplate{
 				ServiceAccountName: serviceAccountName,
+				// Controles de agendamento dos pods de build (padrão do cluster + spec.build.podTemplate)
+				PodTemplate: buildPipelinePodTemplate(function),
 			},
 
 			// 'pipelineSpec' define um pipeline embutido [4]
@@ -1031,26 +1393,9 @@ func (r *FunctionReconciler) buildPipelineRun(function *functionsv1alpha1.Functi
 
 				// 2. DEFINIÇÃO DAS TASKS:
 				// Isto é um slice de 'PipelineTask'.
-				Tasks: []tektonv1.PipelineTask{
-					// --- Task 1: Git Clone ---
-					{
-						Name: "fetch-source",
-						TaskRef: &tektonv1.TaskRef{
-							Name: "git-clone", // Refere-se à Task 'git-clone' instalada [5]
-						},
-						// 'Workspaces' aqui é um slice de 'WorkspacePipelineTaskBinding'
-						Workspaces: []tektonv1.WorkspacePipelineTaskBinding{
-							{
-								Name:      "output",            // A task 'git-clone' define seu workspace de saída como 'output'
-								Workspace: sharedWorkspaceName, // Mapeia para o workspace 'source-workspace' do pipeline
-							},
-						},
-						// 'Params' é um slice de 'Param'
-						Params: []tektonv1.Param{
-							{Name: "url", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: function.Spec.GitRepo}},
-							{Name: "revision", Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: gitRevision}},
-						},
-					},
+				Tasks: append(append([]tektonv1.PipelineTask{
+					// --- Task 1: Fetch Source (git-clone, ou zenith-source-fetch para fontes inline/OCI) ---
+					fetchSourceTask,
 					// --- Task 2: Buildpacks ---
 					{
 						Name: "build-and-push",
@@ -1058,7 +1403,7 @@ func (r *FunctionReconciler) buildPipelineRun(function *functionsv1alpha1.Functi
 							Name: "buildpacks-phases", // Refere-se à Task 'buildpacks-phases' instalada [5]
 						},
 						// 'RunAfter' é um slice de 'string' [5]
-						RunAfter: []string{"fetch-source"}, // Garante que o clone termine antes do build começar
+						RunAfter: buildRunAfter, // Garante que o clone (e os passos de preBuild) terminem antes do build começar
 
 						// 'Workspaces' aqui é um slice de 'WorkspacePipelineTaskBinding'
 						Workspaces: []tektonv1.WorkspacePipelineTaskBinding{
@@ -1069,7 +1414,7 @@ func (r *FunctionReconciler) buildPipelineRun(function *functionsv1alpha1.Functi
 						},
 						Params: r.buildPipelineParams(function),
 					},
-				},
+				}, preBuildTasks...), postBuildTasks...),
 			},
 
 			// 3. VINCULAÇÃO DE WORKSPACE (Workspace Binding):
@@ -1094,11 +1439,26 @@ func (r *FunctionReconciler) buildPipelineRun(function *functionsv1alpha1.Functi
 			},
 		},
 	}
+
+	// Workspaces adicionais da fonte (ex: ConfigMap com o código inline)
+	pipelineRun.Spec.PipelineSpec.Workspaces = append(pipelineRun.Spec.PipelineSpec.Workspaces, sourceWorkspaces...)
+	pipelineRun.Spec.Workspaces = append(pipelineRun.Spec.Workspaces, sourceBindings...)
+
+	// Pipeline do usuário (spec.build.pipelineRef): substitui o pipeline embutido,
+	// passando os parâmetros do contrato e vinculando o workspace 'source'.
+	if function.Spec.Build.PipelineRef != nil {
+		pipelineRun.Spec.PipelineSpec = nil
+		pipelineRun.Spec.PipelineRef = tektonPipelineRef(function.Spec.Build.PipelineRef)
+		pipelineRun.Spec.Params = pipelineRefParams(function, gitRevision)
+		pipelineRun.Spec.Workspaces[0].Name = PipelineWorkspaceSource
+	}
+
+	return pipelineRun
 }
 
 /*
 buildKnativeService constrói um objeto *knservingv1.Service em memória
-baseado no Spec da Função e no ImageDigest do Status.
+baseado no Spec da Função e na imagem a servir (o ImageDigest do Status ou a imagem fixada).
 Ele adere à API 'v1' do Knative Serving, onde o ServiceSpec
 contém ConfigurationSpec e RouteSpec embutidos.
 */
@@ -1107,10 +1467,16 @@ func (r *FunctionReconciler) buildKnativeService(function *functionsv1alpha1.Fun
 	// --- Ponto de Integração do Dapr ---
 	// Estas anotações devem ser aplicadas ao TEMPLATE do Pod.[3, 4]
 	podAnnotations := make(map[string]string)
+	// Porta de serviço do container (spec.deploy.port, ou o padrão compatível com o Dapr)
+	containerPort := functionContainerPort(function)
 	if function.Spec.Deploy.Dapr.Enabled {
 		podAnnotations["dapr.io/enabled"] = annotationValueTrue
 		podAnnotations["dapr.io/app-id"] = function.Spec.Deploy.Dapr.AppID
-		podAnnotations["dapr.io/app-port"] = strconv.Itoa(function.Spec.Deploy.Dapr.AppPort)
+		// O app-port do Dapr acompanha a porta da função
+		podAnnotations["dapr.io/app-port"] = strconv.Itoa(int(containerPort.ContainerPort))
+		if containerPort.Name == string(functionsv1alpha1.PortProtocolH2C) {
+			podAnnotations["dapr.io/app-protocol"] = "h2c"
+		}
 		// Use a different metrics port to avoid conflict with Knative's queue-proxy
 		// which also uses port 9090 for http-autometric
 		podAnnotations["dapr.io/metrics-port"] = "9095"
@@ -1131,54 +1497,47 @@ func (r *FunctionReconciler) buildKnativeService(function *functionsv1alpha1.Fun
 	// ------------------------------------
 
 	// --- Ponto de Integração de Autoscaling ---
-	// Adicionar anotações de scale se configuradas
-	if function.Spec.Deploy.Scale != nil {
-		if function.Spec.Deploy.Scale.MinScale != nil {
-			podAnnotations["autoscaling.knative.dev/min-scale"] = strconv.Itoa(int(*function.Spec.Deploy.Scale.MinScale))
-		}
-		if function.Spec.Deploy.Scale.MaxScale != nil {
-			podAnnotations["autoscaling.knative.dev/max-scale"] = strconv.Itoa(int(*function.Spec.Deploy.Scale.MaxScale))
-		}
+	// Adicionar anotações autoscaling.knative.dev/* se configuradas
+	for k, v := range autoscalingAnnotations(function.Spec.Deploy.Scale) {
+		podAnnotations[k] = v
 	}
 	// ------------------------------------
 
-	// Determinar a porta do container
-	containerPort := int32(8080)
-	if function.Spec.Deploy.Dapr.Enabled && function.Spec.Deploy.Dapr.AppPort > 0 {
-		containerPort = int32(function.Spec.Deploy.Dapr.AppPort)
-	}
-
 	// Construir a definição do container
 	// Usa diretamente os campos nativos do Kubernetes para Env e EnvFrom
 	// IMPORTANTE: Knative não suporta fieldRef/resourceFieldRef, então resolve-se esses valores aqui
 	resolvedEnv := r.resolveEnvVars(function)
 
-	// Use the image digest directly from the Function status
-	// The internal registry (registry.registry.svc.cluster.local:5000) is accessible
-	// from within the cluster via the internal DNS name
-	image := function.Status.ImageDigest
-
-	// Rewrite the image URL for Kind/Minikube compatibility
-	// Containerd on Kind nodes cannot resolve Kubernetes internal DNS names,
-	// so we need to use the NodePort service (127.0.0.1:30500) instead.
-	// The Helm chart creates a NodePort service on port 30500 for this purpose.
-	if strings.HasPrefix(image, "registry.registry.svc.cluster.local:5000") {
-		image = strings.Replace(image, "registry.registry.svc.cluster.local:5000", "127.0.0.1:30500", 1)
-	}
+	// Use the image digest from the Function status (or the pinned image), translated to the endpoint
+	// the nodes pull from. The push registry is not always reachable by the
+	// container runtime (e.g. Kind nodes cannot resolve cluster-internal DNS names),
+	// so the operator applies the pull-through rewrite rules configured in IMAGE_PULL_REWRITES.
+	image, _ := rewriteImageForPull(deployedImage(function))
 
 	container := v1.Container{
 		// Usa o digest do build bem-sucedido da Fase 3.3
-		Image: image,
-		Ports: []v1.ContainerPort{ // Ports é um slice
-			{
-				// Informa ao Knative a porta que o contêiner da aplicação escuta
-				// Isso é importante para o Dapr saber para onde encaminhar [4, 5]
-				ContainerPort: containerPort,
-			},
-		},
+		Image:   image,
 		Env:     resolvedEnv,
 		EnvFrom: function.Spec.Deploy.EnvFrom,
 	}
+	if function.Spec.Deploy.Resources != nil {
+		container.Resources = *function.Spec.Deploy.Resources
+	}
+	// Informa ao Knative a porta (e o protocolo) que o contêiner da aplicação escuta
+	// Isso é importante para o Dapr saber para onde encaminhar [4, 5]
+	// Se um sidecar recebe o tráfego (ex: proxy de autenticação), a porta e as probes ficam com ele
+	if servingSidecar(function) == nil {
+		container.Ports = []v1.ContainerPort{containerPort}
+		applyProbes(&container, function)
+	}
+
+	// Volumes (Secrets, ConfigMaps, emptyDir, arquivos inline...) montados no container
+	volumes, volumeMounts := functionVolumes(function)
+	container.VolumeMounts = volumeMounts
+	if checksum := volumeFilesChecksum(function); checksum != "" {
+		// Editar um arquivo inline gera uma nova revisão, que monta o conteúdo atualizado
+		podAnnotations[VolumeFilesChecksumAnnotation] = checksum
+	}
 
 	// Construir labels para o Knative Service
 	// A visibilidade é controlada pela label networking.knative.dev/visibility
@@ -1217,25 +1576,37 @@ func (r *FunctionReconciler) buildKnativeService(function *functionsv1alpha1.Fun
 						// RevisionSpec incorpora (inlines) um corev1.PodSpec [6]
 						// Nós preenchemos os campos relevantes do PodSpec aqui.
 						PodSpec: v1.PodSpec{
-							Containers: []v1.Container{container}, // Containers é um slice [6]
+							// Containers é um slice [6]: a função primeiro, seguida dos sidecars
+							Containers:     append([]v1.Container{container}, sidecarContainers(function)...),
+							InitContainers: initContainers(function),
+							Volumes:        volumes,
+							// A revisão roda com o ServiceAccount de runtime, não o do build
+							ServiceAccountName: runtimeServiceAccountName(function),
 							// Outros campos do PodSpec podem ser definidos aqui se necessário
 						},
 						// ------------------------
 
-						// (Campos opcionais do RevisionSpec podem ser definidos aqui)
-						// ContainerConcurrency: &int64{100},
-						// TimeoutSeconds: &int64{300},
+						// Concorrência e timeouts de requisição (nil mantém os padrões do Knative)
+						ContainerConcurrency:        function.Spec.Deploy.ContainerConcurrency,
+						TimeoutSeconds:              function.Spec.Deploy.TimeoutSeconds,
+						ResponseStartTimeoutSeconds: function.Spec.Deploy.ResponseStartTimeoutSeconds,
+						IdleTimeoutSeconds:          function.Spec.Deploy.IdleTimeoutSeconds,
 					},
 				},
 			},
 
 			// 3. 'RouteSpec' também é embutido [5]
-			// Deixamos em branco para usar o comportamento padrão do Knative:
-			// 100% do tráfego para a "latestReadyRevision".
-			RouteSpec: knservingv1.RouteSpec{},
+			// Sem 'spec.deploy.traffic', 100% do tráfego vai para a "latestReadyRevision".
+			// As tags de 'spec.deploy.tags' entram na rota com 0% do tráfego.
+			RouteSpec: knservingv1.RouteSpec{
+				Traffic: append(knativeTrafficTargets(function.Spec.Deploy.Traffic), knativeTagTargets(function.Spec.Deploy.Tags)...),
+			},
 		},
 	}
 
+	// Agendamento e securityContext do pod (spec.deploy.pod)
+	applyPodSettings(&ksvc.Spec.Template.Spec.PodSpec, function)
+
 	return ksvc
 }
 
@@ -1281,64 +1652,69 @@ func (r *FunctionReconciler) resolveEnvVars(function *functionsv1alpha1.Function
 	}
 
 	for _, envVar := range function.Spec.Deploy.Env {
-		// Se não tem valueFrom, ou se tem secretKeyRef/configMapKeyRef, manter como está
-		if envVar.ValueFrom == nil ||
-			envVar.ValueFrom.SecretKeyRef != nil ||
-			envVar.ValueFrom.ConfigMapKeyRef != nil {
-			resolved = append(resolved, envVar)
-			continue
-		}
-
-		// Resolver fieldRef
-		if envVar.ValueFrom.FieldRef != nil {
-			fieldPath := envVar.ValueFrom.FieldRef.FieldPath
-			var value string
-
-			switch fieldPath {
-			case "metadata.name":
-				value = function.Name
-			case "metadata.namespace":
-				value = function.Namespace
-			case "metadata.uid":
-				value = string(function.UID)
-			case "metadata.labels":
-				// Não é possível resolver labels como string única
-				value = ""
-			case "metadata.annotations":
-				// Não é possível resolver annotations como string única
-				value = ""
-			default:
-				// Para campos não suportados, deixar vazio
-				value = ""
-			}
-
-			resolved = append(resolved, v1.EnvVar{
-				Name:  envVar.Name,
-				Value: value,
-			})
-			continue
-		}
-
-		// Resolver resourceFieldRef
-		if envVar.ValueFrom.ResourceFieldRef != nil {
-			// ResourceFieldRef requer acesso ao Pod real para obter valores de recursos
-			// Como estamos criando o Knative Service antes do Pod existir,
-			// não podemos resolver esses valores aqui.
-			// A melhor abordagem é deixar vazio ou usar um valor padrão.
-			resolved = append(resolved, v1.EnvVar{
-				Name:  envVar.Name,
-				Value: "", // Knative não suporta, então deixamos vazio
-			})
-			continue
-		}
-
-		// Se chegou aqui, manter o envVar original
-		resolved = append(resolved, envVar)
+		resolved = append(resolved, resolveEnvVar(function, envVar))
 	}
 
 	return resolved
 }
 
+// resolveEnvVar resolve fieldRef e resourceFieldRef de uma variável para um valor estático.
+// Secret e ConfigMap refs são mantidos pois Knative os suporta nativamente.
+func resolveEnvVar(function *functionsv1alpha1.Function, envVar v1.EnvVar) v1.EnvVar {
+	// Se não tem valueFrom, ou se tem secretKeyRef/configMapKeyRef, manter como está
+	if envVar.ValueFrom == nil ||
+		envVar.ValueFrom.SecretKeyRef != nil ||
+		envVar.ValueFrom.ConfigMapKeyRef != nil {
+		return envVar
+	}
+
+	// Resolver fieldRef
+	if envVar.ValueFrom.FieldRef != nil {
+		fieldPath := envVar.ValueFrom.FieldRef.FieldPath
+		var value string
+
+		switch fieldPath {
+		case "metadata.name":
+			value = function.Name
+		case "metadata.namespace":
+			value = function.Namespace
+		case "metadata.uid":
+			value = string(function.UID)
+		case "spec.serviceAccountName":
+			value = runtimeServiceAccountName(function)
+		case "metadata.labels":
+			// Não é possível resolver labels como string única
+			value = ""
+		case "metadata.annotations":
+			// Não é possível resolver annotations como string única
+			value = ""
+		default:
+			// Para campos não suportados, deixar vazio
+			value = ""
+		}
+
+		return v1.EnvVar{
+			Name:  envVar.Name,
+			Value: value,
+		}
+	}
+
+	// Resolver resourceFieldRef
+	if envVar.ValueFrom.ResourceFieldRef != nil {
+		// ResourceFieldRef requer acesso ao Pod real para obter valores de recursos
+		// Como estamos criando o Knative Service antes do Pod existir,
+		// não podemos resolver esses valores aqui.
+		// A melhor abordagem é deixar vazio ou usar um valor padrão.
+		return v1.EnvVar{
+			Name:  envVar.Name,
+			Value: "", // Knative não suporta, então deixamos vazio
+		}
+	}
+
+	// Se chegou aqui, manter o envVar original
+	return envVar
+}
+
 func (r *FunctionReconciler) buildKnativeTrigger(function *functionsv1alpha1.Function) *kneventingv1.Trigger {
 	brokerName := defaultBrokerName // Padrão
 	if function.Spec.Eventing.Broker != "" {
@@ -1573,14 +1949,38 @@ func (r *FunctionReconciler) ensureNamespaceInstrumentation(ctx context.Context,
 	return nil
 }
 
+/*
+setInvalidSpecCondition marca a Function como Ready=False com o erro de validação do spec.
+Não há requeue: a correção do spec gera uma nova reconciliação.
+*/
+func (r *FunctionReconciler) setInvalidSpecCondition(ctx context.Context, function *functionsv1alpha1.Function, reason string, err error) (ctrl.Result, error) {
+	return r.setSourceCondition(ctx, function, reason, err.Error(), 0)
+}
+
+// releaseBuildSlot frees the admission queue entry of a Function whose build finished or was abandoned.
+func (r *FunctionReconciler) releaseBuildSlot(key types.NamespacedName) {
+	if r.BuildQueue != nil {
+		r.BuildQueue.Release(key)
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *FunctionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := registerFunctionConditionCollector(mgr.GetClient()); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&functionsv1alpha1.Function{}).
 		Owns(&tektonv1.PipelineRun{}).
 		Owns(&knservingv1.Service{}).
+		Owns(&knservingv1beta1.DomainMapping{}).
 		Owns(&kneventingv1.Trigger{}).
 		Owns(&v1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&v1.ConfigMap{}).
+		Watches(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.functionsForSourceConfigMap)).
 		Named("function").
 		Complete(r)
 }
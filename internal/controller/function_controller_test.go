@@ -2402,4 +2402,183 @@ var _ = Describe("Function Controller Reconciliation", func() {
 			}
 		})
 	})
+
+	Context("build steps", func() {
+		It("should detect a failed pre-build step TaskRun", func() {
+			ctx := context.Background()
+			namespace := testNamespace
+			taskRunName := "test-pr-taskrun-pre-unit-tests"
+
+			taskRun := &tektonv1.TaskRun{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      taskRunName,
+					Namespace: namespace,
+				},
+			}
+			Expect(k8sClient.Create(ctx, taskRun)).To(Succeed())
+			defer func() {
+				_ = k8sClient.Delete(ctx, taskRun)
+			}()
+
+			taskRun.Status = tektonv1.TaskRunStatus{
+				Status: duckv1.Status{
+					Conditions: duckv1.Conditions{
+						{
+							Type:    apis.ConditionSucceeded,
+							Status:  v1.ConditionFalse,
+							Reason:  "Failed",
+							Message: "\"step-test\" exited with code 1",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, taskRun)).To(Succeed())
+
+			reconciler := &FunctionReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			pr := &tektonv1.PipelineRun{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pr-with-build-step",
+					Namespace: namespace,
+				},
+				Status: tektonv1.PipelineRunStatus{
+					PipelineRunStatusFields: tektonv1.PipelineRunStatusFields{
+						ChildReferences: []tektonv1.ChildStatusReference{
+							{
+								TypeMeta:         runtime.TypeMeta{Kind: "TaskRun"},
+								Name:             taskRunName,
+								PipelineTaskName: "zenith-pre-unit-tests",
+							},
+						},
+					},
+				},
+			}
+
+			stepName, failed := reconciler.failedBuildStep(ctx, &functionsv1alpha1.Function{}, pr)
+			Expect(failed).To(BeTrue())
+			Expect(stepName).To(Equal("zenith-pre-unit-tests"))
+		})
+
+		It("should apply the namespace default build steps only where the Function has none", func() {
+			ctx := context.Background()
+			namespace := testNamespace
+
+			configMap := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      BuildStepsConfigMapName,
+					Namespace: namespace,
+				},
+				Data: map[string]string{
+					"preBuild": "- name: lint\n  taskRef:\n    name: golangci-lint\n",
+					"postBuild": "- name: scan\n  taskRef:\n    name: trivy-scan\n  params:\n" +
+						"    - name: image\n      value: $(tasks.build-and-push.results.APP_IMAGE_DIGEST)\n",
+				},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			defer func() {
+				_ = k8sClient.Delete(ctx, configMap)
+			}()
+
+			reconciler := &FunctionReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			function := &functionsv1alpha1.Function{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-build-steps-default", Namespace: namespace},
+				Spec: functionsv1alpha1.FunctionSpec{
+					Build: functionsv1alpha1.BuildSpec{
+						Image: "registry.io/test:latest",
+						PreBuild: []functionsv1alpha1.BuildStep{
+							{Name: "unit-tests", TaskRef: functionsv1alpha1.BuildStepTaskRef{Name: "go-test"}},
+						},
+					},
+				},
+			}
+
+			Expect(reconciler.applyNamespaceBuildSteps(ctx, function)).To(Succeed())
+			Expect(function.Spec.Build.PreBuild).To(HaveLen(1))
+			Expect(function.Spec.Build.PreBuild[0].Name).To(Equal("unit-tests"))
+			Expect(function.Spec.Build.PostBuild).To(HaveLen(1))
+			Expect(function.Spec.Build.PostBuild[0].TaskRef.Name).To(Equal("trivy-scan"))
+			Expect(function.Spec.Build.PostBuild[0].Params[0].Value).To(Equal("$(tasks.build-and-push.results.APP_IMAGE_DIGEST)"))
+		})
+	})
+
+	Context("inline source", func() {
+		It("should store inline files in an owned ConfigMap and report the source revision", func() {
+			ctx := context.Background()
+			functionName := "test-inline-source"
+			namespace := testNamespace
+
+			function := &functionsv1alpha1.Function{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      functionName,
+					Namespace: namespace,
+				},
+				Spec: functionsv1alpha1.FunctionSpec{
+					Source: &functionsv1alpha1.SourceSpec{
+						Inline: &functionsv1alpha1.InlineSource{
+							Files: map[string]string{"app/main.py": "print('hi')"},
+						},
+					},
+					Build: functionsv1alpha1.BuildSpec{
+						Image: "registry.io/test:latest",
+					},
+					Deploy: functionsv1alpha1.DeploySpec{
+						Dapr: functionsv1alpha1.DaprConfig{
+							Enabled: false,
+							AppPort: 8080,
+						},
+					},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, function)).To(Succeed())
+			defer func() {
+				_ = k8sClient.Delete(ctx, function)
+			}()
+
+			reconciler := &FunctionReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			resolved, result, err := reconciler.reconcileSource(ctx, function)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(BeTrue())
+			Expect(resolved.Revision).To(Equal(hashSourceFiles(function.Spec.Source.Inline.Files, nil)))
+
+			configMap := &v1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: functionName + "-source", Namespace: namespace}, configMap)).To(Succeed())
+			Expect(configMap.Data).To(Equal(map[string]string{"file-000": "print('hi')"}))
+			Expect(configMap.OwnerReferences).To(HaveLen(1))
+			Expect(configMap.OwnerReferences[0].Name).To(Equal(functionName))
+		})
+
+		It("should reject a Function with both gitRepo and source", func() {
+			ctx := context.Background()
+
+			function := &functionsv1alpha1.Function{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-git-and-source",
+					Namespace: testNamespace,
+				},
+				Spec: functionsv1alpha1.FunctionSpec{
+					GitRepo: "https://github.com/user/repo",
+					Source: &functionsv1alpha1.SourceSpec{
+						OCI: &functionsv1alpha1.OCISource{Image: "registry.io/test-src@sha256:4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c"},
+					},
+					Build: functionsv1alpha1.BuildSpec{
+						Image: "registry.io/test:latest",
+					},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, function)).NotTo(Succeed())
+		})
+	})
 })
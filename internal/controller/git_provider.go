@@ -0,0 +1,206 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultGitHubAPIURL is the GitHub REST API used when a PreviewPolicy does not set apiURL
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// githubPageSize is the largest page size accepted by the GitHub REST API
+const githubPageSize = 100
+
+// previewStatusContext identifies the commit status holding the preview URL on the pull request
+const previewStatusContext = "zenith/preview"
+
+// pullRequest is an open pull request reported by a Git provider.
+type pullRequest struct {
+	Number    int64
+	HeadRef   string
+	HeadSHA   string
+	BaseRef   string
+	UpdatedAt time.Time
+	// Fork reports whether the head branch lives outside the base repository (or its repository was deleted)
+	Fork   bool
+	Labels []string
+}
+
+// gitProvider lists the open pull requests of a repository and publishes preview URLs on them.
+type gitProvider interface {
+	openPullRequests(ctx context.Context, repository string) ([]pullRequest, error)
+	publishPreview(ctx context.Context, repository, sha, previewURL string) error
+}
+
+// githubClient is a minimal client for the GitHub REST API (github.com and GitHub Enterprise Server).
+type githubClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// newGitHubClient creates a client for the given API URL. Without a token, requests are anonymous.
+func newGitHubClient(baseURL, token string) *githubClient {
+	if baseURL == "" {
+		baseURL = defaultGitHubAPIURL
+	}
+	return &githubClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// githubPullRequest is the subset of the pull request object used by the controller.
+type githubPullRequest struct {
+	Number int64 `json:"number"`
+	Head   struct {
+		Ref  string            `json:"ref"`
+		SHA  string            `json:"sha"`
+		Repo *githubRepository `json:"repo"`
+	} `json:"head"`
+	Base struct {
+		Ref  string            `json:"ref"`
+		Repo *githubRepository `json:"repo"`
+	} `json:"base"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// githubRepository is the subset of the repository object used to detect pull requests from forks.
+type githubRepository struct {
+	FullName string `json:"full_name"`
+}
+
+// openPullRequests returns every open pull request of a repository ("owner/name").
+func (c *githubClient) openPullRequests(ctx context.Context, repository string) ([]pullRequest, error) {
+	var pullRequests []pullRequest
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("state", "open")
+		query.Set("per_page", fmt.Sprint(githubPageSize))
+		query.Set("page", fmt.Sprint(page))
+
+		var batch []githubPullRequest
+		if err := c.do(ctx, http.MethodGet, "/repos/"+repository+"/pulls?"+query.Encode(), nil, http.StatusOK, &batch); err != nil {
+			return nil, err
+		}
+		for _, pr := range batch {
+			// O repositório de um fork apagado vem nulo: tratado como fork
+			fork := pr.Head.Repo == nil || pr.Base.Repo == nil || !strings.EqualFold(pr.Head.Repo.FullName, pr.Base.Repo.FullName)
+			var labels []string
+			for _, label := range pr.Labels {
+				labels = append(labels, label.Name)
+			}
+			pullRequests = append(pullRequests, pullRequest{
+				Number:    pr.Number,
+				HeadRef:   pr.Head.Ref,
+				HeadSHA:   pr.Head.SHA,
+				BaseRef:   pr.Base.Ref,
+				UpdatedAt: pr.UpdatedAt,
+				Fork:      fork,
+				Labels:    labels,
+			})
+		}
+		if len(batch) < githubPageSize {
+			return pullRequests, nil
+		}
+	}
+}
+
+// publishPreview sets a successful commit status pointing to the preview URL on the pull request head.
+func (c *githubClient) publishPreview(ctx context.Context, repository, sha, previewURL string) error {
+	body := map[string]string{
+		"state":       "success",
+		"target_url":  previewURL,
+		"description": "Preview implantado",
+		"context":     previewStatusContext,
+	}
+	return c.do(ctx, http.MethodPost, "/repos/"+repository+"/statuses/"+sha, body, http.StatusCreated, nil)
+}
+
+// do sends a request to the API and decodes the JSON response into out (if not nil).
+func (c *githubClient) do(ctx context.Context, method, path string, body any, wantStatus int, out any) error {
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != wantStatus {
+		return fmt.Errorf("%s %s: %s", method, strings.SplitN(path, "?", 2)[0], resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("resposta inválida do GitHub: %w", err)
+	}
+	return nil
+}
+
+/*
+repositoryFromGitURL extrai o repositório ("owner/name") de uma URL Git, nos formatos
+"https://github.com/owner/name(.git)", "git@github.com:owner/name.git" e "ssh://git@host/owner/name".
+*/
+func repositoryFromGitURL(gitURL string) (string, bool) {
+	path := gitURL
+	if parsed, err := url.Parse(gitURL); err == nil && parsed.Host != "" {
+		path = parsed.Path
+	} else if _, scpPath, found := strings.Cut(gitURL, ":"); found && strings.Contains(gitURL, "@") {
+		path = scpPath
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if len(segments) < 2 || segments[len(segments)-2] == "" || segments[len(segments)-1] == "" {
+		return "", false
+	}
+	return segments[len(segments)-2] + "/" + segments[len(segments)-1], true
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// fakeGitHub is a GitHub REST API stand-in serving the pull request list (paginated) and commit statuses.
+type fakeGitHub struct {
+	mu       sync.Mutex
+	pulls    []map[string]any
+	statuses map[string]map[string]string // sha -> status body
+	auth     []string
+}
+
+func newFakeGitHub(openPullRequests int) *fakeGitHub {
+	github := &fakeGitHub{statuses: map[string]map[string]string{}}
+	repository := map[string]string{"full_name": "acme/api"}
+	for number := 1; number <= openPullRequests; number++ {
+		head := map[string]any{"ref": fmt.Sprintf("feature-%d", number), "sha": fmt.Sprintf("%040d", number), "repo": repository}
+		var labels []map[string]string
+		if number == 2 {
+			// Pull request de um fork, autorizado por label
+			head["repo"] = map[string]string{"full_name": "mallory/api"}
+			labels = []map[string]string{{"name": "safe-to-preview"}}
+		}
+		github.pulls = append(github.pulls, map[string]any{
+			"number":     number,
+			"head":       head,
+			"base":       map[string]any{"ref": "main", "repo": repository},
+			"labels":     labels,
+			"updated_at": "2025-06-01T10:00:00Z",
+		})
+	}
+	return github
+}
+
+func (g *fakeGitHub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.auth = append(g.auth, req.Header.Get("Authorization"))
+
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/repos/acme/api/pulls":
+		page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(req.URL.Query().Get("per_page"))
+		start := min((page-1)*perPage, len(g.pulls))
+		end := min(start+perPage, len(g.pulls))
+		_ = json.NewEncoder(w).Encode(g.pulls[start:end])
+	case req.Method == http.MethodPost && len(req.URL.Path) > len("/repos/acme/api/statuses/"):
+		var body map[string]string
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		g.statuses[req.URL.Path[len("/repos/acme/api/statuses/"):]] = body
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGitHubClientOpenPullRequests(t *testing.T) {
+	g := NewWithT(t)
+	github := newFakeGitHub(githubPageSize + 5)
+	server := httptest.NewServer(github)
+	defer server.Close()
+
+	pullRequests, err := newGitHubClient(server.URL, "s3cr3t").openPullRequests(context.Background(), "acme/api")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pullRequests).To(HaveLen(githubPageSize + 5))
+	g.Expect(pullRequests[0]).To(And(
+		HaveField("Number", int64(1)),
+		HaveField("HeadRef", "feature-1"),
+		HaveField("HeadSHA", fmt.Sprintf("%040d", 1)),
+		HaveField("BaseRef", "main"),
+		HaveField("Fork", false),
+	))
+	g.Expect(pullRequests[1]).To(And(HaveField("Fork", true), HaveField("Labels", []string{"safe-to-preview"})))
+	g.Expect(github.auth).To(HaveEach("Bearer s3cr3t"))
+
+	_, err = newGitHubClient(server.URL, "").openPullRequests(context.Background(), "acme/missing")
+	g.Expect(err).To(MatchError(ContainSubstring("404")))
+}
+
+func TestGitHubClientPublishPreview(t *testing.T) {
+	g := NewWithT(t)
+	github := newFakeGitHub(0)
+	server := httptest.NewServer(github)
+	defer server.Close()
+
+	sha := fmt.Sprintf("%040d", 7)
+	err := newGitHubClient(server.URL, "s3cr3t").publishPreview(context.Background(), "acme/api", sha, "http://api-pr-7.default.example.com")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(github.statuses[sha]).To(Equal(map[string]string{
+		"state":       "success",
+		"target_url":  "http://api-pr-7.default.example.com",
+		"description": "Preview implantado",
+		"context":     previewStatusContext,
+	}))
+}
+
+func TestRepositoryFromGitURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://github.com/acme/api", want: "acme/api"},
+		{url: "https://github.com/acme/api.git", want: "acme/api"},
+		{url: "git@github.com:acme/api.git", want: "acme/api"},
+		{url: "ssh://git@github.example.com/acme/api", want: "acme/api"},
+		{url: "https://github.com/acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			g := NewWithT(t)
+			repository, found := repositoryFromGitURL(tt.url)
+			g.Expect(found).To(Equal(tt.want != ""))
+			g.Expect(repository).To(Equal(tt.want))
+		})
+	}
+}
@@ -17,14 +17,23 @@ limitations under the License.
 package controller
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	kneventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
 )
@@ -1030,6 +1039,730 @@ func TestDetectInsecureRegistries(t *testing.T) {
 	}
 }
 
+func TestRewriteImageForPull(t *testing.T) {
+	tests := []struct {
+		name         string
+		rules        string
+		image        string
+		expected     string
+		expectedRule *functionsv1alpha1.ImageRewrite
+	}{
+		{
+			name:     "should keep image untouched when no rules are configured",
+			rules:    "",
+			image:    "registry.registry.svc.cluster.local:5000/app:latest@sha256:abc",
+			expected: "registry.registry.svc.cluster.local:5000/app:latest@sha256:abc",
+		},
+		{
+			name:         "should rewrite Kind registry to NodePort endpoint",
+			rules:        "registry.registry.svc.cluster.local:5000=127.0.0.1:30500",
+			image:        "registry.registry.svc.cluster.local:5000/app:latest@sha256:abc",
+			expected:     "127.0.0.1:30500/app:latest@sha256:abc",
+			expectedRule: &functionsv1alpha1.ImageRewrite{From: "registry.registry.svc.cluster.local:5000", To: "127.0.0.1:30500"},
+		},
+		{
+			name:     "should not rewrite images from other registries",
+			rules:    "registry.registry.svc.cluster.local:5000=127.0.0.1:30500",
+			image:    "docker.io/org/app:latest@sha256:abc",
+			expected: "docker.io/org/app:latest@sha256:abc",
+		},
+		{
+			name:     "should only match prefixes on a path boundary",
+			rules:    "registry.local=mirror.local",
+			image:    "registry.local.example.com/app@sha256:abc",
+			expected: "registry.local.example.com/app@sha256:abc",
+		},
+		{
+			name:         "should prefer the longest matching prefix",
+			rules:        "harbor.corp=mirror.corp, harbor.corp/team-a=mirror-a.corp/team-a",
+			image:        "harbor.corp/team-a/app@sha256:abc",
+			expected:     "mirror-a.corp/team-a/app@sha256:abc",
+			expectedRule: &functionsv1alpha1.ImageRewrite{From: "harbor.corp/team-a", To: "mirror-a.corp/team-a"},
+		},
+		{
+			name:         "should ignore malformed entries",
+			rules:        "invalid,=nothing,harbor.corp=,harbor.corp=mirror.corp",
+			image:        "harbor.corp/app@sha256:abc",
+			expected:     "mirror.corp/app@sha256:abc",
+			expectedRule: &functionsv1alpha1.ImageRewrite{From: "harbor.corp", To: "mirror.corp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Setenv("IMAGE_PULL_REWRITES", tt.rules)
+
+			image, rule := rewriteImageForPull(tt.image)
+			g.Expect(image).To(Equal(tt.expected))
+			if tt.expectedRule == nil {
+				g.Expect(rule).To(BeNil())
+			} else {
+				g.Expect(rule).To(Equal(tt.expectedRule))
+			}
+		})
+	}
+}
+
+func TestBuildKnativeServiceAppliesImagePullRewrites(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("IMAGE_PULL_REWRITES", "registry.registry.svc.cluster.local:5000=127.0.0.1:30500")
+
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "rewrite-func", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			GitRepo: "https://github.com/user/repo",
+			Build:   functionsv1alpha1.BuildSpec{Image: "registry.registry.svc.cluster.local:5000/rewrite-func:latest"},
+		},
+		Status: functionsv1alpha1.FunctionStatus{
+			ImageDigest: "registry.registry.svc.cluster.local:5000/rewrite-func:latest@sha256:abc123",
+		},
+	}
+
+	r := &FunctionReconciler{}
+	ksvc := r.buildKnativeService(function)
+	g.Expect(ksvc.Spec.Template.Spec.Containers[0].Image).To(Equal("127.0.0.1:30500/rewrite-func:latest@sha256:abc123"))
+
+	t.Setenv("IMAGE_PULL_REWRITES", "")
+	ksvc = r.buildKnativeService(function)
+	g.Expect(ksvc.Spec.Template.Spec.Containers[0].Image).To(Equal(function.Status.ImageDigest))
+}
+
+func TestBuildPipelineRunPodTemplate(t *testing.T) {
+	buildToleration := v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "build", Effect: v1.TaintEffectNoSchedule}
+
+	tests := []struct {
+		name        string
+		defaults    string
+		podTemplate *functionsv1alpha1.BuildPodTemplate
+		validate    func(*GomegaWithT, *tektonv1.PipelineRun)
+	}{
+		{
+			name: "no default and no function template",
+			validate: func(g *GomegaWithT, pr *tektonv1.PipelineRun) {
+				g.Expect(pr.Spec.TaskRunTemplate.PodTemplate).To(BeNil())
+			},
+		},
+		{
+			name: "function template only",
+			podTemplate: &functionsv1alpha1.BuildPodTemplate{
+				NodeSelector:      map[string]string{"node-role/build": "true"},
+				Tolerations:       []v1.Toleration{buildToleration},
+				PriorityClassName: "builds",
+				SecurityContext:   &v1.PodSecurityContext{FSGroup: int64Ptr(1000)},
+			},
+			validate: func(g *GomegaWithT, pr *tektonv1.PipelineRun) {
+				podTemplate := pr.Spec.TaskRunTemplate.PodTemplate
+				g.Expect(podTemplate).NotTo(BeNil())
+				g.Expect(podTemplate.NodeSelector).To(Equal(map[string]string{"node-role/build": "true"}))
+				g.Expect(podTemplate.Tolerations).To(ConsistOf(buildToleration))
+				g.Expect(podTemplate.PriorityClassName).To(Equal(stringPtr("builds")))
+				g.Expect(podTemplate.SecurityContext.FSGroup).To(Equal(int64Ptr(1000)))
+				g.Expect(pr.Spec.TaskRunTemplate.ServiceAccountName).To(Equal("test-func-sa"))
+			},
+		},
+		{
+			name:     "cluster default only",
+			defaults: `{"nodeSelector":{"pool":"build"},"priorityClassName":"batch"}`,
+			validate: func(g *GomegaWithT, pr *tektonv1.PipelineRun) {
+				podTemplate := pr.Spec.TaskRunTemplate.PodTemplate
+				g.Expect(podTemplate).NotTo(BeNil())
+				g.Expect(podTemplate.NodeSelector).To(Equal(map[string]string{"pool": "build"}))
+				g.Expect(podTemplate.PriorityClassName).To(Equal(stringPtr("batch")))
+			},
+		},
+		{
+			name:     "function fields override cluster default",
+			defaults: `{"nodeSelector":{"pool":"build"},"priorityClassName":"batch"}`,
+			podTemplate: &functionsv1alpha1.BuildPodTemplate{
+				NodeSelector: map[string]string{"pool": "ssd-build"},
+				Tolerations:  []v1.Toleration{buildToleration},
+			},
+			validate: func(g *GomegaWithT, pr *tektonv1.PipelineRun) {
+				podTemplate := pr.Spec.TaskRunTemplate.PodTemplate
+				g.Expect(podTemplate.NodeSelector).To(Equal(map[string]string{"pool": "ssd-build"}))
+				g.Expect(podTemplate.Tolerations).To(ConsistOf(buildToleration))
+				g.Expect(podTemplate.PriorityClassName).To(Equal(stringPtr("batch")))
+			},
+		},
+		{
+			name:     "malformed cluster default is ignored",
+			defaults: `{not-json`,
+			validate: func(g *GomegaWithT, pr *tektonv1.PipelineRun) {
+				g.Expect(pr.Spec.TaskRunTemplate.PodTemplate).To(BeNil())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Setenv("BUILD_POD_TEMPLATE", tt.defaults)
+
+			function := &functionsv1alpha1.Function{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-func", Namespace: "default"},
+				Spec: functionsv1alpha1.FunctionSpec{
+					GitRepo: "https://github.com/user/repo",
+					Build: functionsv1alpha1.BuildSpec{
+						Image:       "registry.example.com/test-func",
+						PodTemplate: tt.podTemplate,
+					},
+				},
+			}
+
+			r := &FunctionReconciler{}
+			tt.validate(g, r.buildPipelineRun(function))
+		})
+	}
+}
+
+func TestBuildPipelineRunBuildSteps(t *testing.T) {
+	g := NewWithT(t)
+
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-func", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			GitRepo: "https://github.com/user/repo",
+			Build: functionsv1alpha1.BuildSpec{
+				Image: "registry.io/test:latest",
+				PreBuild: []functionsv1alpha1.BuildStep{
+					{
+						Name:    "unit-tests",
+						TaskRef: functionsv1alpha1.BuildStepTaskRef{Name: "go-test"},
+						Params: []functionsv1alpha1.BuildStepParam{
+							{Name: "commit", Value: "$(tasks.fetch-source.results.commit)"},
+						},
+					},
+					{Name: "lint", TaskRef: functionsv1alpha1.BuildStepTaskRef{Name: "golangci-lint"}, Workspace: "code"},
+				},
+				PostBuild: []functionsv1alpha1.BuildStep{
+					{
+						Name:    "contract-tests",
+						TaskRef: functionsv1alpha1.BuildStepTaskRef{Name: "pact-verify"},
+						Params: []functionsv1alpha1.BuildStepParam{
+							{Name: "image-digest", Value: "$(tasks.build-and-push.results.APP_IMAGE_DIGEST)"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	r := &FunctionReconciler{}
+	pr := r.buildPipelineRun(function)
+
+	tasks := map[string]tektonv1.PipelineTask{}
+	for _, task := range pr.Spec.PipelineSpec.Tasks {
+		tasks[task.Name] = task
+	}
+	g.Expect(tasks).To(HaveLen(5))
+
+	unitTests := tasks["zenith-pre-unit-tests"]
+	g.Expect(unitTests.TaskRef.Name).To(Equal("go-test"))
+	g.Expect(unitTests.RunAfter).To(Equal([]string{"fetch-source"}))
+	g.Expect(unitTests.Workspaces).To(ConsistOf(tektonv1.WorkspacePipelineTaskBinding{Name: "source", Workspace: "source-workspace"}))
+	g.Expect(findParam(unitTests.Params, "commit").Value.StringVal).To(Equal("$(tasks.fetch-source.results.commit)"))
+
+	g.Expect(tasks["zenith-pre-lint"].Workspaces).To(ConsistOf(tektonv1.WorkspacePipelineTaskBinding{Name: "code", Workspace: "source-workspace"}))
+
+	g.Expect(tasks["build-and-push"].RunAfter).To(Equal([]string{"fetch-source", "zenith-pre-unit-tests", "zenith-pre-lint"}))
+
+	contractTests := tasks["zenith-post-contract-tests"]
+	g.Expect(contractTests.RunAfter).To(Equal([]string{"build-and-push"}))
+	g.Expect(findParam(contractTests.Params, "image-digest").Value.StringVal).To(Equal("$(tasks.build-and-push.results.APP_IMAGE_DIGEST)"))
+}
+
+func TestValidateBuildSteps(t *testing.T) {
+	step := func(name, task string, params ...functionsv1alpha1.BuildStepParam) functionsv1alpha1.BuildStep {
+		return functionsv1alpha1.BuildStep{Name: name, TaskRef: functionsv1alpha1.BuildStepTaskRef{Name: task}, Params: params}
+	}
+	digestParam := functionsv1alpha1.BuildStepParam{Name: "image", Value: "$(tasks.build-and-push.results.APP_IMAGE_DIGEST)"}
+
+	tests := []struct {
+		name      string
+		preBuild  []functionsv1alpha1.BuildStep
+		postBuild []functionsv1alpha1.BuildStep
+		wantErr   bool
+	}{
+		{name: "no steps"},
+		{name: "valid steps", preBuild: []functionsv1alpha1.BuildStep{step("test", "go-test")}, postBuild: []functionsv1alpha1.BuildStep{step("scan", "trivy", digestParam)}},
+		{name: "same name in pre and post build", preBuild: []functionsv1alpha1.BuildStep{step("check", "a")}, postBuild: []functionsv1alpha1.BuildStep{step("check", "b")}},
+		{name: "duplicate names", preBuild: []functionsv1alpha1.BuildStep{step("test", "a"), step("test", "b")}, wantErr: true},
+		{name: "missing task reference", postBuild: []functionsv1alpha1.BuildStep{step("scan", "")}, wantErr: true},
+		{name: "pre-build step using the image digest", preBuild: []functionsv1alpha1.BuildStep{step("test", "go-test", digestParam)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{
+				Spec: functionsv1alpha1.FunctionSpec{
+					Build: functionsv1alpha1.BuildSpec{PreBuild: tt.preBuild, PostBuild: tt.postBuild},
+				},
+			}
+			err := validateBuildSteps(function)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestBuildPipelineRunWithPipelineRef(t *testing.T) {
+	newFunction := func(ref *functionsv1alpha1.BuildPipelineRef) *functionsv1alpha1.Function {
+		return &functionsv1alpha1.Function{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-func", Namespace: "default"},
+			Spec: functionsv1alpha1.FunctionSpec{
+				GitRepo:     "https://github.com/user/repo",
+				GitRevision: "v1.2.0",
+				Build: functionsv1alpha1.BuildSpec{
+					Image:         "registry.io/test",
+					SourceSubpath: "services/checkout",
+					PipelineRef:   ref,
+				},
+			},
+		}
+	}
+
+	t.Run("local pipeline", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &FunctionReconciler{}
+		pr := r.buildPipelineRun(newFunction(&functionsv1alpha1.BuildPipelineRef{
+			Name:   "hardened-build",
+			Params: []functionsv1alpha1.BuildStepParam{{Name: "scan", Value: "true"}},
+		}))
+
+		g.Expect(pr.Spec.PipelineSpec).To(BeNil())
+		g.Expect(pr.Spec.PipelineRef).To(Equal(&tektonv1.PipelineRef{Name: "hardened-build"}))
+		g.Expect(findParam(pr.Spec.Params, "url").Value.StringVal).To(Equal("https://github.com/user/repo"))
+		g.Expect(findParam(pr.Spec.Params, "revision").Value.StringVal).To(Equal("v1.2.0"))
+		g.Expect(findParam(pr.Spec.Params, "image").Value.StringVal).To(Equal("registry.io/test"))
+		g.Expect(findParam(pr.Spec.Params, "subpath").Value.StringVal).To(Equal("services/checkout"))
+		g.Expect(findParam(pr.Spec.Params, "scan").Value.StringVal).To(Equal("true"))
+		g.Expect(pr.Spec.Workspaces).To(HaveLen(1))
+		g.Expect(pr.Spec.Workspaces[0].Name).To(Equal("source"))
+		g.Expect(pr.Spec.TaskRunTemplate.ServiceAccountName).To(Equal("test-func-sa"))
+	})
+
+	t.Run("resolver reference", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &FunctionReconciler{}
+		pr := r.buildPipelineRun(newFunction(&functionsv1alpha1.BuildPipelineRef{
+			Resolver: "git",
+			ResolverParams: []functionsv1alpha1.BuildStepParam{
+				{Name: "url", Value: "https://github.com/platform/pipelines"},
+				{Name: "pathInRepo", Value: "build.yaml"},
+			},
+		}))
+
+		g.Expect(pr.Spec.PipelineRef.Name).To(BeEmpty())
+		g.Expect(pr.Spec.PipelineRef.Resolver).To(Equal(tektonv1.ResolverName("git")))
+		g.Expect(findParam(pr.Spec.PipelineRef.Params, "pathInRepo").Value.StringVal).To(Equal("build.yaml"))
+	})
+
+	t.Run("inline pipeline passes the subpath to buildpacks", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &FunctionReconciler{}
+		pr := r.buildPipelineRun(newFunction(nil))
+
+		g.Expect(pr.Spec.PipelineRef).To(BeNil())
+		buildTask := pr.Spec.PipelineSpec.Tasks[1]
+		g.Expect(findParam(buildTask.Params, "SOURCE_SUBPATH").Value.StringVal).To(Equal("services/checkout"))
+	})
+}
+
+func TestValidatePipelineContract(t *testing.T) {
+	stringParam := func(name string) tektonv1.ParamSpec {
+		return tektonv1.ParamSpec{Name: name, Type: tektonv1.ParamTypeString}
+	}
+	validSpec := func() *tektonv1.PipelineSpec {
+		return &tektonv1.PipelineSpec{
+			Params:     tektonv1.ParamSpecs{stringParam("url"), stringParam("revision"), stringParam("image"), stringParam("subpath")},
+			Workspaces: []tektonv1.PipelineWorkspaceDeclaration{{Name: "source"}, {Name: "cache", Optional: true}},
+			Results:    []tektonv1.PipelineResult{{Name: "APP_IMAGE_DIGEST"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*tektonv1.PipelineSpec)
+		ref     functionsv1alpha1.BuildPipelineRef
+		wantErr string
+	}{
+		{name: "valid pipeline"},
+		{
+			name:   "subpath is optional",
+			mutate: func(spec *tektonv1.PipelineSpec) { spec.Params = spec.Params[:3] },
+		},
+		{
+			name: "missing revision param",
+			mutate: func(spec *tektonv1.PipelineSpec) {
+				spec.Params = tektonv1.ParamSpecs{stringParam("url"), stringParam("image")}
+			},
+			wantErr: "'revision' não declarado",
+		},
+		{
+			name:    "array image param",
+			mutate:  func(spec *tektonv1.PipelineSpec) { spec.Params[2].Type = tektonv1.ParamTypeArray },
+			wantErr: "'image' deve ser do tipo string",
+		},
+		{
+			name:    "missing digest result",
+			mutate:  func(spec *tektonv1.PipelineSpec) { spec.Results = nil },
+			wantErr: "'APP_IMAGE_DIGEST' não declarado",
+		},
+		{
+			name: "required workspace not bound by the operator",
+			mutate: func(spec *tektonv1.PipelineSpec) {
+				spec.Workspaces = append(spec.Workspaces, tektonv1.PipelineWorkspaceDeclaration{Name: "docker-config"})
+			},
+			wantErr: "'docker-config'",
+		},
+		{
+			name:    "undeclared extra param",
+			ref:     functionsv1alpha1.BuildPipelineRef{Params: []functionsv1alpha1.BuildStepParam{{Name: "scan", Value: "true"}}},
+			wantErr: "'scan' de pipelineRef.params",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			spec := validSpec()
+			if tt.mutate != nil {
+				tt.mutate(spec)
+			}
+			err := validatePipelineContract(spec, &tt.ref)
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			}
+		})
+	}
+}
+
+func TestBuildPipelineRunWithSource(t *testing.T) {
+	newFunction := func(source *functionsv1alpha1.SourceSpec) *functionsv1alpha1.Function {
+		return &functionsv1alpha1.Function{
+			ObjectMeta: metav1.ObjectMeta{Name: "glue", Namespace: "default"},
+			Spec: functionsv1alpha1.FunctionSpec{
+				Source: source,
+				Build:  functionsv1alpha1.BuildSpec{Image: "registry.io/glue"},
+			},
+		}
+	}
+
+	t.Run("inline files", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &FunctionReconciler{}
+		pr := r.buildPipelineRun(newFunction(&functionsv1alpha1.SourceSpec{
+			Inline: &functionsv1alpha1.InlineSource{Files: map[string]string{
+				"main.py":         "print('hi')",
+				"lib/__init__.py": "",
+			}},
+		}))
+
+		fetch := pr.Spec.PipelineSpec.Tasks[0]
+		g.Expect(fetch.Name).To(Equal("fetch-source"))
+		g.Expect(fetch.TaskRef.Name).To(Equal(SourceFetchTaskName))
+		g.Expect(findParam(fetch.Params, "mode").Value.StringVal).To(Equal("inline"))
+		g.Expect(fetch.Workspaces).To(ContainElement(tektonv1.WorkspacePipelineTaskBinding{Name: "inline", Workspace: "inline-source"}))
+		g.Expect(pr.Spec.PipelineSpec.Workspaces).To(ContainElement(tektonv1.PipelineWorkspaceDeclaration{Name: "inline-source"}))
+
+		g.Expect(pr.Spec.Workspaces).To(HaveLen(2))
+		binding := pr.Spec.Workspaces[1]
+		g.Expect(binding.Name).To(Equal("inline-source"))
+		g.Expect(binding.ConfigMap.Name).To(Equal("glue-source"))
+		g.Expect(binding.ConfigMap.Items).To(Equal([]v1.KeyToPath{
+			{Key: "file-000", Path: "lib/__init__.py"},
+			{Key: "file-001", Path: "main.py"},
+		}))
+
+		// the rest of the pipeline is unchanged
+		g.Expect(pr.Spec.PipelineSpec.Tasks[1].TaskRef.Name).To(Equal("buildpacks-phases"))
+	})
+
+	t.Run("inline ConfigMap reference", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &FunctionReconciler{}
+		pr := r.buildPipelineRun(newFunction(&functionsv1alpha1.SourceSpec{
+			Inline: &functionsv1alpha1.InlineSource{ConfigMapRef: &v1.LocalObjectReference{Name: "glue-src"}},
+		}))
+
+		binding := pr.Spec.Workspaces[1]
+		g.Expect(binding.ConfigMap.Name).To(Equal("glue-src"))
+		g.Expect(binding.ConfigMap.Items).To(BeEmpty())
+	})
+
+	t.Run("OCI artifact", func(t *testing.T) {
+		g := NewWithT(t)
+		t.Setenv("INSECURE_REGISTRIES", "")
+		r := &FunctionReconciler{}
+		pr := r.buildPipelineRun(newFunction(&functionsv1alpha1.SourceSpec{
+			OCI: &functionsv1alpha1.OCISource{Image: "registry.registry.svc.cluster.local:5000/glue-src@sha256:4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c"},
+		}))
+
+		fetch := pr.Spec.PipelineSpec.Tasks[0]
+		g.Expect(fetch.TaskRef.Name).To(Equal(SourceFetchTaskName))
+		g.Expect(findParam(fetch.Params, "mode").Value.StringVal).To(Equal("oci"))
+		g.Expect(findParam(fetch.Params, "oci-image").Value.StringVal).To(Equal("registry.registry.svc.cluster.local:5000/glue-src@sha256:4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c4f1c"))
+		g.Expect(findParam(fetch.Params, "oci-plain-http").Value.StringVal).To(Equal("true"))
+		g.Expect(pr.Spec.Workspaces).To(HaveLen(1))
+	})
+
+	t.Run("S3 object with credentials", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &FunctionReconciler{}
+		pr := r.buildPipelineRun(newFunction(&functionsv1alpha1.SourceSpec{
+			S3: &functionsv1alpha1.S3Source{
+				Bucket:               "sources",
+				Key:                  "glue/app v2#1.tar.gz",
+				Endpoint:             "http://minio.minio.svc:9000/",
+				CredentialsSecretRef: &v1.LocalObjectReference{Name: "minio-creds"},
+			},
+		}))
+
+		fetch := pr.Spec.PipelineSpec.Tasks[0]
+		g.Expect(fetch.TaskRef.Name).To(Equal(SourceFetchTaskName))
+		g.Expect(findParam(fetch.Params, "mode").Value.StringVal).To(Equal("s3"))
+		g.Expect(findParam(fetch.Params, "s3-endpoint").Value.StringVal).To(Equal("http://minio.minio.svc:9000"))
+		g.Expect(findParam(fetch.Params, "s3-region").Value.StringVal).To(Equal("us-east-1"))
+		g.Expect(findParam(fetch.Params, "s3-bucket").Value.StringVal).To(Equal("sources"))
+		g.Expect(findParam(fetch.Params, "s3-key").Value.StringVal).To(Equal("glue/app v2#1.tar.gz"))
+		g.Expect(findParam(fetch.Params, "s3-path").Value.StringVal).To(Equal("/sources/glue/app%20v2%231.tar.gz"))
+		g.Expect(fetch.Workspaces).To(ContainElement(tektonv1.WorkspacePipelineTaskBinding{Name: "s3-credentials", Workspace: "s3-credentials"}))
+
+		g.Expect(pr.Spec.Workspaces).To(HaveLen(2))
+		g.Expect(pr.Spec.Workspaces[1].Secret.SecretName).To(Equal("minio-creds"))
+	})
+
+	t.Run("anonymous S3 object on AWS", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &FunctionReconciler{}
+		pr := r.buildPipelineRun(newFunction(&functionsv1alpha1.SourceSpec{
+			S3: &functionsv1alpha1.S3Source{Bucket: "public", Key: "app.tar", Region: "eu-west-1"},
+		}))
+
+		fetch := pr.Spec.PipelineSpec.Tasks[0]
+		g.Expect(findParam(fetch.Params, "s3-endpoint").Value.StringVal).To(Equal("https://s3.eu-west-1.amazonaws.com"))
+		g.Expect(findParam(fetch.Params, "s3-region").Value.StringVal).To(Equal("eu-west-1"))
+		g.Expect(findParam(fetch.Params, "s3-path").Value.StringVal).To(Equal("/public/app.tar"))
+		g.Expect(fetch.Workspaces).To(HaveLen(1))
+		g.Expect(pr.Spec.Workspaces).To(HaveLen(1))
+	})
+}
+
+func TestParseBuildMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected *functionsv1alpha1.RuntimeStatus
+	}{
+		{
+			name: "buildpacks and process types",
+			raw: `{"buildpacks":[{"id":"paketo-buildpacks/node-engine","version":"4.1.2"},{"id":"paketo-buildpacks/npm-start","version":"2.0.1"}],"processes":["web"]}
+`,
+			expected: &functionsv1alpha1.RuntimeStatus{
+				Buildpacks: []functionsv1alpha1.DetectedBuildpack{
+					{ID: "paketo-buildpacks/node-engine", Version: "4.1.2"},
+					{ID: "paketo-buildpacks/npm-start", Version: "2.0.1"},
+				},
+				ProcessTypes: []string{"web"},
+			},
+		},
+		{
+			name: "entries without id are skipped",
+			raw:  `{"buildpacks":[{"id":"","version":"1"},{"id":"paketo-buildpacks/go","version":"4.0.0"}],"processes":[""]}`,
+			expected: &functionsv1alpha1.RuntimeStatus{
+				Buildpacks: []functionsv1alpha1.DetectedBuildpack{{ID: "paketo-buildpacks/go", Version: "4.0.0"}},
+			},
+		},
+		{name: "empty payload", raw: `{"buildpacks":[],"processes":[]}`, expected: nil},
+		{name: "empty result", raw: "", expected: nil},
+		{name: "malformed result", raw: "not json", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(parseBuildMetadata(tt.raw)).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestBuildpacksPhasesTaskPublishesBuildMetadata(t *testing.T) {
+	g := NewWithT(t)
+	r := &FunctionReconciler{}
+	task := r.buildBuildpacksPhasesTask("default")
+
+	var names []string
+	for _, result := range task.Spec.Results {
+		names = append(names, result.Name)
+	}
+	g.Expect(names).To(ConsistOf("APP_IMAGE_DIGEST", BuildMetadataResult))
+	g.Expect(resultsScript).To(ContainSubstring("/layers/group.toml"))
+	g.Expect(resultsScript).To(ContainSubstring("$(results.BUILD_METADATA.path)"))
+}
+
+func TestBuildProgress(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *metav1.Time { return &metav1.Time{Time: start.Add(d)} }
+	withCondition := func(status v1.ConditionStatus) duckv1.Status {
+		return duckv1.Status{Conditions: duckv1.Conditions{{Type: apis.ConditionSucceeded, Status: status}}}
+	}
+
+	newPipelineRun := func() *tektonv1.PipelineRun {
+		pr := &tektonv1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "hello-build", Namespace: "default"}}
+		pr.Status.StartTime = at(0)
+		pr.Status.PipelineSpec = &tektonv1.PipelineSpec{Tasks: []tektonv1.PipelineTask{
+			{Name: "fetch-source"}, {Name: "build-and-push"}, {Name: "zenith-post-scan"},
+		}}
+		pr.Status.Status = withCondition(v1.ConditionUnknown)
+		return pr
+	}
+
+	fetched := &tektonv1.TaskRun{}
+	fetched.Status.Status = withCondition(v1.ConditionTrue)
+	fetched.Status.StartTime = at(0)
+	fetched.Status.CompletionTime = at(20 * time.Second)
+
+	building := &tektonv1.TaskRun{}
+	building.Status.Status = withCondition(v1.ConditionUnknown)
+	building.Status.StartTime = at(25 * time.Second)
+	building.Status.Steps = []tektonv1.StepState{
+		{Name: "detect", ContainerState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{}}},
+		{Name: "export", ContainerState: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+		{Name: "results", ContainerState: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}}},
+	}
+
+	t.Run("running build reports the current task and step", func(t *testing.T) {
+		g := NewWithT(t)
+		progress := buildProgress(newPipelineRun(), map[string]*tektonv1.TaskRun{
+			"fetch-source":   fetched,
+			"build-and-push": building,
+		}, start.Add(2*time.Minute+13*time.Second))
+
+		g.Expect(progress.PipelineRun).To(Equal("hello-build"))
+		g.Expect(progress.CurrentTask).To(Equal("build-and-push"))
+		g.Expect(progress.CurrentStep).To(Equal("export"))
+		g.Expect(progress.Elapsed).To(Equal("2m13s"))
+		g.Expect(progress.Summary).To(Equal("exporting image (2m13s)"))
+		g.Expect(progress.Tasks).To(Equal([]functionsv1alpha1.BuildTaskProgress{
+			{Name: "fetch-source", Status: "Succeeded", StartTime: at(0), CompletionTime: at(20 * time.Second), Duration: "20s"},
+			{Name: "build-and-push", Status: "Running", StartTime: at(25 * time.Second), Duration: "1m48s"},
+			{Name: "zenith-post-scan", Status: "Pending"},
+		}))
+	})
+
+	t.Run("finished build keeps the final durations", func(t *testing.T) {
+		g := NewWithT(t)
+		pr := newPipelineRun()
+		pr.Status.Status = withCondition(v1.ConditionFalse)
+		pr.Status.CompletionTime = at(3 * time.Minute)
+		pr.Status.SkippedTasks = []tektonv1.SkippedTask{{Name: "zenith-post-scan"}}
+
+		failed := building.DeepCopy()
+		failed.Status.Status = withCondition(v1.ConditionFalse)
+		failed.Status.CompletionTime = at(3 * time.Minute)
+
+		progress := buildProgress(pr, map[string]*tektonv1.TaskRun{
+			"fetch-source":   fetched,
+			"build-and-push": failed,
+		}, start.Add(time.Hour))
+
+		g.Expect(progress.CurrentTask).To(BeEmpty())
+		g.Expect(progress.Elapsed).To(Equal("3m0s"))
+		g.Expect(progress.Summary).To(Equal("failed (3m0s)"))
+		g.Expect(progress.Tasks[1].Status).To(Equal("Failed"))
+		g.Expect(progress.Tasks[1].Duration).To(Equal("2m35s"))
+		g.Expect(progress.Tasks[2].Status).To(Equal("Skipped"))
+	})
+
+	t.Run("unknown steps fall back to task/step", func(t *testing.T) {
+		g := NewWithT(t)
+		scan := &tektonv1.TaskRun{}
+		scan.Status.Status = withCondition(v1.ConditionUnknown)
+		scan.Status.StartTime = at(time.Minute)
+		scan.Status.Steps = []tektonv1.StepState{{Name: "trivy", ContainerState: v1.ContainerState{Running: &v1.ContainerStateRunning{}}}}
+
+		progress := buildProgress(newPipelineRun(), map[string]*tektonv1.TaskRun{
+			"fetch-source":     fetched,
+			"build-and-push":   fetched,
+			"zenith-post-scan": scan,
+		}, start.Add(90*time.Second))
+
+		g.Expect(progress.Summary).To(Equal("zenith-post-scan/trivy (1m30s)"))
+	})
+
+	t.Run("progress comparison ignores sub-second timestamps", func(t *testing.T) {
+		g := NewWithT(t)
+		a := buildProgress(newPipelineRun(), map[string]*tektonv1.TaskRun{"fetch-source": fetched}, start.Add(time.Minute))
+		b := a.DeepCopy()
+		b.StartTime = &metav1.Time{Time: start.Add(300 * time.Millisecond)}
+		g.Expect(buildProgressEqual(a, b)).To(BeTrue())
+
+		b.Elapsed = "1m1s"
+		g.Expect(buildProgressEqual(a, b)).To(BeFalse())
+	})
+}
+
+func TestHashSourceFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	files := map[string]string{"main.py": "print('hi')", "Procfile": "web: python main.py"}
+	g.Expect(hashSourceFiles(files, nil)).To(Equal(hashSourceFiles(map[string]string{
+		"Procfile": "web: python main.py",
+		"main.py":  "print('hi')",
+	}, nil)))
+	g.Expect(hashSourceFiles(files, nil)).To(HavePrefix("sha256:"))
+	g.Expect(hashSourceFiles(files, nil)).NotTo(Equal(hashSourceFiles(map[string]string{
+		"main.py":  "print('hello')",
+		"Procfile": "web: python main.py",
+	}, nil)))
+	// moving content between files changes the digest
+	g.Expect(hashSourceFiles(map[string]string{"a": "bc"}, nil)).NotTo(Equal(hashSourceFiles(map[string]string{"ab": "c"}, nil)))
+}
+
+func TestReconcileOCISource(t *testing.T) {
+	ctx := context.Background()
+	base := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "glue", Namespace: "default", Generation: 1},
+		Spec:       functionsv1alpha1.FunctionSpec{Source: &functionsv1alpha1.SourceSpec{OCI: &functionsv1alpha1.OCISource{}}},
+	}
+
+	t.Run("a digest reference is the revision", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Source.OCI.Image = "registry.example.com/glue-src@sha256:" + strings.Repeat("ab", 32)
+		r := newFakeReconciler(nil, function)
+
+		resolved, result, err := r.reconcileSource(ctx, function)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(resolved.Revision).To(Equal(function.Spec.Source.OCI.Image))
+	})
+
+	// Um novo push para a mesma tag não mudaria a revisão, então a tag é recusada
+	t.Run("a tag is rejected", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Source.OCI.Image = "registry.example.com/glue-src:dev"
+		r := newFakeReconciler(nil, function)
+
+		resolved, result, err := r.reconcileSource(ctx, function)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeFalse())
+		g.Expect(resolved.Revision).To(BeEmpty())
+
+		ready := meta.FindStatusCondition(function.Status.Conditions, "Ready")
+		g.Expect(ready.Reason).To(Equal("InvalidSource"))
+		g.Expect(ready.Message).To(ContainSubstring("precisa ser fixada por digest"))
+	})
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -1037,3 +1770,21 @@ func stringPtr(s string) *string {
 func int32Ptr(i int32) *int32 {
 	return &i
 }
+
+// newFakeReconciler returns a FunctionReconciler backed by a fake client holding objs. The scheme
+// always knows the operator API; addToScheme registers the other types the test needs.
+func newFakeReconciler(addToScheme []func(*runtime.Scheme) error, objs ...client.Object) *FunctionReconciler {
+	scheme := runtime.NewScheme()
+	_ = functionsv1alpha1.AddToScheme(scheme)
+	for _, add := range addToScheme {
+		_ = add(scheme)
+	}
+	return &FunctionReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(objs...).
+			WithStatusSubresource(&functionsv1alpha1.Function{}, &functionsv1alpha1.PreviewPolicy{}).
+			Build(),
+		Scheme: scheme,
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"os"
+	"strings"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// imagePullRewritesEnv is the environment variable holding the pull-through rewrite rules.
+// Format: "<push-prefix>=<pull-prefix>[,<push-prefix>=<pull-prefix>...]"
+// Example: "registry.registry.svc.cluster.local:5000=127.0.0.1:30500"
+const imagePullRewritesEnv = "IMAGE_PULL_REWRITES"
+
+// imagePullRewriteRules reads the pull-through rewrite rules from the operator configuration.
+// Malformed entries (without "=" or with an empty side) are ignored.
+func imagePullRewriteRules() []functionsv1alpha1.ImageRewrite {
+	raw := os.Getenv(imagePullRewritesEnv)
+	if raw == "" {
+		return nil
+	}
+
+	var rules []functionsv1alpha1.ImageRewrite
+	for _, entry := range strings.Split(raw, ",") {
+		from, to, found := strings.Cut(strings.TrimSpace(entry), "=")
+		from = strings.TrimSpace(from)
+		to = strings.TrimSpace(to)
+		if !found || from == "" || to == "" {
+			continue
+		}
+		rules = append(rules, functionsv1alpha1.ImageRewrite{From: from, To: to})
+	}
+	return rules
+}
+
+/*
+rewriteImageForPull traduz a referência de imagem usada no push (pelo pipeline de build)
+para o endpoint que os nós do cluster conseguem alcançar no pull.
+Quando mais de uma regra se aplica, vence a de prefixo mais longo.
+Um prefixo só casa em fronteira de componente (seguido de "/" ou "@"),
+evitando que "registry.local" reescreva "registry.local.example.com/app".
+Retorna a imagem resultante e a regra aplicada (nil quando nenhuma regra se aplica).
+*/
+func rewriteImageForPull(image string) (string, *functionsv1alpha1.ImageRewrite) {
+	var applied *functionsv1alpha1.ImageRewrite
+	for _, rule := range imagePullRewriteRules() {
+		if !strings.HasPrefix(image, rule.From) {
+			continue
+		}
+		rest := image[len(rule.From):]
+		if rest != "" && !strings.HasPrefix(rest, "/") && !strings.HasPrefix(rest, "@") {
+			continue
+		}
+		if applied == nil || len(rule.From) > len(applied.From) {
+			applied = &functionsv1alpha1.ImageRewrite{From: rule.From, To: rule.To}
+		}
+	}
+
+	if applied == nil {
+		return image, nil
+	}
+	return applied.To + image[len(applied.From):], applied
+}
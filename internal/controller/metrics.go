@@ -0,0 +1,261 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// Build strategies and results used as metric labels
+const (
+	buildStrategyBuildpacks  = "buildpacks"
+	buildStrategyPipelineRef = "pipelineRef"
+
+	buildResultSucceeded = "succeeded"
+	buildResultFailed    = "failed"
+)
+
+// Domain metrics exposed on the manager's metrics endpoint, next to controller-runtime's defaults.
+var (
+	// buildQueueDepth is the number of Functions waiting for a build slot
+	buildQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "zenith_build_queue_depth",
+		Help: "Number of Functions waiting in the build admission queue",
+	})
+
+	// buildsRunning is the number of builds holding a slot in the admission queue
+	buildsRunning = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "zenith_builds_running",
+		Help: "Number of builds admitted by the build admission queue and still running",
+	})
+
+	// buildDuration is the duration of finished build PipelineRuns
+	buildDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "zenith_build_duration_seconds",
+		Help:    "Duration of finished Function builds (PipelineRuns)",
+		Buckets: []float64{30, 60, 120, 180, 300, 450, 600, 900, 1200, 1800, 3600},
+	}, []string{"namespace", "strategy", "result"})
+
+	// buildFailures counts failed builds by classified reason
+	buildFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "zenith_build_failures_total",
+		Help: "Number of failed Function builds by classified reason",
+	}, []string{"namespace", "strategy", "reason"})
+
+	// timeToReady is the time between a spec change (new generation) and the Function becoming Ready
+	timeToReady = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "zenith_function_time_to_ready_seconds",
+		Help:    "Time from a Function spec change (new generation) until the Function is Ready",
+		Buckets: []float64{10, 30, 60, 120, 180, 300, 600, 900, 1800, 3600},
+	}, []string{"namespace"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		buildQueueDepth,
+		buildsRunning,
+		buildDuration,
+		buildFailures,
+		timeToReady,
+	)
+}
+
+// buildStrategy returns the strategy label of a Function's builds.
+func buildStrategy(function *functionsv1alpha1.Function) string {
+	if function.Spec.Build.PipelineRef != nil {
+		return buildStrategyPipelineRef
+	}
+	return buildStrategyBuildpacks
+}
+
+/*
+classifyBuildFailure reduz a falha de um build a um conjunto pequeno de motivos,
+adequado como label de métrica (os motivos do Tekton e as mensagens têm cardinalidade alta):
+Timeout, Cancelled, TestsFailed, SourceFetchFailed, BuildpacksFailed ou TaskFailed.
+*/
+func classifyBuildFailure(function *functionsv1alpha1.Function, tektonReason string, progress *functionsv1alpha1.BuildProgress) string {
+	switch tektonReason {
+	case "PipelineRunTimeout", "TaskRunTimeout":
+		return "Timeout"
+	case "Cancelled", "CancelledRunningFinally", "StoppedRunningFinally", "PipelineRunCancelled", "TaskRunCancelled":
+		return "Cancelled"
+	}
+
+	for _, task := range progress.Tasks {
+		if task.Status != buildTaskFailed {
+			continue
+		}
+		switch {
+		case isBuildStepTask(function, task.Name):
+			return "TestsFailed"
+		case task.Name == "fetch-source":
+			return "SourceFetchFailed"
+		case task.Name == buildPipelineTaskName:
+			return "BuildpacksFailed"
+		}
+		return "TaskFailed"
+	}
+	return "TaskFailed"
+}
+
+// recordBuildCompletion observes a finished build in the build duration and failure metrics.
+func recordBuildCompletion(function *functionsv1alpha1.Function, progress *functionsv1alpha1.BuildProgress, succeeded bool, tektonReason string) {
+	if progress.StartTime == nil || progress.CompletionTime == nil {
+		return
+	}
+
+	strategy := buildStrategy(function)
+	result := buildResultSucceeded
+	if !succeeded {
+		result = buildResultFailed
+		buildFailures.WithLabelValues(function.Namespace, strategy, classifyBuildFailure(function, tektonReason, progress)).Inc()
+	}
+	buildDuration.WithLabelValues(function.Namespace, strategy, result).
+		Observe(progress.CompletionTime.Sub(progress.StartTime.Time).Seconds())
+}
+
+// readinessTracker measures the time between a new Function generation and the Function becoming Ready.
+// It is kept in memory: generations that were pending when the operator restarted are not observed.
+type readinessTracker struct {
+	mu      sync.Mutex
+	pending map[types.NamespacedName]pendingGeneration
+}
+
+type pendingGeneration struct {
+	generation int64
+	since      time.Time
+}
+
+func newReadinessTracker() *readinessTracker {
+	return &readinessTracker{pending: map[types.NamespacedName]pendingGeneration{}}
+}
+
+// readiness is the process-wide tracker feeding zenith_function_time_to_ready_seconds
+var readiness = newReadinessTracker()
+
+/*
+observe registra o estado atual de uma Function e retorna o tempo até Ready
+quando a geração pendente acabou de ficar pronta (ok=true apenas uma vez por geração).
+*/
+func (t *readinessTracker) observe(function *functionsv1alpha1.Function, now time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := types.NamespacedName{Name: function.Name, Namespace: function.Namespace}
+	pending, tracked := t.pending[key]
+
+	if meta.IsStatusConditionTrue(function.Status.Conditions, "Ready") && function.Status.ObservedGeneration == function.Generation {
+		if !tracked || pending.generation != function.Generation {
+			return 0, false
+		}
+		delete(t.pending, key)
+		return now.Sub(pending.since), true
+	}
+
+	if !tracked || pending.generation != function.Generation {
+		since := now
+		// A primeira geração começa na criação do objeto
+		if function.Generation == 1 && !function.CreationTimestamp.IsZero() {
+			since = function.CreationTimestamp.Time
+		}
+		t.pending[key] = pendingGeneration{generation: function.Generation, since: since}
+	}
+	return 0, false
+}
+
+// forget drops the state of a deleted Function.
+func (t *readinessTracker) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, key)
+}
+
+// functionConditionCollector exposes zenith_functions, the number of Functions per Ready status and reason,
+// computed from the manager's cache at scrape time.
+type functionConditionCollector struct {
+	reader client.Reader
+	desc   *prometheus.Desc
+}
+
+func newFunctionConditionCollector(reader client.Reader) *functionConditionCollector {
+	return &functionConditionCollector{
+		reader: reader,
+		desc: prometheus.NewDesc("zenith_functions",
+			"Number of Functions by namespace and Ready condition status and reason",
+			[]string{"namespace", "status", "reason"}, nil),
+	}
+}
+
+func (c *functionConditionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *functionConditionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var functions functionsv1alpha1.FunctionList
+	if err := c.reader.List(ctx, &functions); err != nil {
+		// Cache ainda não sincronizado: a série simplesmente não aparece neste scrape
+		return
+	}
+
+	counts := functionConditionCounts(functions.Items)
+	for labels, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), labels.namespace, labels.status, labels.reason)
+	}
+}
+
+type functionConditionLabels struct {
+	namespace, status, reason string
+}
+
+// functionConditionCounts groups Functions by namespace and Ready condition. Functions without a Ready
+// condition yet are counted with status "Unknown" and reason "Pending".
+func functionConditionCounts(functions []functionsv1alpha1.Function) map[functionConditionLabels]int {
+	counts := map[functionConditionLabels]int{}
+	for _, function := range functions {
+		labels := functionConditionLabels{namespace: function.Namespace, status: string(metav1.ConditionUnknown), reason: "Pending"}
+		if ready := meta.FindStatusCondition(function.Status.Conditions, "Ready"); ready != nil {
+			labels.status = string(ready.Status)
+			labels.reason = ready.Reason
+		}
+		counts[labels]++
+	}
+	return counts
+}
+
+// registerFunctionConditionCollector registers the zenith_functions collector once per process.
+func registerFunctionConditionCollector(reader client.Reader) error {
+	err := metrics.Registry.Register(newFunctionConditionCollector(reader))
+	if are := (prometheus.AlreadyRegisteredError{}); errors.As(err, &are) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestClassifyBuildFailure(t *testing.T) {
+	failedTask := func(name string) *functionsv1alpha1.BuildProgress {
+		return &functionsv1alpha1.BuildProgress{Tasks: []functionsv1alpha1.BuildTaskProgress{
+			{Name: "fetch-source", Status: "Succeeded"},
+			{Name: name, Status: "Failed"},
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		pipelineRef  bool
+		tektonReason string
+		progress     *functionsv1alpha1.BuildProgress
+		expected     string
+	}{
+		{"pipeline timeout", false, "PipelineRunTimeout", failedTask("build-and-push"), "Timeout"},
+		{"cancelled", false, "Cancelled", failedTask("build-and-push"), "Cancelled"},
+		{"user test step", false, "Failed", failedTask("zenith-pre-unit-tests"), "TestsFailed"},
+		{"task named like a step", false, "Failed", failedTask("pre-flight"), "TaskFailed"},
+		{"pipelineRef task", true, "Failed", failedTask("zenith-pre-unit-tests"), "TaskFailed"},
+		{"source fetch", false, "Failed", failedTask("fetch-source"), "SourceFetchFailed"},
+		{"buildpacks", false, "Failed", failedTask("build-and-push"), "BuildpacksFailed"},
+		{"custom pipeline task", true, "Failed", failedTask("kaniko"), "TaskFailed"},
+		{"no failed task", false, "Failed", &functionsv1alpha1.BuildProgress{}, "TaskFailed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{}
+			if tt.pipelineRef {
+				function.Spec.Build.PipelineRef = &functionsv1alpha1.BuildPipelineRef{Name: "custom"}
+			}
+			g.Expect(classifyBuildFailure(function, tt.tektonReason, tt.progress)).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestRecordBuildCompletion(t *testing.T) {
+	g := NewWithT(t)
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	function := &functionsv1alpha1.Function{ObjectMeta: metav1.ObjectMeta{Name: "fn", Namespace: "metrics-test"}}
+	progress := &functionsv1alpha1.BuildProgress{
+		StartTime:      &metav1.Time{Time: start},
+		CompletionTime: &metav1.Time{Time: start.Add(2 * time.Minute)},
+		Tasks:          []functionsv1alpha1.BuildTaskProgress{{Name: "zenith-pre-lint", Status: "Failed"}},
+	}
+
+	recordBuildCompletion(function, progress, false, "Failed")
+	recordBuildCompletion(function, progress, true, "Succeeded")
+
+	g.Expect(testutil.ToFloat64(buildFailures.WithLabelValues("metrics-test", "buildpacks", "TestsFailed"))).To(Equal(1.0))
+	g.Expect(testutil.CollectAndCount(buildDuration.MustCurryWith(map[string]string{"namespace": "metrics-test"}))).To(Equal(2))
+
+	// running builds are not observed
+	recordBuildCompletion(function, &functionsv1alpha1.BuildProgress{StartTime: progress.StartTime}, false, "")
+	g.Expect(testutil.ToFloat64(buildFailures.WithLabelValues("metrics-test", "buildpacks", "TestsFailed"))).To(Equal(1.0))
+}
+
+func TestReadinessTracker(t *testing.T) {
+	created := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	newFunction := func(generation, observed int64, ready metav1.ConditionStatus) *functionsv1alpha1.Function {
+		return &functionsv1alpha1.Function{
+			ObjectMeta: metav1.ObjectMeta{Name: "fn", Namespace: "default", Generation: generation, CreationTimestamp: metav1.Time{Time: created}},
+			Status: functionsv1alpha1.FunctionStatus{
+				ObservedGeneration: observed,
+				Conditions:         []metav1.Condition{{Type: "Ready", Status: ready, Reason: "Test"}},
+			},
+		}
+	}
+
+	t.Run("first generation is measured from creation", func(t *testing.T) {
+		g := NewWithT(t)
+		tracker := newReadinessTracker()
+
+		_, ok := tracker.observe(newFunction(1, 1, metav1.ConditionFalse), created.Add(10*time.Second))
+		g.Expect(ok).To(BeFalse())
+
+		elapsed, ok := tracker.observe(newFunction(1, 1, metav1.ConditionTrue), created.Add(3*time.Minute))
+		g.Expect(ok).To(BeTrue())
+		g.Expect(elapsed).To(Equal(3 * time.Minute))
+
+		// observed only once per generation
+		_, ok = tracker.observe(newFunction(1, 1, metav1.ConditionTrue), created.Add(4*time.Minute))
+		g.Expect(ok).To(BeFalse())
+	})
+
+	t.Run("spec change is measured from the first reconcile of the new generation", func(t *testing.T) {
+		g := NewWithT(t)
+		tracker := newReadinessTracker()
+		changed := created.Add(time.Hour)
+
+		// Ready=True for the previous generation does not count for the new one
+		_, ok := tracker.observe(newFunction(2, 1, metav1.ConditionTrue), changed)
+		g.Expect(ok).To(BeFalse())
+		_, ok = tracker.observe(newFunction(2, 2, metav1.ConditionFalse), changed.Add(time.Minute))
+		g.Expect(ok).To(BeFalse())
+
+		elapsed, ok := tracker.observe(newFunction(2, 2, metav1.ConditionTrue), changed.Add(90*time.Second))
+		g.Expect(ok).To(BeTrue())
+		g.Expect(elapsed).To(Equal(90 * time.Second))
+	})
+
+	t.Run("ready functions seen for the first time are not measured", func(t *testing.T) {
+		g := NewWithT(t)
+		tracker := newReadinessTracker()
+		_, ok := tracker.observe(newFunction(3, 3, metav1.ConditionTrue), created.Add(time.Hour))
+		g.Expect(ok).To(BeFalse())
+	})
+}
+
+func TestFunctionConditionCounts(t *testing.T) {
+	g := NewWithT(t)
+	withReady := func(namespace string, status metav1.ConditionStatus, reason string) functionsv1alpha1.Function {
+		return functionsv1alpha1.Function{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			Status: functionsv1alpha1.FunctionStatus{
+				Conditions: []metav1.Condition{{Type: "Ready", Status: status, Reason: reason}},
+			},
+		}
+	}
+
+	counts := functionConditionCounts([]functionsv1alpha1.Function{
+		withReady("team-a", metav1.ConditionTrue, "Ready"),
+		withReady("team-a", metav1.ConditionTrue, "Ready"),
+		withReady("team-a", metav1.ConditionFalse, "BuildFailed"),
+		withReady("team-b", metav1.ConditionFalse, "BuildFailed"),
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b"}},
+	})
+
+	g.Expect(counts).To(Equal(map[functionConditionLabels]int{
+		{namespace: "team-a", status: "True", reason: "Ready"}:        2,
+		{namespace: "team-a", status: "False", reason: "BuildFailed"}: 1,
+		{namespace: "team-b", status: "False", reason: "BuildFailed"}: 1,
+		{namespace: "team-b", status: "Unknown", reason: "Pending"}:   1,
+	}))
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	knconfig "knative.dev/serving/pkg/apis/config"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// knativeServingNamespaceEnv is the environment variable holding the namespace where Knative Serving is installed
+const knativeServingNamespaceEnv = "KNATIVE_SERVING_NAMESPACE"
+
+const (
+	defaultKnativeServingNamespace = "knative-serving"
+	knativeFeaturesConfigMapName   = "config-features"
+)
+
+/*
+knativeFeatures lê as feature flags do Knative Serving (ConfigMap config-features).
+Sem o ConfigMap, valem os padrões do Knative.
+*/
+func (r *FunctionReconciler) knativeFeatures(ctx context.Context) (*knconfig.Features, error) {
+	namespace := os.Getenv(knativeServingNamespaceEnv)
+	if namespace == "" {
+		namespace = defaultKnativeServingNamespace
+	}
+
+	configMap := &v1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: knativeFeaturesConfigMapName, Namespace: namespace}, configMap)
+	if errors.IsNotFound(err) {
+		return knconfig.NewFeaturesConfigFromMap(nil)
+	} else if err != nil {
+		return nil, err
+	}
+	return knconfig.NewFeaturesConfigFromConfigMap(configMap)
+}
+
+/*
+validatePodSettings verifica se cada campo de 'spec.deploy.pod' é permitido pelas feature flags do Knative.
+Sem a flag, o webhook do Knative rejeitaria o Service; validar antes dá uma mensagem que aponta a flag.
+*/
+func validatePodSettings(function *functionsv1alpha1.Function, features *knconfig.Features) error {
+	pod := function.Spec.Deploy.Pod
+	if pod == nil {
+		return nil
+	}
+
+	gated := []struct {
+		field string
+		set   bool
+		flag  knconfig.Flag
+		name  string
+	}{
+		{"nodeSelector", len(pod.NodeSelector) > 0, features.PodSpecNodeSelector, "kubernetes.podspec-nodeselector"},
+		{"tolerations", len(pod.Tolerations) > 0, features.PodSpecTolerations, "kubernetes.podspec-tolerations"},
+		{"affinity", pod.Affinity != nil, features.PodSpecAffinity, "kubernetes.podspec-affinity"},
+		{"topologySpreadConstraints", len(pod.TopologySpreadConstraints) > 0, features.PodSpecTopologySpreadConstraints, "kubernetes.podspec-topologyspreadconstraints"},
+		{"priorityClassName", pod.PriorityClassName != "", features.PodSpecPriorityClassName, "kubernetes.podspec-priorityclassname"},
+		{"runtimeClassName", pod.RuntimeClassName != nil, features.PodSpecRuntimeClassName, "kubernetes.podspec-runtimeclassname"},
+	}
+	for _, g := range gated {
+		if g.set && g.flag == knconfig.Disabled {
+			return fmt.Errorf("spec.deploy.pod.%s exige a flag '%s' habilitada no Knative (config-features)", g.field, g.name)
+		}
+	}
+
+	// Com 'secure-pod-defaults', o Knative aceita um seccompProfile RuntimeDefault ou Unconfined no pod sem a flag
+	if sc := pod.SecurityContext; sc != nil && features.PodSpecSecurityContext == knconfig.Disabled {
+		onlySeccomp := sc.RunAsUser == nil && sc.RunAsGroup == nil && sc.RunAsNonRoot == nil && sc.FSGroup == nil &&
+			len(sc.SupplementalGroups) == 0 && sc.SeccompProfile != nil &&
+			(sc.SeccompProfile.Type == v1.SeccompProfileTypeRuntimeDefault || sc.SeccompProfile.Type == v1.SeccompProfileTypeUnconfined)
+		if !onlySeccomp || features.SecurePodDefaults != knconfig.Enabled {
+			return fmt.Errorf("spec.deploy.pod.securityContext exige a flag 'kubernetes.podspec-securitycontext' habilitada no Knative (config-features)")
+		}
+	}
+
+	// Sem a flag, o Knative só aceita adicionar NET_BIND_SERVICE, e apenas com 'secure-pod-defaults'
+	if sc := pod.ContainerSecurityContext; sc != nil && sc.Capabilities != nil && len(sc.Capabilities.Add) > 0 &&
+		features.ContainerSpecAddCapabilities != knconfig.Enabled {
+		netBindOnly := len(sc.Capabilities.Add) == 1 && sc.Capabilities.Add[0] == "NET_BIND_SERVICE"
+		if !netBindOnly || features.SecurePodDefaults != knconfig.Enabled {
+			return fmt.Errorf("spec.deploy.pod.containerSecurityContext.capabilities.add exige a flag 'kubernetes.containerspec-addcapabilities' habilitada no Knative (config-features)")
+		}
+	}
+	return nil
+}
+
+// applyPodSettings copies spec.deploy.pod into the revision PodSpec; the container securityContext goes to every container.
+func applyPodSettings(podSpec *v1.PodSpec, function *functionsv1alpha1.Function) {
+	pod := function.Spec.Deploy.Pod
+	if pod == nil {
+		return
+	}
+
+	podSpec.NodeSelector = pod.NodeSelector
+	podSpec.Tolerations = pod.Tolerations
+	podSpec.Affinity = pod.Affinity
+	podSpec.TopologySpreadConstraints = pod.TopologySpreadConstraints
+	podSpec.PriorityClassName = pod.PriorityClassName
+	podSpec.RuntimeClassName = pod.RuntimeClassName
+	if sc := pod.SecurityContext; sc != nil {
+		podSpec.SecurityContext = &v1.PodSecurityContext{
+			RunAsUser:          sc.RunAsUser,
+			RunAsGroup:         sc.RunAsGroup,
+			RunAsNonRoot:       sc.RunAsNonRoot,
+			FSGroup:            sc.FSGroup,
+			SupplementalGroups: sc.SupplementalGroups,
+			SeccompProfile:     sc.SeccompProfile,
+		}
+	}
+
+	if sc := pod.ContainerSecurityContext; sc != nil {
+		containerSecurityContext := v1.SecurityContext{
+			RunAsUser:                sc.RunAsUser,
+			RunAsGroup:               sc.RunAsGroup,
+			RunAsNonRoot:             sc.RunAsNonRoot,
+			AllowPrivilegeEscalation: sc.AllowPrivilegeEscalation,
+			ReadOnlyRootFilesystem:   sc.ReadOnlyRootFilesystem,
+			Capabilities:             sc.Capabilities,
+			SeccompProfile:           sc.SeccompProfile,
+		}
+		for i := range podSpec.InitContainers {
+			podSpec.InitContainers[i].SecurityContext = containerSecurityContext.DeepCopy()
+		}
+		for i := range podSpec.Containers {
+			podSpec.Containers[i].SecurityContext = containerSecurityContext.DeepCopy()
+		}
+	}
+}
+
+/*
+podSettingsChanged compara o ServiceAccount, o agendamento e o securityContext do pod no cluster com os desejados.
+O Service desejado deve vir de withKnativeDefaults: com 'secure-pod-defaults', o Knative completa
+o securityContext dos containers e isso não deve gerar atualizações em loop.
+*/
+func podSettingsChanged(current, desired *knservingv1.Service) bool {
+	currentSpec, desiredSpec := current.Spec.Template.Spec.PodSpec, desired.Spec.Template.Spec.PodSpec
+	if currentSpec.ServiceAccountName != desiredSpec.ServiceAccountName ||
+		!equality.Semantic.DeepEqual(currentSpec.NodeSelector, desiredSpec.NodeSelector) ||
+		!equality.Semantic.DeepEqual(currentSpec.Tolerations, desiredSpec.Tolerations) ||
+		!equality.Semantic.DeepEqual(currentSpec.Affinity, desiredSpec.Affinity) ||
+		!equality.Semantic.DeepEqual(currentSpec.TopologySpreadConstraints, desiredSpec.TopologySpreadConstraints) ||
+		currentSpec.PriorityClassName != desiredSpec.PriorityClassName ||
+		!equality.Semantic.DeepEqual(currentSpec.RuntimeClassName, desiredSpec.RuntimeClassName) ||
+		!equality.Semantic.DeepEqual(currentSpec.SecurityContext, desiredSpec.SecurityContext) {
+		return true
+	}
+
+	pairs, ok := containerPairs(current, desired)
+	if !ok {
+		return true
+	}
+	for _, pair := range pairs {
+		if securityContextChanged(pair[0].SecurityContext, pair[1].SecurityContext) {
+			return true
+		}
+	}
+	return false
+}
+
+// securityContextChanged reports whether the container securityContext was set, removed or changed.
+func securityContextChanged(current, desired *v1.SecurityContext) bool {
+	return !equality.Semantic.DeepEqual(current, desired)
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	knconfig "knative.dev/serving/pkg/apis/config"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func knativeFeaturesFrom(t *testing.T, flags map[string]string) *knconfig.Features {
+	features, err := knconfig.NewFeaturesConfigFromMap(flags)
+	NewWithT(t).Expect(err).NotTo(HaveOccurred())
+	return features
+}
+
+func TestValidatePodSettings(t *testing.T) {
+	base := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "billing", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Sidecars: []functionsv1alpha1.FunctionContainer{{Name: "log-shipper", Image: "fluent/fluent-bit:3.0"}},
+				Pod: &functionsv1alpha1.FunctionPodSpec{
+					NodeSelector: map[string]string{"node-role.example.com/functions": "true"},
+					Tolerations: []v1.Toleration{
+						{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "functions", Effect: v1.TaintEffectNoSchedule},
+					},
+					PriorityClassName: "functions-high",
+					SecurityContext: &functionsv1alpha1.PodSecurityContext{
+						RunAsNonRoot:   boolPtr(true),
+						SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+					},
+					ContainerSecurityContext: &functionsv1alpha1.ContainerSecurityContext{
+						AllowPrivilegeEscalation: boolPtr(false),
+						Capabilities:             &v1.Capabilities{Drop: []v1.Capability{"ALL"}},
+					},
+				},
+			},
+		},
+	}
+
+	allowed := map[string]string{
+		"kubernetes.podspec-nodeselector":      "enabled",
+		"kubernetes.podspec-tolerations":       "enabled",
+		"kubernetes.podspec-priorityclassname": "enabled",
+		"kubernetes.podspec-securitycontext":   "enabled",
+		"kubernetes.podspec-affinity":          "allowed",
+		"kubernetes.podspec-runtimeclassname":  "disabled",
+	}
+
+	g := NewWithT(t)
+	g.Expect(validatePodSettings(&functionsv1alpha1.Function{}, knativeFeaturesFrom(t, nil))).To(Succeed())
+	g.Expect(validatePodSettings(base, knativeFeaturesFrom(t, allowed))).To(Succeed())
+	g.Expect(validatePodSettings(base, knativeFeaturesFrom(t, nil))).
+		To(MatchError(ContainSubstring("spec.deploy.pod.nodeSelector exige a flag 'kubernetes.podspec-nodeselector'")))
+
+	runtimeClass := base.DeepCopy()
+	runtimeClass.Spec.Deploy.Pod.RuntimeClassName = stringPtr("gvisor")
+	g.Expect(validatePodSettings(runtimeClass, knativeFeaturesFrom(t, allowed))).
+		To(MatchError(ContainSubstring("kubernetes.podspec-runtimeclassname")))
+
+	affinity := base.DeepCopy()
+	affinity.Spec.Deploy.Pod.Affinity = &v1.Affinity{}
+	g.Expect(validatePodSettings(affinity, knativeFeaturesFrom(t, allowed))).To(Succeed())
+
+	t.Run("pod securityContext", func(t *testing.T) {
+		g := NewWithT(t)
+		seccompOnly := &functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{
+			Pod: &functionsv1alpha1.FunctionPodSpec{SecurityContext: &functionsv1alpha1.PodSecurityContext{
+				SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+			}},
+		}}}
+		g.Expect(validatePodSettings(seccompOnly, knativeFeaturesFrom(t, nil))).
+			To(MatchError(ContainSubstring("kubernetes.podspec-securitycontext")))
+		g.Expect(validatePodSettings(seccompOnly, knativeFeaturesFrom(t, map[string]string{"secure-pod-defaults": "enabled"}))).To(Succeed())
+
+		seccompOnly.Spec.Deploy.Pod.SecurityContext.RunAsUser = int64Ptr(1000)
+		g.Expect(validatePodSettings(seccompOnly, knativeFeaturesFrom(t, map[string]string{"secure-pod-defaults": "enabled"}))).
+			To(MatchError(ContainSubstring("kubernetes.podspec-securitycontext")))
+	})
+
+	t.Run("added capabilities", func(t *testing.T) {
+		g := NewWithT(t)
+		function := &functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{
+			Pod: &functionsv1alpha1.FunctionPodSpec{ContainerSecurityContext: &functionsv1alpha1.ContainerSecurityContext{
+				Capabilities: &v1.Capabilities{Drop: []v1.Capability{"ALL"}, Add: []v1.Capability{"NET_BIND_SERVICE"}},
+			}},
+		}}}
+		g.Expect(validatePodSettings(function, knativeFeaturesFrom(t, nil))).
+			To(MatchError(ContainSubstring("kubernetes.containerspec-addcapabilities")))
+		g.Expect(validatePodSettings(function, knativeFeaturesFrom(t, map[string]string{"secure-pod-defaults": "enabled"}))).To(Succeed())
+		g.Expect(validatePodSettings(function, knativeFeaturesFrom(t, map[string]string{"kubernetes.containerspec-addcapabilities": "enabled"}))).To(Succeed())
+
+		function.Spec.Deploy.Pod.ContainerSecurityContext.Capabilities.Add = []v1.Capability{"NET_ADMIN"}
+		g.Expect(validatePodSettings(function, knativeFeaturesFrom(t, map[string]string{"secure-pod-defaults": "enabled"}))).
+			To(MatchError(ContainSubstring("kubernetes.containerspec-addcapabilities")))
+	})
+}
+
+func TestKnativeFeatures(t *testing.T) {
+	ctx := context.Background()
+	addToScheme := []func(*runtime.Scheme) error{v1.AddToScheme}
+
+	g := NewWithT(t)
+	features, err := newFakeReconciler(addToScheme).knativeFeatures(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(features.PodSpecNodeSelector).To(Equal(knconfig.Disabled))
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "config-features", Namespace: "serving"},
+		Data:       map[string]string{"kubernetes.podspec-nodeselector": "enabled"},
+	}
+	t.Setenv(knativeServingNamespaceEnv, "serving")
+	features, err = newFakeReconciler(addToScheme, configMap).knativeFeatures(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(features.PodSpecNodeSelector).To(Equal(knconfig.Enabled))
+}
+
+func TestBuildKnativeServicePodSettings(t *testing.T) {
+	base := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "billing", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Sidecars: []functionsv1alpha1.FunctionContainer{{Name: "log-shipper", Image: "fluent/fluent-bit:3.0"}},
+				Pod: &functionsv1alpha1.FunctionPodSpec{
+					NodeSelector: map[string]string{"node-role.example.com/functions": "true"},
+					Tolerations: []v1.Toleration{
+						{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "functions", Effect: v1.TaintEffectNoSchedule},
+					},
+					PriorityClassName: "functions-high",
+					SecurityContext: &functionsv1alpha1.PodSecurityContext{
+						RunAsNonRoot:   boolPtr(true),
+						SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+					},
+					ContainerSecurityContext: &functionsv1alpha1.ContainerSecurityContext{
+						AllowPrivilegeEscalation: boolPtr(false),
+						Capabilities:             &v1.Capabilities{Drop: []v1.Capability{"ALL"}},
+					},
+				},
+			},
+		},
+	}
+
+	g := NewWithT(t)
+	r := &FunctionReconciler{}
+
+	spec := r.buildKnativeService(base).Spec.Template.Spec
+	g.Expect(spec.NodeSelector).To(HaveKeyWithValue("node-role.example.com/functions", "true"))
+	g.Expect(spec.Tolerations).To(HaveLen(1))
+	g.Expect(spec.PriorityClassName).To(Equal("functions-high"))
+	g.Expect(spec.SecurityContext).To(Equal(&v1.PodSecurityContext{
+		RunAsNonRoot:   boolPtr(true),
+		SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+	}))
+	for _, container := range spec.Containers {
+		g.Expect(container.SecurityContext).To(Equal(&v1.SecurityContext{
+			AllowPrivilegeEscalation: boolPtr(false),
+			Capabilities:             &v1.Capabilities{Drop: []v1.Capability{"ALL"}},
+		}))
+	}
+
+	plain := r.buildKnativeService(&functionsv1alpha1.Function{}).Spec.Template.Spec
+	g.Expect(plain.NodeSelector).To(BeNil())
+	g.Expect(plain.SecurityContext).To(BeNil())
+	g.Expect(plain.Containers[0].SecurityContext).To(BeNil())
+}
+
+func TestPodSettingsChanged(t *testing.T) {
+	base := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "billing", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Sidecars: []functionsv1alpha1.FunctionContainer{{Name: "log-shipper", Image: "fluent/fluent-bit:3.0"}},
+				Pod: &functionsv1alpha1.FunctionPodSpec{
+					NodeSelector: map[string]string{"node-role.example.com/functions": "true"},
+					Tolerations: []v1.Toleration{
+						{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "functions", Effect: v1.TaintEffectNoSchedule},
+					},
+					PriorityClassName: "functions-high",
+					SecurityContext: &functionsv1alpha1.PodSecurityContext{
+						RunAsNonRoot:   boolPtr(true),
+						SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+					},
+					ContainerSecurityContext: &functionsv1alpha1.ContainerSecurityContext{
+						AllowPrivilegeEscalation: boolPtr(false),
+						Capabilities:             &v1.Capabilities{Drop: []v1.Capability{"ALL"}},
+					},
+				},
+			},
+		},
+	}
+
+	r := &FunctionReconciler{}
+	desired := r.buildKnativeService(base)
+
+	t.Run("in sync with secure-pod-defaults applied", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx := context.Background()
+		defaults, err := knconfig.NewDefaultsConfigFromMap(nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		features := knativeFeaturesFrom(t, map[string]string{"secure-pod-defaults": "enabled"})
+
+		// The webhook completes the securityContext of the Service stored in the cluster
+		current := applyKnativeDefaults(ctx, desired, defaults, features)
+		g.Expect(podSettingsChanged(current, applyKnativeDefaults(ctx, desired, defaults, features))).To(BeFalse())
+
+		withoutPod := r.buildKnativeService(&functionsv1alpha1.Function{})
+		current = applyKnativeDefaults(ctx, withoutPod, defaults, features)
+		g.Expect(current.Spec.Template.Spec.Containers[0].SecurityContext.AllowPrivilegeEscalation).To(Equal(boolPtr(false)))
+		g.Expect(podSettingsChanged(current, withoutPod)).To(BeTrue())
+		g.Expect(podSettingsChanged(current, applyKnativeDefaults(ctx, withoutPod, defaults, features))).To(BeFalse())
+	})
+
+	t.Run("scheduling changed", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Deploy.Pod.NodeSelector = map[string]string{"kubernetes.io/arch": "arm64"}
+		g.Expect(podSettingsChanged(desired, r.buildKnativeService(function))).To(BeTrue())
+
+		function = base.DeepCopy()
+		function.Spec.Deploy.Pod = nil
+		g.Expect(podSettingsChanged(desired, r.buildKnativeService(function))).To(BeTrue())
+	})
+
+	t.Run("container securityContext changed", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Deploy.Pod.ContainerSecurityContext.ReadOnlyRootFilesystem = boolPtr(true)
+		g.Expect(podSettingsChanged(desired, r.buildKnativeService(function))).To(BeTrue())
+	})
+
+	t.Run("container securityContext removed", func(t *testing.T) {
+		g := NewWithT(t)
+		function := base.DeepCopy()
+		function.Spec.Deploy.Pod.ContainerSecurityContext = nil
+		g.Expect(podSettingsChanged(desired, r.buildKnativeService(function))).To(BeTrue())
+	})
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// defaultFunctionPort is the port functions listen on when neither deploy.port nor dapr.appPort is set
+const defaultFunctionPort = 8080
+
+// knativeReservedPorts are used by the Knative queue-proxy sidecar
+var knativeReservedPorts = map[int32]bool{8012: true, 8013: true, 8022: true, 9090: true, 9091: true}
+
+// daprReservedPorts are used by the Dapr sidecar (HTTP and gRPC APIs, and the metrics port set by the operator)
+var daprReservedPorts = map[int32]bool{3500: true, 50001: true, 9095: true}
+
+/*
+functionContainerPort retorna a porta de serviço do container da função.
+Sem 'spec.deploy.port', mantém o comportamento anterior (8080 ou 'dapr.appPort', sem nome),
+para não alterar os Knative Services já existentes. Com ele, a porta recebe o nome do
+protocolo ("http1" ou "h2c"), que o Knative usa para decidir entre HTTP/1 e HTTP/2.
+*/
+func functionContainerPort(function *functionsv1alpha1.Function) v1.ContainerPort {
+	deploy := function.Spec.Deploy
+	if deploy.Port != nil {
+		return namedContainerPort(deploy.Port)
+	}
+	if deploy.Dapr.Enabled && deploy.Dapr.AppPort > 0 {
+		return v1.ContainerPort{ContainerPort: int32(deploy.Dapr.AppPort)}
+	}
+	return v1.ContainerPort{ContainerPort: defaultFunctionPort}
+}
+
+// namedContainerPort translates a PortSpec into a container port named after its protocol.
+func namedContainerPort(port *functionsv1alpha1.PortSpec) v1.ContainerPort {
+	protocol := port.Protocol
+	if protocol == "" {
+		protocol = functionsv1alpha1.PortProtocolHTTP1
+	}
+	return v1.ContainerPort{Name: string(protocol), ContainerPort: port.Number}
+}
+
+/*
+validatePort verifica as regras de 'spec.deploy.port' que a validação do CRD não expressa:
+a porta não pode colidir com os sidecars do Knative e do Dapr, e 'dapr.appPort' deve ser igual a ela.
+*/
+func validatePort(function *functionsv1alpha1.Function) error {
+	deploy := function.Spec.Deploy
+	port := functionContainerPort(function).ContainerPort
+
+	if knativeReservedPorts[port] {
+		return fmt.Errorf("spec.deploy.port: a porta %d é reservada pelo Knative", port)
+	}
+	if !deploy.Dapr.Enabled {
+		return nil
+	}
+	if daprReservedPorts[port] {
+		return fmt.Errorf("spec.deploy.port: a porta %d é reservada pelo sidecar do Dapr", port)
+	}
+	if deploy.Port != nil && deploy.Dapr.AppPort > 0 && int32(deploy.Dapr.AppPort) != deploy.Port.Number {
+		return fmt.Errorf("spec.deploy.dapr.appPort (%d) difere de spec.deploy.port.number (%d)", deploy.Dapr.AppPort, deploy.Port.Number)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestFunctionContainerPort(t *testing.T) {
+	tests := []struct {
+		name   string
+		deploy functionsv1alpha1.DeploySpec
+		want   v1.ContainerPort
+	}{
+		{name: "default", want: v1.ContainerPort{ContainerPort: 8080}},
+		{
+			name:   "dapr app port without deploy.port",
+			deploy: functionsv1alpha1.DeploySpec{Dapr: functionsv1alpha1.DaprConfig{Enabled: true, AppID: "fn", AppPort: 3000}},
+			want:   v1.ContainerPort{ContainerPort: 3000},
+		},
+		{
+			name:   "explicit port defaults to http1",
+			deploy: functionsv1alpha1.DeploySpec{Port: &functionsv1alpha1.PortSpec{Number: 3000}},
+			want:   v1.ContainerPort{Name: "http1", ContainerPort: 3000},
+		},
+		{
+			name:   "h2c",
+			deploy: functionsv1alpha1.DeploySpec{Port: &functionsv1alpha1.PortSpec{Number: 50051, Protocol: functionsv1alpha1.PortProtocolH2C}},
+			want:   v1.ContainerPort{Name: "h2c", ContainerPort: 50051},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: tt.deploy}}
+			g.Expect(functionContainerPort(function)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestValidatePort(t *testing.T) {
+	tests := []struct {
+		name    string
+		deploy  functionsv1alpha1.DeploySpec
+		wantErr string
+	}{
+		{name: "default"},
+		{
+			name: "dapr app port matches",
+			deploy: functionsv1alpha1.DeploySpec{
+				Port: &functionsv1alpha1.PortSpec{Number: 3000},
+				Dapr: functionsv1alpha1.DaprConfig{Enabled: true, AppID: "fn", AppPort: 3000},
+			},
+		},
+		{
+			name: "dapr app port mismatch",
+			deploy: functionsv1alpha1.DeploySpec{
+				Port: &functionsv1alpha1.PortSpec{Number: 3000},
+				Dapr: functionsv1alpha1.DaprConfig{Enabled: true, AppID: "fn", AppPort: 8080},
+			},
+			wantErr: "dapr.appPort (8080) difere de spec.deploy.port.number (3000)",
+		},
+		{
+			name:    "knative queue-proxy port",
+			deploy:  functionsv1alpha1.DeploySpec{Port: &functionsv1alpha1.PortSpec{Number: 8012}},
+			wantErr: "reservada pelo Knative",
+		},
+		{
+			name: "dapr sidecar port",
+			deploy: functionsv1alpha1.DeploySpec{
+				Port: &functionsv1alpha1.PortSpec{Number: 3500},
+				Dapr: functionsv1alpha1.DaprConfig{Enabled: true, AppID: "fn"},
+			},
+			wantErr: "reservada pelo sidecar do Dapr",
+		},
+		{
+			name:   "dapr port is free without dapr",
+			deploy: functionsv1alpha1.DeploySpec{Port: &functionsv1alpha1.PortSpec{Number: 3500}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: tt.deploy}}
+			err := validatePort(function)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestBuildKnativeServicePort(t *testing.T) {
+	g := NewWithT(t)
+
+	function := &functionsv1alpha1.Function{
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Port: &functionsv1alpha1.PortSpec{Number: 50051, Protocol: functionsv1alpha1.PortProtocolH2C},
+				Dapr: functionsv1alpha1.DaprConfig{Enabled: true, AppID: "grpc-fn"},
+			},
+		},
+	}
+	ksvc := (&FunctionReconciler{}).buildKnativeService(function)
+	g.Expect(ksvc.Spec.Template.Spec.Containers[0].Ports).To(Equal([]v1.ContainerPort{{Name: "h2c", ContainerPort: 50051}}))
+	// Dapr defaults its app port (and protocol) from deploy.port
+	g.Expect(ksvc.Spec.Template.Annotations).To(HaveKeyWithValue("dapr.io/app-port", "50051"))
+	g.Expect(ksvc.Spec.Template.Annotations).To(HaveKeyWithValue("dapr.io/app-protocol", "h2c"))
+
+	current := ksvc.DeepCopy()
+	current.Spec.Template.Spec.Containers[0].Ports[0].Name = "http1"
+	g.Expect(revisionSettingsChanged(current, ksvc)).To(BeTrue())
+}
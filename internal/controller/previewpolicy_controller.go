@@ -0,0 +1,506 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+const (
+	// PreviewPolicyLabel records the PreviewPolicy that created a preview Function
+	PreviewPolicyLabel = "functions.zenith.com/preview-policy"
+	// PullRequestLabel records the pull request number deployed by a preview Function
+	PullRequestLabel = "functions.zenith.com/pull-request"
+
+	defaultPreviewPollInterval = time.Minute
+	defaultMaxPreviews         = 10
+
+	// maxPreviewFunctionNameLength leaves room for the suffixes of the resources created per Function
+	// (e.g. "-build" on the PipelineRun, "-00001" on Knative revisions) within the 63-character label limit.
+	maxPreviewFunctionNameLength = 50
+)
+
+// PreviewPolicyReconciler reconciles a PreviewPolicy object
+type PreviewPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// newGitProvider creates the client for the Git provider of a policy; tests replace it with a stand-in.
+	newGitProvider func(policy *functionsv1alpha1.PreviewPolicy, token string) gitProvider
+	// now returns the current time; tests replace it to exercise the TTL.
+	now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=functions.zenith.com,resources=previewpolicies,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=functions.zenith.com,resources=previewpolicies/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=functions.zenith.com,resources=previewpolicies/finalizers,verbs=update
+
+/*
+Reconcile consulta o provedor Git a cada 'pollInterval' e mantém uma Function de preview para cada
+pull request aberto: o spec da Function de referência com 'gitRevision' no commit do pull request.
+Previews de pull requests fechados, ou que excederam o TTL, são removidos.
+Entre as consultas (ex: quando uma Function de preview muda de estado), os previews do último
+status são sincronizados sem chamar o provedor, para não esgotar o limite de requisições da API.
+*/
+func (r *PreviewPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	policy := &functionsv1alpha1.PreviewPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, policy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	parent := &functionsv1alpha1.Function{}
+	err := r.Get(ctx, types.NamespacedName{Name: policy.Spec.FunctionRef.Name, Namespace: policy.Namespace}, parent)
+	if errors.IsNotFound(err) {
+		return r.setPolicyCondition(ctx, policy, "FunctionNotFound",
+			fmt.Sprintf("Function não encontrada: %s", policy.Spec.FunctionRef.Name), 30*time.Second)
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if parent.Spec.GitRepo == "" || parent.Spec.Source != nil {
+		return r.setPolicyCondition(ctx, policy, "InvalidFunction",
+			fmt.Sprintf("A Function %s não usa spec.gitRepo; previews só são suportados para fontes Git", parent.Name), 0)
+	}
+
+	repository := policy.Spec.Repository
+	if repository == "" {
+		var found bool
+		if repository, found = repositoryFromGitURL(parent.Spec.GitRepo); !found {
+			return r.setPolicyCondition(ctx, policy, "InvalidRepository",
+				fmt.Sprintf("Não foi possível obter o repositório de %s; defina spec.repository", parent.Spec.GitRepo), 0)
+		}
+	}
+
+	var token string
+	if policy.Spec.TokenSecretRef != nil {
+		secret := &v1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: policy.Spec.TokenSecretRef.Name, Namespace: policy.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			return r.setPolicyCondition(ctx, policy, "SecretNotFound",
+				fmt.Sprintf("Secret do token não encontrado: %s", policy.Spec.TokenSecretRef.Name), 30*time.Second)
+		} else if err != nil {
+			return ctrl.Result{}, err
+		}
+		token = string(secret.Data["token"])
+	}
+
+	provider := r.gitProvider(policy, token)
+	now := r.clock()
+	var previews []functionsv1alpha1.PreviewStatus
+	if previewPollDue(policy, now) {
+		pullRequests, err := provider.openPullRequests(ctx, repository)
+		if err != nil {
+			logger.Error(err, "Falha ao listar os pull requests", "Repository", repository)
+			return r.setPolicyCondition(ctx, policy, "ProviderUnavailable",
+				fmt.Sprintf("Falha ao listar os pull requests de %s: %v", repository, err), previewPollInterval(policy))
+		}
+		previews = planPreviews(policy, parent, pullRequests, now)
+		policy.Status.LastSyncTime = &metav1.Time{Time: now}
+	} else {
+		previews = make([]functionsv1alpha1.PreviewStatus, len(policy.Status.Previews))
+		for i, preview := range policy.Status.Previews {
+			preview.Expired = previewExpired(policy, preview, now)
+			previews[i] = preview
+		}
+	}
+	active := map[string]bool{}
+	for i := range previews {
+		preview := &previews[i]
+		if preview.Expired {
+			preview.Ready, preview.URL = false, ""
+			continue
+		}
+		active[preview.FunctionName] = true
+
+		function, owned, err := r.reconcilePreviewFunction(ctx, policy, parent, preview)
+		if err != nil {
+			logger.Error(err, "Falha ao sincronizar a Function de preview", "Function.Name", preview.FunctionName)
+			return ctrl.Result{}, err
+		}
+		if !owned {
+			logger.Info("Function já existe e não pertence à política", "Function.Name", preview.FunctionName)
+			preview.Ready, preview.URL = false, ""
+			preview.Reason = "FunctionConflict"
+			preview.Message = fmt.Sprintf("A Function %s já existe no namespace %s e não pertence a esta política",
+				preview.FunctionName, policy.Namespace)
+			continue
+		}
+		preview.Reason, preview.Message = "", ""
+		preview.Ready = meta.IsStatusConditionTrue(function.Status.Conditions, "Ready")
+		preview.URL = function.Status.URL
+
+		// Publicar a URL no pull request uma vez por commit, quando o preview fica pronto
+		if preview.Ready && preview.URL != "" && token != "" && preview.PublishedSHA != preview.HeadSHA {
+			if err := provider.publishPreview(ctx, repository, preview.HeadSHA, preview.URL); err != nil {
+				logger.Error(err, "Falha ao publicar a URL do preview", "PullRequest", preview.Number)
+			} else {
+				logger.Info("URL do preview publicada", "PullRequest", preview.Number, "URL", preview.URL)
+				preview.PublishedSHA = preview.HeadSHA
+			}
+		}
+	}
+
+	// Remover os previews de pull requests fechados ou expirados
+	functions := &functionsv1alpha1.FunctionList{}
+	if err := r.List(ctx, functions, client.InNamespace(policy.Namespace),
+		client.MatchingLabels{PreviewPolicyLabel: policy.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range functions.Items {
+		function := &functions.Items[i]
+		if active[function.Name] || !metav1.IsControlledBy(function, policy) {
+			continue
+		}
+		logger.Info("Removendo Function de preview", "Function.Name", function.Name, "PullRequest", function.Labels[PullRequestLabel])
+		if err := r.Delete(ctx, function); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	policy.Status.Previews = previews
+	meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  "Synced",
+		Message: fmt.Sprintf("%d preview(s) ativo(s) para %s", len(active), repository),
+	})
+	policy.Status.ObservedGeneration = policy.Generation
+	if err := r.Status().Update(ctx, policy); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: previewRequeueAfter(policy, previews, now)}, nil
+}
+
+/*
+planPreviews escolhe os pull requests com preview (filtrados por 'baseBranch', sem forks não autorizados
+por 'forkLabel', e limitados a 'maxPreviews', dos atualizados mais recentemente) e calcula o estado de
+cada preview a partir do anterior:
+um novo commit reinicia o TTL (e recria um preview expirado).
+*/
+func planPreviews(policy *functionsv1alpha1.PreviewPolicy, parent *functionsv1alpha1.Function,
+	pullRequests []pullRequest, now time.Time) []functionsv1alpha1.PreviewStatus {
+	var candidates []pullRequest
+	for _, pr := range pullRequests {
+		if policy.Spec.BaseBranch != "" && pr.BaseRef != policy.Spec.BaseBranch {
+			continue
+		}
+		if pr.Fork && (policy.Spec.ForkLabel == "" || !slices.Contains(pr.Labels, policy.Spec.ForkLabel)) {
+			continue
+		}
+		candidates = append(candidates, pr)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].UpdatedAt.Equal(candidates[j].UpdatedAt) {
+			return candidates[i].UpdatedAt.After(candidates[j].UpdatedAt)
+		}
+		return candidates[i].Number > candidates[j].Number
+	})
+	maxPreviews := int(policy.Spec.MaxPreviews)
+	if maxPreviews <= 0 {
+		maxPreviews = defaultMaxPreviews
+	}
+	if len(candidates) > maxPreviews {
+		candidates = candidates[:maxPreviews]
+	}
+
+	previous := map[int64]functionsv1alpha1.PreviewStatus{}
+	for _, preview := range policy.Status.Previews {
+		previous[preview.Number] = preview
+	}
+
+	previews := make([]functionsv1alpha1.PreviewStatus, 0, len(candidates))
+	for _, pr := range candidates {
+		preview, found := previous[pr.Number]
+		if !found || preview.HeadSHA != pr.HeadSHA {
+			preview = functionsv1alpha1.PreviewStatus{
+				Number:     pr.Number,
+				HeadSHA:    pr.HeadSHA,
+				DeployedAt: metav1.Time{Time: now},
+			}
+		}
+		preview.HeadRef = pr.HeadRef
+		preview.FunctionName = previewFunctionName(parent.Name, pr.Number)
+		preview.Expired = previewExpired(policy, preview, now)
+		previews = append(previews, preview)
+	}
+	sort.Slice(previews, func(i, j int) bool { return previews[i].Number < previews[j].Number })
+	return previews
+}
+
+// previewFunctionName returns "<parent>-pr-<number>", shortening the parent name to fit maxPreviewFunctionNameLength.
+func previewFunctionName(parent string, number int64) string {
+	suffix := "-pr-" + strconv.FormatInt(number, 10)
+	if len(parent)+len(suffix) > maxPreviewFunctionNameLength {
+		parent = strings.TrimRight(parent[:maxPreviewFunctionNameLength-len(suffix)], "-")
+	}
+	return parent + suffix
+}
+
+// previewImage returns the image repository of the parent tagged for the pull request ("<repository>:pr-<number>"),
+// so that preview builds do not overwrite the tag of the parent Function.
+func previewImage(image string, number int64) string {
+	repository, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+	return fmt.Sprintf("%s:pr-%d", repository, number)
+}
+
+/*
+buildPreviewFunction clona o spec da Function de referência para o commit do pull request.
+As configurações que só fazem sentido para a Function original não são copiadas: domínios,
+divisão de tráfego, tags, rollout, imagem fixada e eventing (o preview não consome eventos de produção).
+O app-id do Dapr recebe o nome do preview, para não colidir com o da Function original.
+O código do pull request também não recebe as credenciais da Function original (ver stripPreviewCredentials).
+*/
+func buildPreviewFunction(policy *functionsv1alpha1.PreviewPolicy, parent *functionsv1alpha1.Function,
+	preview functionsv1alpha1.PreviewStatus) *functionsv1alpha1.Function {
+	spec := parent.Spec.DeepCopy()
+	spec.GitRevision = preview.HeadSHA
+	spec.Build.Image = previewImage(parent.Spec.Build.Image, preview.Number)
+	spec.Deploy.Domains = nil
+	spec.Deploy.Traffic = nil
+	spec.Deploy.Tags = nil
+	spec.Deploy.Rollout = nil
+	spec.Deploy.PinnedImage = ""
+	spec.Eventing = functionsv1alpha1.EventingSpec{}
+	if spec.Deploy.Dapr.Enabled {
+		spec.Deploy.Dapr.AppID = preview.FunctionName
+	}
+	stripPreviewCredentials(spec)
+
+	return &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      preview.FunctionName,
+			Namespace: policy.Namespace,
+			Labels: map[string]string{
+				"functions.zenith.com/managed-by": "zenith-operator",
+				PreviewPolicyLabel:                policy.Name,
+				PullRequestLabel:                  strconv.FormatInt(preview.Number, 10),
+			},
+		},
+		Spec: *spec,
+	}
+}
+
+/*
+stripPreviewCredentials remove do spec de um preview o acesso às credenciais da Function original:
+variáveis e envFrom vindos de Secrets, volumes com Secrets (e as montagens deles nos sidecars e
+init containers), as permissões na API do Kubernetes e as annotations de identidade de workload.
+*/
+func stripPreviewCredentials(spec *functionsv1alpha1.FunctionSpec) {
+	deploy := &spec.Deploy
+	deploy.Permissions = nil
+	deploy.ServiceAccount = nil
+
+	withoutSecretEnv := func(env []v1.EnvVar, envFrom []v1.EnvFromSource) ([]v1.EnvVar, []v1.EnvFromSource) {
+		env = slices.DeleteFunc(env, func(envVar v1.EnvVar) bool {
+			return envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil
+		})
+		envFrom = slices.DeleteFunc(envFrom, func(source v1.EnvFromSource) bool { return source.SecretRef != nil })
+		return env, envFrom
+	}
+	deploy.Env, deploy.EnvFrom = withoutSecretEnv(deploy.Env, deploy.EnvFrom)
+
+	removed := map[string]bool{}
+	deploy.Volumes = slices.DeleteFunc(deploy.Volumes, func(volume functionsv1alpha1.FunctionVolume) bool {
+		secret := volume.Secret != nil
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				secret = secret || source.Secret != nil
+			}
+		}
+		removed[volume.Name] = secret
+		return secret
+	})
+
+	for _, containers := range [][]functionsv1alpha1.FunctionContainer{deploy.Sidecars, deploy.InitContainers} {
+		for i := range containers {
+			containers[i].Env, containers[i].EnvFrom = withoutSecretEnv(containers[i].Env, containers[i].EnvFrom)
+			containers[i].VolumeMounts = slices.DeleteFunc(containers[i].VolumeMounts, func(mount functionsv1alpha1.ContainerVolumeMount) bool {
+				return removed[mount.Name]
+			})
+		}
+	}
+}
+
+/*
+reconcilePreviewFunction cria ou atualiza a Function de um preview e a retorna.
+Uma Function de mesmo nome que não pertence à política não é alterada: retorna false.
+*/
+func (r *PreviewPolicyReconciler) reconcilePreviewFunction(ctx context.Context, policy *functionsv1alpha1.PreviewPolicy,
+	parent *functionsv1alpha1.Function, preview *functionsv1alpha1.PreviewStatus) (*functionsv1alpha1.Function, bool, error) {
+	logger := logf.FromContext(ctx)
+
+	desired := buildPreviewFunction(policy, parent, *preview)
+	existing := &functionsv1alpha1.Function{}
+	err := r.Get(ctx, types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, existing)
+	if err == nil && !metav1.IsControlledBy(existing, policy) {
+		return nil, false, nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return nil, false, err
+	}
+
+	function := &functionsv1alpha1.Function{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, function, func() error {
+		if function.Labels == nil {
+			function.Labels = map[string]string{}
+		}
+		for key, value := range desired.Labels {
+			function.Labels[key] = value
+		}
+		function.Spec = desired.Spec
+		return controllerutil.SetControllerReference(policy, function, r.Scheme)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("Function de preview sincronizada", "Function.Name", function.Name, "PullRequest", preview.Number,
+			"HeadSHA", preview.HeadSHA, "Operation", op)
+	}
+	return function, true, nil
+}
+
+// previewPollInterval returns how often the Git provider of a policy is queried.
+func previewPollInterval(policy *functionsv1alpha1.PreviewPolicy) time.Duration {
+	if interval := policy.Spec.PollInterval; interval != nil && interval.Duration > 0 {
+		return interval.Duration
+	}
+	return defaultPreviewPollInterval
+}
+
+// previewPollDue reports whether the Git provider must be queried: on the first sync, after a spec change
+// or once pollInterval has passed since the last successful query.
+func previewPollDue(policy *functionsv1alpha1.PreviewPolicy, now time.Time) bool {
+	lastSync := policy.Status.LastSyncTime
+	return lastSync == nil || policy.Status.ObservedGeneration != policy.Generation ||
+		!now.Before(lastSync.Add(previewPollInterval(policy)))
+}
+
+// previewExpired reports whether the current commit of a preview has been up for longer than the TTL.
+func previewExpired(policy *functionsv1alpha1.PreviewPolicy, preview functionsv1alpha1.PreviewStatus, now time.Time) bool {
+	ttl := policy.Spec.TTL
+	return ttl != nil && ttl.Duration > 0 && !now.Before(preview.DeployedAt.Add(ttl.Duration))
+}
+
+// previewRequeueAfter returns the time until the next poll, or the next TTL expiry if it comes first.
+func previewRequeueAfter(policy *functionsv1alpha1.PreviewPolicy, previews []functionsv1alpha1.PreviewStatus, now time.Time) time.Duration {
+	requeueAfter := previewPollInterval(policy)
+	if lastSync := policy.Status.LastSyncTime; lastSync != nil {
+		requeueAfter = lastSync.Add(requeueAfter).Sub(now)
+	}
+	if ttl := policy.Spec.TTL; ttl != nil && ttl.Duration > 0 {
+		for _, preview := range previews {
+			if remaining := preview.DeployedAt.Add(ttl.Duration).Sub(now); !preview.Expired && remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+		}
+	}
+	if requeueAfter < time.Second {
+		requeueAfter = time.Second
+	}
+	return requeueAfter
+}
+
+// setPolicyCondition reports a problem in the Ready condition of the policy and requeues after the given delay.
+func (r *PreviewPolicyReconciler) setPolicyCondition(ctx context.Context, policy *functionsv1alpha1.PreviewPolicy,
+	reason, message string, requeueAfter time.Duration) (ctrl.Result, error) {
+	meta.SetStatusCondition(&policy.Status.Conditions, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	policy.Status.ObservedGeneration = policy.Generation
+	if err := r.Status().Update(ctx, policy); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+func (r *PreviewPolicyReconciler) gitProvider(policy *functionsv1alpha1.PreviewPolicy, token string) gitProvider {
+	if r.newGitProvider != nil {
+		return r.newGitProvider(policy, token)
+	}
+	return newGitHubClient(policy.Spec.APIURL, token)
+}
+
+func (r *PreviewPolicyReconciler) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// policiesForFunction enqueues the PreviewPolicies that clone a Function, so that spec changes reach the previews.
+func (r *PreviewPolicyReconciler) policiesForFunction(ctx context.Context, obj client.Object) []reconcile.Request {
+	policies := &functionsv1alpha1.PreviewPolicyList{}
+	if err := r.List(ctx, policies, client.InNamespace(obj.GetNamespace())); err != nil {
+		logf.FromContext(ctx).Error(err, "Falha ao listar PreviewPolicies")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, policy := range policies.Items {
+		if policy.Spec.FunctionRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: policy.Name, Namespace: policy.Namespace},
+			})
+		}
+	}
+	return requests
+}
+
+/*
+SetupWithManager sets up the controller with the Manager.
+Updates to the status of the policy (and of the source Function) do not trigger a reconciliation; changes
+to the preview Functions do, but only poll the provider once pollInterval has passed.
+*/
+func (r *PreviewPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&functionsv1alpha1.PreviewPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&functionsv1alpha1.Function{}).
+		Watches(&functionsv1alpha1.Function{}, handler.EnqueueRequestsFromMapFunc(r.policiesForFunction),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named("previewpolicy").
+		Complete(r)
+}
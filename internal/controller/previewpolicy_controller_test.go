@@ -0,0 +1,437 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// fakeGitProvider serves a fixed list of pull requests and records the published previews.
+type fakeGitProvider struct {
+	pullRequests []pullRequest
+	err          error
+	published    map[string]string // sha -> preview URL
+	polls        int
+}
+
+func (p *fakeGitProvider) openPullRequests(_ context.Context, _ string) ([]pullRequest, error) {
+	p.polls++
+	return p.pullRequests, p.err
+}
+
+func (p *fakeGitProvider) publishPreview(_ context.Context, _, sha, previewURL string) error {
+	p.published[sha] = previewURL
+	return nil
+}
+
+func sha(n int) string {
+	return fmt.Sprintf("%040x", n)
+}
+
+func TestPlanPreviews(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	parent := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			GitRepo:     "https://github.com/acme/api",
+			GitRevision: "main",
+			Build:       functionsv1alpha1.BuildSpec{Image: "registry.example.com/acme/api:latest"},
+			Deploy: functionsv1alpha1.DeploySpec{
+				Visibility: functionsv1alpha1.VisibilityExternal,
+				Domains:    []functionsv1alpha1.FunctionDomain{{Name: "api.example.com"}},
+				Tags:       []functionsv1alpha1.RevisionTag{{Name: "stable", LatestRevision: true}},
+				Dapr:       functionsv1alpha1.DaprConfig{Enabled: true, AppID: "api"},
+			},
+			Eventing: functionsv1alpha1.EventingSpec{Broker: "default"},
+		},
+	}
+	policy := &functionsv1alpha1.PreviewPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-previews", Namespace: "default", UID: "policy-uid"},
+		Spec: functionsv1alpha1.PreviewPolicySpec{
+			FunctionRef:    corev1.LocalObjectReference{Name: "api"},
+			TokenSecretRef: &corev1.LocalObjectReference{Name: "github-token"},
+			BaseBranch:     "main",
+			TTL:            &metav1.Duration{Duration: 24 * time.Hour},
+			MaxPreviews:    2,
+		},
+	}
+	pullRequests := []pullRequest{
+		{Number: 1, HeadSHA: sha(1), BaseRef: "main", UpdatedAt: now.Add(-3 * time.Hour)},
+		{Number: 2, HeadSHA: sha(2), BaseRef: "main", UpdatedAt: now.Add(-1 * time.Hour)},
+		{Number: 3, HeadSHA: sha(3), BaseRef: "release", UpdatedAt: now},
+		{Number: 4, HeadSHA: sha(4), BaseRef: "main", UpdatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	previews := planPreviews(policy, parent, pullRequests, now)
+	g.Expect(previews).To(HaveLen(2))
+	g.Expect(previews[0]).To(And(HaveField("Number", int64(2)), HaveField("FunctionName", "api-pr-2")))
+	g.Expect(previews[1]).To(And(HaveField("Number", int64(4)), HaveField("FunctionName", "api-pr-4")))
+	g.Expect(previews[0].DeployedAt.Time).To(Equal(now))
+
+	// O mesmo commit mantém o estado anterior; um novo commit reinicia o TTL
+	policy.Status.Previews = []functionsv1alpha1.PreviewStatus{
+		{Number: 2, HeadSHA: sha(2), DeployedAt: metav1.Time{Time: now.Add(-25 * time.Hour)}, PublishedSHA: sha(2)},
+		{Number: 4, HeadSHA: sha(40), DeployedAt: metav1.Time{Time: now.Add(-25 * time.Hour)}, PublishedSHA: sha(40)},
+	}
+	previews = planPreviews(policy, parent, pullRequests, now)
+	g.Expect(previews[0].Expired).To(BeTrue())
+	g.Expect(previews[0].PublishedSHA).To(Equal(sha(2)))
+	g.Expect(previews[1].Expired).To(BeFalse())
+	g.Expect(previews[1].HeadSHA).To(Equal(sha(4)))
+	g.Expect(previews[1].PublishedSHA).To(BeEmpty())
+	g.Expect(previews[1].DeployedAt.Time).To(Equal(now))
+
+	t.Run("forks need the fork label", func(t *testing.T) {
+		g := NewWithT(t)
+		policy := policy.DeepCopy()
+		policy.Spec.MaxPreviews = 10
+		policy.Status = functionsv1alpha1.PreviewPolicyStatus{}
+		pullRequests := []pullRequest{
+			{Number: 5, HeadSHA: sha(5), BaseRef: "main", UpdatedAt: now, Fork: true},
+			{Number: 6, HeadSHA: sha(6), BaseRef: "main", UpdatedAt: now, Fork: true, Labels: []string{"safe-to-preview"}},
+		}
+		g.Expect(planPreviews(policy, parent, pullRequests, now)).To(BeEmpty())
+
+		policy.Spec.ForkLabel = "safe-to-preview"
+		previews := planPreviews(policy, parent, pullRequests, now)
+		g.Expect(previews).To(HaveLen(1))
+		g.Expect(previews[0].Number).To(Equal(int64(6)))
+	})
+}
+
+func TestPreviewNames(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(previewFunctionName("api", 42)).To(Equal("api-pr-42"))
+	long := previewFunctionName("a-very-long-function-name-that-goes-on-and-on-and-on", 1234)
+	g.Expect(long).To(Equal("a-very-long-function-name-that-goes-on-and-pr-1234"))
+	g.Expect(len(long)).To(BeNumerically("<=", maxPreviewFunctionNameLength))
+
+	g.Expect(previewImage("registry.example.com/acme/api:latest", 7)).To(Equal("registry.example.com/acme/api:pr-7"))
+	g.Expect(previewImage("registry.example.com:5000/api", 7)).To(Equal("registry.example.com:5000/api:pr-7"))
+	g.Expect(previewImage("registry.example.com/api:v1@"+digestA, 7)).To(Equal("registry.example.com/api:pr-7"))
+}
+
+func TestBuildPreviewFunction(t *testing.T) {
+	g := NewWithT(t)
+	parent := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			GitRepo:     "https://github.com/acme/api",
+			GitRevision: "main",
+			Build:       functionsv1alpha1.BuildSpec{Image: "registry.example.com/acme/api:latest"},
+			Deploy: functionsv1alpha1.DeploySpec{
+				Visibility: functionsv1alpha1.VisibilityExternal,
+				Domains:    []functionsv1alpha1.FunctionDomain{{Name: "api.example.com"}},
+				Tags:       []functionsv1alpha1.RevisionTag{{Name: "stable", LatestRevision: true}},
+				Dapr:       functionsv1alpha1.DaprConfig{Enabled: true, AppID: "api"},
+			},
+			Eventing: functionsv1alpha1.EventingSpec{Broker: "default"},
+		},
+	}
+	policy := &functionsv1alpha1.PreviewPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-previews", Namespace: "default", UID: "policy-uid"},
+		Spec: functionsv1alpha1.PreviewPolicySpec{
+			FunctionRef:    corev1.LocalObjectReference{Name: "api"},
+			TokenSecretRef: &corev1.LocalObjectReference{Name: "github-token"},
+			BaseBranch:     "main",
+			TTL:            &metav1.Duration{Duration: 24 * time.Hour},
+			MaxPreviews:    10,
+		},
+	}
+	preview := functionsv1alpha1.PreviewStatus{Number: 7, HeadSHA: sha(7), FunctionName: "api-pr-7"}
+
+	function := buildPreviewFunction(policy, parent, preview)
+	g.Expect(function.Name).To(Equal("api-pr-7"))
+	g.Expect(function.Labels).To(And(
+		HaveKeyWithValue(PreviewPolicyLabel, "api-previews"),
+		HaveKeyWithValue(PullRequestLabel, "7"),
+	))
+	g.Expect(function.Spec.GitRepo).To(Equal("https://github.com/acme/api"))
+	g.Expect(function.Spec.GitRevision).To(Equal(sha(7)))
+	g.Expect(function.Spec.Build.Image).To(Equal("registry.example.com/acme/api:pr-7"))
+	g.Expect(function.Spec.Deploy.Visibility).To(Equal(functionsv1alpha1.VisibilityExternal))
+	g.Expect(function.Spec.Deploy.Domains).To(BeEmpty())
+	g.Expect(function.Spec.Deploy.Tags).To(BeEmpty())
+	g.Expect(function.Spec.Deploy.Dapr.AppID).To(Equal("api-pr-7"))
+	g.Expect(function.Spec.Eventing).To(Equal(functionsv1alpha1.EventingSpec{}))
+
+	t.Run("credentials of the parent are not copied", func(t *testing.T) {
+		g := NewWithT(t)
+		parent := parent.DeepCopy()
+		secretKey := &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "api-secrets"}, Key: "db-password",
+		}}
+		parent.Spec.Deploy.Env = []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "DB_PASSWORD", ValueFrom: secretKey}}
+		parent.Spec.Deploy.EnvFrom = []corev1.EnvFromSource{
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "api-secrets"}}},
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "api-config"}}},
+		}
+		parent.Spec.Deploy.Volumes = []functionsv1alpha1.FunctionVolume{
+			{Name: "tls", MountPath: "/tls", Secret: &corev1.SecretVolumeSource{SecretName: "api-tls"}},
+			{Name: "cache", MountPath: "/cache", EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		}
+		parent.Spec.Deploy.Sidecars = []functionsv1alpha1.FunctionContainer{{
+			Name: "proxy", Image: "envoyproxy/envoy:v1.30",
+			Env:          []corev1.EnvVar{{Name: "DB_PASSWORD", ValueFrom: secretKey}},
+			VolumeMounts: []functionsv1alpha1.ContainerVolumeMount{{Name: "tls", MountPath: "/tls"}, {Name: "cache", MountPath: "/cache"}},
+		}}
+		parent.Spec.Deploy.Permissions = []functionsv1alpha1.PermissionRule{{Resources: []string{"configmaps"}, Verbs: []string{"get"}}}
+		parent.Spec.Deploy.ServiceAccount = &functionsv1alpha1.RuntimeServiceAccountSpec{
+			Annotations: map[string]string{"iam.gke.io/gcp-service-account": "api@acme.iam.gserviceaccount.com"},
+		}
+
+		deploy := buildPreviewFunction(policy, parent, preview).Spec.Deploy
+		g.Expect(deploy.Env).To(Equal([]corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}}))
+		g.Expect(deploy.EnvFrom).To(HaveLen(1))
+		g.Expect(deploy.EnvFrom[0].ConfigMapRef).NotTo(BeNil())
+		g.Expect(deploy.Volumes).To(HaveLen(1))
+		g.Expect(deploy.Volumes[0].Name).To(Equal("cache"))
+		g.Expect(deploy.Sidecars[0].Env).To(BeEmpty())
+		g.Expect(deploy.Sidecars[0].VolumeMounts).To(Equal([]functionsv1alpha1.ContainerVolumeMount{{Name: "cache", MountPath: "/cache"}}))
+		g.Expect(deploy.Permissions).To(BeNil())
+		g.Expect(deploy.ServiceAccount).To(BeNil())
+
+		// O spec da Function original não é alterado
+		g.Expect(parent.Spec.Deploy.Env).To(HaveLen(2))
+		g.Expect(parent.Spec.Deploy.Sidecars[0].VolumeMounts).To(HaveLen(2))
+	})
+}
+
+func TestPreviewPolicyReconcile(t *testing.T) {
+	ctx := context.Background()
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Name: "api-previews", Namespace: "default"}}
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	parent := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			GitRepo:     "https://github.com/acme/api",
+			GitRevision: "main",
+			Build:       functionsv1alpha1.BuildSpec{Image: "registry.example.com/acme/api:latest"},
+			Deploy: functionsv1alpha1.DeploySpec{
+				Visibility: functionsv1alpha1.VisibilityExternal,
+				Domains:    []functionsv1alpha1.FunctionDomain{{Name: "api.example.com"}},
+				Tags:       []functionsv1alpha1.RevisionTag{{Name: "stable", LatestRevision: true}},
+				Dapr:       functionsv1alpha1.DaprConfig{Enabled: true, AppID: "api"},
+			},
+			Eventing: functionsv1alpha1.EventingSpec{Broker: "default"},
+		},
+	}
+	policy := &functionsv1alpha1.PreviewPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-previews", Namespace: "default", UID: "policy-uid"},
+		Spec: functionsv1alpha1.PreviewPolicySpec{
+			FunctionRef:    corev1.LocalObjectReference{Name: "api"},
+			TokenSecretRef: &corev1.LocalObjectReference{Name: "github-token"},
+			BaseBranch:     "main",
+			TTL:            &metav1.Duration{Duration: 24 * time.Hour},
+			MaxPreviews:    10,
+		},
+	}
+	token := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+
+	newReconciler := func(provider *fakeGitProvider, now *time.Time, objects ...client.Object) *PreviewPolicyReconciler {
+		functionReconciler := newFakeReconciler([]func(*runtime.Scheme) error{corev1.AddToScheme},
+			append(objects, policy.DeepCopy(), token.DeepCopy())...)
+		return &PreviewPolicyReconciler{
+			Client:         functionReconciler.Client,
+			Scheme:         functionReconciler.Scheme,
+			newGitProvider: func(*functionsv1alpha1.PreviewPolicy, string) gitProvider { return provider },
+			now:            func() time.Time { return *now },
+		}
+	}
+	getPolicy := func(g *WithT, r *PreviewPolicyReconciler) *functionsv1alpha1.PreviewPolicy {
+		policy := &functionsv1alpha1.PreviewPolicy{}
+		g.Expect(r.Get(ctx, request.NamespacedName, policy)).To(Succeed())
+		return policy
+	}
+	previewFunctions := func(g *WithT, r *PreviewPolicyReconciler) []string {
+		functions := &functionsv1alpha1.FunctionList{}
+		g.Expect(r.List(ctx, functions, client.MatchingLabels{PreviewPolicyLabel: "api-previews"})).To(Succeed())
+		var names []string
+		for _, function := range functions.Items {
+			names = append(names, function.Name)
+		}
+		return names
+	}
+
+	t.Run("keeps a preview per open pull request", func(t *testing.T) {
+		g := NewWithT(t)
+		now := start
+		provider := &fakeGitProvider{published: map[string]string{}, pullRequests: []pullRequest{
+			{Number: 1, HeadSHA: sha(1), BaseRef: "main", UpdatedAt: start},
+			{Number: 2, HeadSHA: sha(2), BaseRef: "main", UpdatedAt: start},
+		}}
+		r := newReconciler(provider, &now, parent.DeepCopy())
+
+		result, err := r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(defaultPreviewPollInterval))
+		g.Expect(previewFunctions(g, r)).To(ConsistOf("api-pr-1", "api-pr-2"))
+
+		policy := getPolicy(g, r)
+		g.Expect(meta.IsStatusConditionTrue(policy.Status.Conditions, "Ready")).To(BeTrue())
+		g.Expect(policy.Status.Previews).To(HaveLen(2))
+		g.Expect(policy.Status.LastSyncTime.Time).To(BeTemporally("==", start))
+
+		function := &functionsv1alpha1.Function{}
+		g.Expect(r.Get(ctx, types.NamespacedName{Name: "api-pr-1", Namespace: "default"}, function)).To(Succeed())
+		g.Expect(metav1.IsControlledBy(function, policy)).To(BeTrue())
+		g.Expect(function.Spec.GitRevision).To(Equal(sha(1)))
+
+		// Um novo commit atualiza a revisão; o pull request fechado perde o preview
+		provider.pullRequests = []pullRequest{{Number: 1, HeadSHA: sha(10), BaseRef: "main", UpdatedAt: start}}
+		now = start.Add(defaultPreviewPollInterval)
+		_, err = r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(previewFunctions(g, r)).To(ConsistOf("api-pr-1"))
+		g.Expect(r.Get(ctx, types.NamespacedName{Name: "api-pr-1", Namespace: "default"}, function)).To(Succeed())
+		g.Expect(function.Spec.GitRevision).To(Equal(sha(10)))
+	})
+
+	t.Run("publishes the URL once the preview is ready", func(t *testing.T) {
+		g := NewWithT(t)
+		now := start
+		provider := &fakeGitProvider{published: map[string]string{}, pullRequests: []pullRequest{
+			{Number: 1, HeadSHA: sha(1), BaseRef: "main", UpdatedAt: start},
+		}}
+		r := newReconciler(provider, &now, parent.DeepCopy())
+
+		_, err := r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(provider.published).To(BeEmpty())
+
+		function := &functionsv1alpha1.Function{}
+		g.Expect(r.Get(ctx, types.NamespacedName{Name: "api-pr-1", Namespace: "default"}, function)).To(Succeed())
+		function.Status.URL = "http://api-pr-1.default.example.com"
+		meta.SetStatusCondition(&function.Status.Conditions, metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Ready"})
+		g.Expect(r.Status().Update(ctx, function)).To(Succeed())
+
+		// A mudança de estado do preview não consulta o provedor antes de 'pollInterval'
+		now = start.Add(10 * time.Second)
+		result, err := r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(provider.polls).To(Equal(1))
+		g.Expect(result.RequeueAfter).To(Equal(defaultPreviewPollInterval - 10*time.Second))
+		g.Expect(provider.published).To(Equal(map[string]string{sha(1): "http://api-pr-1.default.example.com"}))
+
+		policy := getPolicy(g, r)
+		g.Expect(policy.Status.Previews[0]).To(And(
+			HaveField("Ready", true),
+			HaveField("URL", "http://api-pr-1.default.example.com"),
+			HaveField("PublishedSHA", sha(1)),
+		))
+	})
+
+	t.Run("expired previews are removed until a new commit", func(t *testing.T) {
+		g := NewWithT(t)
+		now := start
+		provider := &fakeGitProvider{published: map[string]string{}, pullRequests: []pullRequest{
+			{Number: 1, HeadSHA: sha(1), BaseRef: "main", UpdatedAt: start},
+		}}
+		r := newReconciler(provider, &now, parent.DeepCopy())
+
+		_, err := r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		now = start.Add(23 * time.Hour)
+		result, err := r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(defaultPreviewPollInterval))
+		g.Expect(previewFunctions(g, r)).To(ConsistOf("api-pr-1"))
+
+		now = start.Add(24 * time.Hour)
+		_, err = r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(previewFunctions(g, r)).To(BeEmpty())
+		g.Expect(getPolicy(g, r).Status.Previews[0].Expired).To(BeTrue())
+
+		provider.pullRequests[0].HeadSHA = sha(2)
+		now = now.Add(defaultPreviewPollInterval)
+		_, err = r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(previewFunctions(g, r)).To(ConsistOf("api-pr-1"))
+		g.Expect(getPolicy(g, r).Status.Previews[0].Expired).To(BeFalse())
+	})
+
+	t.Run("Functions owned by someone else are left alone", func(t *testing.T) {
+		g := NewWithT(t)
+		now := start
+		provider := &fakeGitProvider{published: map[string]string{}, pullRequests: []pullRequest{
+			{Number: 1, HeadSHA: sha(1), BaseRef: "main", UpdatedAt: start},
+		}}
+		foreign := &functionsv1alpha1.Function{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-pr-1", Namespace: "default"},
+			Spec:       functionsv1alpha1.FunctionSpec{GitRepo: "https://github.com/acme/other", GitRevision: "main"},
+		}
+		r := newReconciler(provider, &now, parent.DeepCopy(), foreign)
+
+		_, err := r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(getPolicy(g, r).Status.Previews[0]).To(And(HaveField("Reason", "FunctionConflict"), HaveField("Ready", false)))
+
+		function := &functionsv1alpha1.Function{}
+		g.Expect(r.Get(ctx, types.NamespacedName{Name: "api-pr-1", Namespace: "default"}, function)).To(Succeed())
+		g.Expect(function.Spec.GitRepo).To(Equal("https://github.com/acme/other"))
+		g.Expect(function.OwnerReferences).To(BeEmpty())
+	})
+
+	t.Run("reports provider errors", func(t *testing.T) {
+		g := NewWithT(t)
+		now := start
+		provider := &fakeGitProvider{err: errors.New("GET /repos/acme/api/pulls: 401 Unauthorized")}
+		r := newReconciler(provider, &now, parent.DeepCopy())
+
+		result, err := r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(defaultPreviewPollInterval))
+
+		condition := meta.FindStatusCondition(getPolicy(g, r).Status.Conditions, "Ready")
+		g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		g.Expect(condition.Reason).To(Equal("ProviderUnavailable"))
+	})
+
+	t.Run("rejects Functions without a Git source", func(t *testing.T) {
+		g := NewWithT(t)
+		now := start
+		parent := parent.DeepCopy()
+		parent.Spec.GitRepo = ""
+		r := newReconciler(&fakeGitProvider{}, &now, parent)
+
+		_, err := r.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(meta.FindStatusCondition(getPolicy(g, r).Status.Conditions, "Ready").Reason).To(Equal("InvalidFunction"))
+	})
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// slowStartBuildpacks are buildpack ID fragments of runtimes that take long to start (JVM, .NET)
+var slowStartBuildpacks = []string{"java", "jvm", "spring-boot", "liberica", "dotnet"}
+
+// Default startup probe for slow-starting runtimes: up to 3 minutes to open the function port
+const (
+	slowStartProbePeriodSeconds    = 2
+	slowStartProbeFailureThreshold = 90
+)
+
+// Kubernetes defaults applied by Knative to readiness probes with a period, mirrored to keep the drift check stable
+const (
+	defaultProbePeriodSeconds    = 10
+	defaultProbeTimeoutSeconds   = 1
+	defaultProbeFailureThreshold = 3
+)
+
+/*
+effectiveProbes combina 'spec.deploy.probes' com os padrões do runtime detectado no build
+(status.runtime): runtimes que iniciam devagar, como a JVM, ganham uma startup probe TCP.
+*/
+func effectiveProbes(function *functionsv1alpha1.Function) functionsv1alpha1.ProbesSpec {
+	var probes functionsv1alpha1.ProbesSpec
+	if function.Spec.Deploy.Probes != nil {
+		probes = *function.Spec.Deploy.Probes
+	}
+	if probes.Startup == nil && isSlowStartRuntime(function.Status.Runtime) {
+		probes.Startup = &functionsv1alpha1.ProbeSpec{
+			Type:             functionsv1alpha1.ProbeTypeTCP,
+			PeriodSeconds:    slowStartProbePeriodSeconds,
+			FailureThreshold: slowStartProbeFailureThreshold,
+		}
+	}
+	return probes
+}
+
+// isSlowStartRuntime reports whether a detected buildpack belongs to a slow-starting runtime.
+func isSlowStartRuntime(runtime *functionsv1alpha1.RuntimeStatus) bool {
+	if runtime == nil {
+		return false
+	}
+	for _, buildpack := range runtime.Buildpacks {
+		id := strings.ToLower(buildpack.ID)
+		for _, fragment := range slowStartBuildpacks {
+			if strings.Contains(id, fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyProbes sets the readiness, liveness and startup probes of the function container.
+func applyProbes(container *v1.Container, function *functionsv1alpha1.Function) {
+	probes := effectiveProbes(function)
+	container.ReadinessProbe = readinessProbe(probes.Readiness, container.Ports[0].ContainerPort)
+	container.LivenessProbe = containerProbe(probes.Liveness, container.Ports[0].ContainerPort)
+	container.StartupProbe = containerProbe(probes.Startup, container.Ports[0].ContainerPort)
+}
+
+/*
+readinessProbe traduz a readiness probe, já com os valores que o Knative aplicaria por padrão.
+Sem probe, reproduz o padrão do Knative (TCP na porta da função) de forma explícita,
+para que a comparação com o Service no cluster seja estável.
+*/
+func readinessProbe(spec *functionsv1alpha1.ProbeSpec, port int32) *v1.Probe {
+	if spec == nil {
+		return &v1.Probe{
+			ProbeHandler:     v1.ProbeHandler{TCPSocket: &v1.TCPSocketAction{}},
+			SuccessThreshold: 1,
+		}
+	}
+
+	probe := containerProbe(spec, port)
+	probe.SuccessThreshold = 1
+	// A verificação agressiva do Knative (periodSeconds 0) não aceita timeout nem limite de falhas
+	if probe.PeriodSeconds == 0 && (probe.TimeoutSeconds != 0 || probe.FailureThreshold != 0) {
+		probe.PeriodSeconds = defaultProbePeriodSeconds
+	}
+	if probe.PeriodSeconds != 0 {
+		if probe.TimeoutSeconds == 0 {
+			probe.TimeoutSeconds = defaultProbeTimeoutSeconds
+		}
+		if probe.FailureThreshold == 0 {
+			probe.FailureThreshold = defaultProbeFailureThreshold
+		}
+	}
+	return probe
+}
+
+// containerProbe translates a ProbeSpec into a container probe. HTTP and TCP probes leave the port
+// empty, as Knative requires them to target the function port.
+func containerProbe(spec *functionsv1alpha1.ProbeSpec, port int32) *v1.Probe {
+	if spec == nil {
+		return nil
+	}
+
+	probe := &v1.Probe{
+		InitialDelaySeconds: spec.InitialDelaySeconds,
+		PeriodSeconds:       spec.PeriodSeconds,
+		TimeoutSeconds:      spec.TimeoutSeconds,
+		FailureThreshold:    spec.FailureThreshold,
+	}
+	switch spec.Type {
+	case functionsv1alpha1.ProbeTypeHTTP:
+		path := spec.Path
+		if path == "" {
+			path = "/"
+		}
+		probe.HTTPGet = &v1.HTTPGetAction{Path: path}
+	case functionsv1alpha1.ProbeTypeTCP:
+		probe.TCPSocket = &v1.TCPSocketAction{}
+	case functionsv1alpha1.ProbeTypeGRPC:
+		service := spec.Service
+		probe.GRPC = &v1.GRPCAction{Port: port, Service: &service}
+	}
+	return probe
+}
+
+// probesChanged reports whether the probes of any container differ from the desired ones.
+func probesChanged(current, desired *knservingv1.Service) bool {
+	pairs, ok := containerPairs(current, desired)
+	if !ok {
+		return true
+	}
+	for _, pair := range pairs {
+		currentContainer, desiredContainer := pair[0], pair[1]
+		if !equality.Semantic.DeepEqual(currentContainer.ReadinessProbe, desiredContainer.ReadinessProbe) ||
+			!equality.Semantic.DeepEqual(currentContainer.LivenessProbe, desiredContainer.LivenessProbe) ||
+			!equality.Semantic.DeepEqual(currentContainer.StartupProbe, desiredContainer.StartupProbe) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestBuildKnativeServiceProbes(t *testing.T) {
+	r := &FunctionReconciler{}
+
+	t.Run("defaults match Knative", func(t *testing.T) {
+		g := NewWithT(t)
+		container := r.buildKnativeService(&functionsv1alpha1.Function{}).Spec.Template.Spec.Containers[0]
+		g.Expect(container.ReadinessProbe).To(Equal(&v1.Probe{
+			ProbeHandler:     v1.ProbeHandler{TCPSocket: &v1.TCPSocketAction{}},
+			SuccessThreshold: 1,
+		}))
+		g.Expect(container.LivenessProbe).To(BeNil())
+		g.Expect(container.StartupProbe).To(BeNil())
+	})
+
+	t.Run("configured probes", func(t *testing.T) {
+		g := NewWithT(t)
+		function := &functionsv1alpha1.Function{
+			Spec: functionsv1alpha1.FunctionSpec{
+				Deploy: functionsv1alpha1.DeploySpec{
+					Probes: &functionsv1alpha1.ProbesSpec{
+						Readiness: &functionsv1alpha1.ProbeSpec{Type: functionsv1alpha1.ProbeTypeHTTP, Path: "/ready", PeriodSeconds: 5},
+						Liveness:  &functionsv1alpha1.ProbeSpec{Type: functionsv1alpha1.ProbeTypeGRPC, FailureThreshold: 6},
+						Startup:   &functionsv1alpha1.ProbeSpec{Type: functionsv1alpha1.ProbeTypeTCP, InitialDelaySeconds: 10},
+					},
+				},
+			},
+		}
+		container := r.buildKnativeService(function).Spec.Template.Spec.Containers[0]
+		g.Expect(container.ReadinessProbe).To(Equal(&v1.Probe{
+			ProbeHandler:     v1.ProbeHandler{HTTPGet: &v1.HTTPGetAction{Path: "/ready"}},
+			PeriodSeconds:    5,
+			TimeoutSeconds:   1,
+			FailureThreshold: 3,
+			SuccessThreshold: 1,
+		}))
+		g.Expect(container.LivenessProbe).To(Equal(&v1.Probe{
+			ProbeHandler:     v1.ProbeHandler{GRPC: &v1.GRPCAction{Port: 8080, Service: stringPtr("")}},
+			FailureThreshold: 6,
+		}))
+		g.Expect(container.StartupProbe).To(Equal(&v1.Probe{
+			ProbeHandler:        v1.ProbeHandler{TCPSocket: &v1.TCPSocketAction{}},
+			InitialDelaySeconds: 10,
+		}))
+	})
+
+	t.Run("readiness thresholds without period leave the aggressive probe", func(t *testing.T) {
+		g := NewWithT(t)
+		probe := readinessProbe(&functionsv1alpha1.ProbeSpec{Type: functionsv1alpha1.ProbeTypeTCP, FailureThreshold: 10}, 8080)
+		g.Expect(probe.PeriodSeconds).To(Equal(int32(10)))
+		g.Expect(probe.TimeoutSeconds).To(Equal(int32(1)))
+		g.Expect(probe.FailureThreshold).To(Equal(int32(10)))
+	})
+
+	t.Run("slow-starting runtimes get a startup probe", func(t *testing.T) {
+		g := NewWithT(t)
+		function := &functionsv1alpha1.Function{
+			Status: functionsv1alpha1.FunctionStatus{
+				Runtime: &functionsv1alpha1.RuntimeStatus{Buildpacks: []functionsv1alpha1.DetectedBuildpack{
+					{ID: "paketo-buildpacks/bellsoft-liberica"},
+					{ID: "paketo-buildpacks/spring-boot"},
+				}},
+			},
+		}
+		container := r.buildKnativeService(function).Spec.Template.Spec.Containers[0]
+		g.Expect(container.StartupProbe).To(Equal(&v1.Probe{
+			ProbeHandler:     v1.ProbeHandler{TCPSocket: &v1.TCPSocketAction{}},
+			PeriodSeconds:    2,
+			FailureThreshold: 90,
+		}))
+
+		// An explicit startup probe wins over the runtime default
+		function.Spec.Deploy.Probes = &functionsv1alpha1.ProbesSpec{
+			Startup: &functionsv1alpha1.ProbeSpec{Type: functionsv1alpha1.ProbeTypeHTTP, Path: "/actuator/health"},
+		}
+		container = r.buildKnativeService(function).Spec.Template.Spec.Containers[0]
+		g.Expect(container.StartupProbe.HTTPGet.Path).To(Equal("/actuator/health"))
+	})
+}
+
+func TestProbesChanged(t *testing.T) {
+	r := &FunctionReconciler{}
+	function := &functionsv1alpha1.Function{
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Probes: &functionsv1alpha1.ProbesSpec{
+					Liveness: &functionsv1alpha1.ProbeSpec{Type: functionsv1alpha1.ProbeTypeHTTP, Path: "/healthz"},
+				},
+			},
+		},
+	}
+	desired := r.buildKnativeService(function)
+
+	g := NewWithT(t)
+	g.Expect(probesChanged(desired.DeepCopy(), desired)).To(BeFalse())
+
+	current := desired.DeepCopy()
+	current.Spec.Template.Spec.Containers[0].LivenessProbe = nil
+	g.Expect(probesChanged(current, desired)).To(BeTrue())
+}
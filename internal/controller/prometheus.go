@@ -0,0 +1,125 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// prometheusClient runs instant queries against a Prometheus-compatible HTTP API
+// (Prometheus, Thanos, Mimir, VictoriaMetrics...).
+type prometheusClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func newPrometheusClient(baseURL string) *prometheusClient {
+	return &prometheusClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// prometheusQueryResponse is the subset of the /api/v1/query response used by the controller.
+type prometheusQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+/*
+query executa uma consulta instantânea e retorna o seu valor.
+Aceita resultados escalares ou vetores com uma única série; 'found' é falso quando o vetor está vazio.
+*/
+func (c *prometheusClient) query(ctx context.Context, promQL string) (value float64, found bool, err error) {
+	endpoint := c.baseURL + "/api/v1/query?" + url.Values{"query": {promQL}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, false, err
+	}
+	var result prometheusQueryResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, false, fmt.Errorf("resposta inválida do Prometheus (%s): %w", resp.Status, err)
+	}
+	if result.Status != "success" {
+		return 0, false, fmt.Errorf("consulta falhou (%s): %s", resp.Status, result.Error)
+	}
+
+	var sample []any
+	switch result.Data.ResultType {
+	case "scalar":
+		if err := json.Unmarshal(result.Data.Result, &sample); err != nil {
+			return 0, false, err
+		}
+	case "vector":
+		var series []struct {
+			Value []any `json:"value"`
+		}
+		if err := json.Unmarshal(result.Data.Result, &series); err != nil {
+			return 0, false, err
+		}
+		if len(series) == 0 {
+			return 0, false, nil
+		}
+		if len(series) > 1 {
+			return 0, false, fmt.Errorf("a consulta retornou %d séries, esperado 1", len(series))
+		}
+		sample = series[0].Value
+	default:
+		return 0, false, fmt.Errorf("tipo de resultado não suportado: %q", result.Data.ResultType)
+	}
+
+	// Amostras são pares [timestamp, "valor"]
+	if len(sample) != 2 {
+		return 0, false, fmt.Errorf("amostra inválida: %v", sample)
+	}
+	raw, ok := sample[1].(string)
+	if !ok {
+		return 0, false, fmt.Errorf("amostra inválida: %v", sample)
+	}
+	value, err = strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("amostra inválida %q: %w", raw, err)
+	}
+	// Razões sem tráfego (0/0) resultam em NaN: tratar como ausência de dados
+	if math.IsNaN(value) {
+		return 0, false, nil
+	}
+	return value, true, nil
+}
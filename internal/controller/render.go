@@ -0,0 +1,220 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kneventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	knservingv1beta1 "knative.dev/serving/pkg/apis/serving/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// RenderOptions configures an offline render of the resources generated for a Function.
+type RenderOptions struct {
+	// ImageDigest is the digest ("sha256:...") used in place of a real build result.
+	ImageDigest string
+	// Namespace is used for Functions without metadata.namespace.
+	Namespace string
+	// PermissionAllowlist is the operator policy for spec.deploy.permissions; nil uses DefaultPermissionAllowlist.
+	PermissionAllowlist PermissionAllowlist
+}
+
+// DefaultRenderImageDigest is the placeholder digest used when RenderOptions.ImageDigest is empty.
+const DefaultRenderImageDigest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+/*
+RenderFunction gera, sem acessar o cluster, os recursos que o operator criaria para a Function:
+Tasks do Tekton, ConfigMap do código-fonte inline, ServiceAccount de runtime (e o Role e RoleBinding de 'permissions'),
+PipelineRun, Knative Service, DomainMappings e (com eventing) Trigger.
+Usa os mesmos builders da reconciliação. Dependências do cluster não são resolvidas:
+os passos de build padrão do namespace, o Pipeline de 'pipelineRef', referências a Secrets/ConfigMaps
+e alvos de tráfego e tags por digest de imagem.
+*/
+func RenderFunction(function *functionsv1alpha1.Function, opts RenderOptions) ([]client.Object, error) {
+	function = function.DeepCopy()
+	if function.Namespace == "" {
+		function.Namespace = opts.Namespace
+	}
+	if function.Namespace == "" {
+		function.Namespace = "default"
+	}
+	if function.Name == "" {
+		return nil, fmt.Errorf("metadata.name é obrigatório")
+	}
+	if function.Spec.Build.Image == "" {
+		return nil, fmt.Errorf("function %s: spec.build.image é obrigatório", function.Name)
+	}
+	if err := validateBuildSteps(function); err != nil {
+		return nil, fmt.Errorf("function %s: %w", function.Name, err)
+	}
+	if err := validateTraffic(function); err != nil {
+		return nil, fmt.Errorf("function %s: %w", function.Name, err)
+	}
+	if err := validateRollout(function); err != nil {
+		return nil, fmt.Errorf("function %s: %w", function.Name, err)
+	}
+	if err := validateScale(function); err != nil {
+		return nil, fmt.Errorf("function %s: %w", function.Name, err)
+	}
+	if err := validatePort(function); err != nil {
+		return nil, fmt.Errorf("function %s: %w", function.Name, err)
+	}
+	if err := validateDomains(function); err != nil {
+		return nil, fmt.Errorf("function %s: %w", function.Name, err)
+	}
+	if err := validateContainers(function); err != nil {
+		return nil, fmt.Errorf("function %s: %w", function.Name, err)
+	}
+	allowlist := opts.PermissionAllowlist
+	if allowlist == nil {
+		allowlist = DefaultPermissionAllowlist()
+	}
+	if err := validatePermissions(function, allowlist); err != nil {
+		return nil, fmt.Errorf("function %s: %w", function.Name, err)
+	}
+	if source := function.Spec.Source; source != nil && source.OCI != nil && !ociDigestPattern.MatchString(source.OCI.Image) {
+		return nil, fmt.Errorf("function %s: spec.source.oci.image: a referência %q precisa ser fixada por digest (@sha256:...)", function.Name, source.OCI.Image)
+	}
+	for _, target := range function.Spec.Deploy.Traffic {
+		if target.ImageDigest != "" && target.RevisionName == "" {
+			return nil, fmt.Errorf("function %s: o alvo de tráfego %s depende das revisões no cluster; use revisionName", function.Name, target.ImageDigest)
+		}
+	}
+	for _, tag := range function.Spec.Deploy.Tags {
+		if tag.ImageDigest != "" && tag.RevisionName == "" {
+			return nil, fmt.Errorf("function %s: a tag %s depende das revisões no cluster; use revisionName", function.Name, tag.Name)
+		}
+	}
+
+	digest := opts.ImageDigest
+	if digest == "" {
+		digest = DefaultRenderImageDigest
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil, fmt.Errorf("digest de imagem inválido %q: esperado 'sha256:<hex>'", digest)
+	}
+	function.Status.ImageDigest = function.Spec.Build.Image + "@" + digest
+
+	r := &FunctionReconciler{}
+	var objects []client.Object
+
+	// Tasks instaladas pelo operator no namespace (apenas para o pipeline embutido)
+	if function.Spec.Build.PipelineRef == nil {
+		if function.Spec.Source == nil {
+			objects = append(objects, withTypeMeta(r.buildGitCloneTask(function.Namespace), tektonv1.SchemeGroupVersion.String(), "Task"))
+		} else {
+			objects = append(objects, withTypeMeta(r.buildSourceFetchTask(function.Namespace), tektonv1.SchemeGroupVersion.String(), "Task"))
+		}
+		objects = append(objects, withTypeMeta(r.buildBuildpacksPhasesTask(function.Namespace), tektonv1.SchemeGroupVersion.String(), "Task"))
+	}
+
+	if source := function.Spec.Source; source != nil && source.Inline != nil && source.Inline.Files != nil {
+		data, _ := inlineSourceItems(source.Inline.Files)
+		objects = append(objects, withTypeMeta(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      inlineSourceConfigMapName(function),
+				Namespace: function.Namespace,
+				Labels:    map[string]string{"functions.zenith.com/managed-by": "zenith-operator"},
+			},
+			Data: data,
+		}, "v1", "ConfigMap"))
+	}
+
+	if configMap := buildVolumeFilesConfigMap(function); configMap != nil {
+		objects = append(objects, withTypeMeta(configMap, "v1", "ConfigMap"))
+	}
+
+	objects = append(objects, withTypeMeta(buildRuntimeServiceAccount(function), "v1", "ServiceAccount"))
+	if role := buildRuntimeRole(function); role != nil {
+		objects = append(objects,
+			withTypeMeta(role, rbacv1.SchemeGroupVersion.String(), "Role"),
+			withTypeMeta(buildRuntimeRoleBinding(function), rbacv1.SchemeGroupVersion.String(), "RoleBinding"),
+		)
+	}
+
+	objects = append(objects,
+		withTypeMeta(r.buildPipelineRun(function), tektonv1.SchemeGroupVersion.String(), "PipelineRun"),
+		withTypeMeta(r.buildKnativeService(function), knservingv1.SchemeGroupVersion.String(), "Service"),
+	)
+	for _, domain := range function.Spec.Deploy.Domains {
+		objects = append(objects, withTypeMeta(buildDomainMapping(function, domain), knservingv1beta1.SchemeGroupVersion.String(), "DomainMapping"))
+	}
+	if function.Spec.Eventing.Broker != "" {
+		objects = append(objects, withTypeMeta(r.buildKnativeTrigger(function), kneventingv1.SchemeGroupVersion.String(), "Trigger"))
+	}
+	return objects, nil
+}
+
+// withTypeMeta sets apiVersion and kind, which builders leave empty for typed clients.
+func withTypeMeta(obj client.Object, apiVersion, kind string) client.Object {
+	typeMeta := metav1.TypeMeta{APIVersion: apiVersion, Kind: kind}
+	obj.GetObjectKind().SetGroupVersionKind(typeMeta.GroupVersionKind())
+	return obj
+}
+
+// RenderYAML serializes objects as a multi-document YAML stream, omitting status and empty creation timestamps.
+func RenderYAML(objects []client.Object) ([]byte, error) {
+	var out bytes.Buffer
+	for i, obj := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		delete(content, "status")
+		pruneNullTimestamps(content)
+
+		document, err := yaml.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			out.WriteString("---\n")
+		}
+		out.Write(document)
+	}
+	return out.Bytes(), nil
+}
+
+// pruneNullTimestamps removes the "creationTimestamp: null" entries left by empty ObjectMeta values,
+// including the ones in embedded templates.
+func pruneNullTimestamps(value any) {
+	switch typed := value.(type) {
+	case map[string]any:
+		if timestamp, ok := typed["creationTimestamp"]; ok && timestamp == nil {
+			delete(typed, "creationTimestamp")
+		}
+		for _, nested := range typed {
+			pruneNullTimestamps(nested)
+		}
+	case []any:
+		for _, nested := range typed {
+			pruneNullTimestamps(nested)
+		}
+	}
+}
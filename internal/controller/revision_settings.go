@@ -0,0 +1,126 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	knconfig "knative.dev/serving/pkg/apis/config"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+)
+
+/*
+knativeDefaults lê os padrões de revisão do Knative Serving (ConfigMap config-defaults).
+Sem o ConfigMap, valem os padrões do Knative.
+*/
+func (r *FunctionReconciler) knativeDefaults(ctx context.Context) (*knconfig.Defaults, error) {
+	namespace := os.Getenv(knativeServingNamespaceEnv)
+	if namespace == "" {
+		namespace = defaultKnativeServingNamespace
+	}
+
+	configMap := &v1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: knconfig.DefaultsConfigName, Namespace: namespace}, configMap)
+	if errors.IsNotFound(err) {
+		return knconfig.NewDefaultsConfigFromMap(nil)
+	} else if err != nil {
+		return nil, err
+	}
+	return knconfig.NewDefaultsConfigFromConfigMap(configMap)
+}
+
+/*
+withKnativeDefaults retorna uma cópia do Knative Service desejado com os padrões que o webhook
+do Knative aplica à revisão (timeouts, concorrência, recursos e, com 'secure-pod-defaults',
+o securityContext dos containers). Comparar o Service do cluster com essa cópia detecta tanto
+valores alterados quanto campos removidos da Function, sem atualizações em loop.
+As feature flags são as já lidas pela reconciliação para validar a Function.
+*/
+func (r *FunctionReconciler) withKnativeDefaults(ctx context.Context, desired *knservingv1.Service, features *knconfig.Features) (*knservingv1.Service, error) {
+	defaults, err := r.knativeDefaults(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return applyKnativeDefaults(ctx, desired, defaults, features), nil
+}
+
+// applyKnativeDefaults runs the Knative revision defaulting on a copy of the desired Service.
+func applyKnativeDefaults(ctx context.Context, desired *knservingv1.Service, defaults *knconfig.Defaults, features *knconfig.Features) *knservingv1.Service {
+	defaulted := desired.DeepCopy()
+	ctx = knconfig.ToContext(ctx, &knconfig.Config{Defaults: defaults, Features: features})
+	defaulted.Spec.Template.Spec.SetDefaults(ctx)
+	return defaulted
+}
+
+/*
+revisionSettingsChanged compara as portas e os recursos dos containers, a concorrência e os timeouts
+do Knative Service no cluster com os desejados.
+O Service desejado deve vir de withKnativeDefaults: os campos omitidos na Function valem os padrões
+do Knative (ex: timeoutSeconds=300), e um campo removido da Function volta ao padrão.
+*/
+func revisionSettingsChanged(current, desired *knservingv1.Service) bool {
+	currentSpec, desiredSpec := current.Spec.Template.Spec, desired.Spec.Template.Spec
+	if int64FieldChanged(currentSpec.ContainerConcurrency, desiredSpec.ContainerConcurrency) ||
+		int64FieldChanged(currentSpec.TimeoutSeconds, desiredSpec.TimeoutSeconds) ||
+		int64FieldChanged(currentSpec.ResponseStartTimeoutSeconds, desiredSpec.ResponseStartTimeoutSeconds) ||
+		int64FieldChanged(currentSpec.IdleTimeoutSeconds, desiredSpec.IdleTimeoutSeconds) {
+		return true
+	}
+
+	pairs, ok := containerPairs(current, desired)
+	if !ok {
+		return true
+	}
+	for _, pair := range pairs {
+		if !equality.Semantic.DeepEqual(pair[0].Ports, pair[1].Ports) {
+			return true
+		}
+		currentResources, desiredResources := pair[0].Resources, pair[1].Resources
+		if resourceListChanged(currentResources.Requests, desiredResources.Requests) ||
+			resourceListChanged(currentResources.Limits, desiredResources.Limits) {
+			return true
+		}
+	}
+	return false
+}
+
+// int64FieldChanged reports whether an optional value was set, removed or changed.
+func int64FieldChanged(current, desired *int64) bool {
+	if current == nil || desired == nil {
+		return current != desired
+	}
+	return *current != *desired
+}
+
+// resourceListChanged reports whether a quantity was added, removed or changed.
+func resourceListChanged(current, desired v1.ResourceList) bool {
+	if len(current) != len(desired) {
+		return true
+	}
+	for name, quantity := range desired {
+		currentQuantity, found := current[name]
+		if !found || currentQuantity.Cmp(quantity) != 0 {
+			return true
+		}
+	}
+	return false
+}
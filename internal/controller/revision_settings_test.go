@@ -0,0 +1,118 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	knconfig "knative.dev/serving/pkg/apis/config"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestBuildKnativeServiceRevisionSettings(t *testing.T) {
+	g := NewWithT(t)
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "reports", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Resources: &v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("512Mi")},
+					Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi")},
+				},
+				ContainerConcurrency:        int64Ptr(4),
+				TimeoutSeconds:              int64Ptr(600),
+				ResponseStartTimeoutSeconds: int64Ptr(120),
+				IdleTimeoutSeconds:          int64Ptr(60),
+			},
+		},
+	}
+
+	ksvc := (&FunctionReconciler{}).buildKnativeService(function)
+	spec := ksvc.Spec.Template.Spec
+	g.Expect(spec.ContainerConcurrency).To(Equal(int64Ptr(4)))
+	g.Expect(spec.TimeoutSeconds).To(Equal(int64Ptr(600)))
+	g.Expect(spec.ResponseStartTimeoutSeconds).To(Equal(int64Ptr(120)))
+	g.Expect(spec.IdleTimeoutSeconds).To(Equal(int64Ptr(60)))
+	g.Expect(spec.Containers[0].Resources.Limits.Memory().String()).To(Equal("2Gi"))
+
+	t.Run("unset fields keep the Knative defaults", func(t *testing.T) {
+		g := NewWithT(t)
+		ksvc := (&FunctionReconciler{}).buildKnativeService(&functionsv1alpha1.Function{})
+		g.Expect(ksvc.Spec.Template.Spec.ContainerConcurrency).To(BeNil())
+		g.Expect(ksvc.Spec.Template.Spec.TimeoutSeconds).To(BeNil())
+		g.Expect(ksvc.Spec.Template.Spec.Containers[0].Resources).To(Equal(v1.ResourceRequirements{}))
+	})
+}
+
+func TestRevisionSettingsChanged(t *testing.T) {
+	ctx := context.Background()
+	defaults, err := knconfig.NewDefaultsConfigFromMap(map[string]string{"revision-cpu-request": "100m"})
+	NewWithT(t).Expect(err).NotTo(HaveOccurred())
+	features := knativeFeaturesFrom(t, nil)
+
+	function := &functionsv1alpha1.Function{
+		Spec: functionsv1alpha1.FunctionSpec{
+			Deploy: functionsv1alpha1.DeploySpec{
+				Resources: &v1.ResourceRequirements{
+					Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+				},
+				ContainerConcurrency: int64Ptr(10),
+				TimeoutSeconds:       int64Ptr(600),
+			},
+		},
+	}
+	r := &FunctionReconciler{}
+	// current is the Service as stored in the cluster, after the Knative webhook
+	current := applyKnativeDefaults(ctx, r.buildKnativeService(function), defaults, features)
+
+	t.Run("in sync with Knative defaults applied", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(current.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String()).To(Equal("100m"))
+		desired := applyKnativeDefaults(ctx, r.buildKnativeService(function), defaults, features)
+		// Same quantity, different notation
+		desired.Spec.Template.Spec.Containers[0].Resources.Limits = v1.ResourceList{v1.ResourceMemory: resource.MustParse("1024Mi")}
+		g.Expect(revisionSettingsChanged(current, desired)).To(BeFalse())
+	})
+
+	tests := []struct {
+		name   string
+		update func(deploy *functionsv1alpha1.DeploySpec)
+	}{
+		{name: "timeout changed", update: func(deploy *functionsv1alpha1.DeploySpec) { deploy.TimeoutSeconds = int64Ptr(300) }},
+		{name: "timeout removed", update: func(deploy *functionsv1alpha1.DeploySpec) { deploy.TimeoutSeconds = nil }},
+		{name: "concurrency removed", update: func(deploy *functionsv1alpha1.DeploySpec) { deploy.ContainerConcurrency = nil }},
+		{name: "memory limit changed", update: func(deploy *functionsv1alpha1.DeploySpec) {
+			deploy.Resources.Limits = v1.ResourceList{v1.ResourceMemory: resource.MustParse("512Mi")}
+		}},
+		{name: "memory limit removed", update: func(deploy *functionsv1alpha1.DeploySpec) { deploy.Resources = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			changed := function.DeepCopy()
+			tt.update(&changed.Spec.Deploy)
+			desired := applyKnativeDefaults(ctx, r.buildKnativeService(changed), defaults, features)
+			g.Expect(revisionSettingsChanged(current, desired)).To(BeTrue())
+		})
+	}
+}
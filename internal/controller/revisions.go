@@ -0,0 +1,145 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+const (
+	// revisionHistoryLimit bounds the entries kept in status.revisions
+	revisionHistoryLimit = 10
+
+	// fetchSourcePipelineTaskName is the pipeline task that fetches the source; its "commit" result identifies it
+	fetchSourcePipelineTaskName = "fetch-source"
+)
+
+/*
+deployedImage retorna a referência da imagem que a função deve servir:
+a imagem fixada em 'spec.deploy.pinnedImage' ou, sem fixação, a do último build.
+*/
+func deployedImage(function *functionsv1alpha1.Function) string {
+	pinned := function.Spec.Deploy.PinnedImage
+	if pinned == "" {
+		return function.Status.ImageDigest
+	}
+	if strings.Contains(pinned, "@") {
+		return pinned
+	}
+	// Apenas o digest: usar a imagem registrada no histórico (o repositório pode ter mudado desde então)
+	for _, entry := range function.Status.Revisions {
+		if imageDigestOf(entry.ImageDigest) == pinned {
+			return entry.ImageDigest
+		}
+	}
+	return function.Spec.Build.Image + "@" + pinned
+}
+
+/*
+recordRevision registra a revisão pronta no início do histórico, limitado a revisionHistoryLimit entradas.
+Uma nova revisão com a mesma imagem da entrada mais recente (ex: só o env mudou) substitui essa entrada.
+*/
+func recordRevision(history []functionsv1alpha1.RevisionHistoryEntry, entry functionsv1alpha1.RevisionHistoryEntry) []functionsv1alpha1.RevisionHistoryEntry {
+	if len(history) > 0 {
+		latest := history[0]
+		if latest.RevisionName == entry.RevisionName && latest.ImageDigest == entry.ImageDigest {
+			return history
+		}
+		if latest.ImageDigest == entry.ImageDigest {
+			if entry.Commit == "" {
+				entry.Commit = latest.Commit
+			}
+			history = history[1:]
+		}
+	}
+
+	updated := append([]functionsv1alpha1.RevisionHistoryEntry{entry}, history...)
+	if len(updated) > revisionHistoryLimit {
+		updated = updated[:revisionHistoryLimit]
+	}
+	return updated
+}
+
+/*
+updateRevisionHistory acrescenta ao 'status.revisions' a revisão Knative pronta que serve a imagem atual.
+O commit vem do último build; para uma imagem fixada, do histórico da própria imagem.
+Durante um rollout a revisão nova só recebe o tráfego do canário, e depois de um rollback nenhum:
+ela só entra no histórico quando o rollout é concluído.
+*/
+func updateRevisionHistory(function *functionsv1alpha1.Function, ksvc *knservingv1.Service, now metav1.Time) {
+	revisionName := ksvc.Status.LatestReadyRevisionName
+	if revisionName == "" || revisionName != ksvc.Status.LatestCreatedRevisionName {
+		return
+	}
+	if status := function.Status.Rollout; status != nil && status.Phase != rolloutSucceeded && function.Spec.Deploy.PinnedImage == "" {
+		return
+	}
+
+	image := deployedImage(function)
+	entry := functionsv1alpha1.RevisionHistoryEntry{
+		ImageDigest:  image,
+		RevisionName: revisionName,
+		DeployedAt:   &now,
+	}
+	if function.Spec.Deploy.PinnedImage != "" {
+		for _, previous := range function.Status.Revisions {
+			if previous.ImageDigest == image {
+				entry.Commit = previous.Commit
+				break
+			}
+		}
+	} else if function.Status.Build != nil {
+		entry.Commit = function.Status.Build.SourceRevision
+	}
+	function.Status.Revisions = recordRevision(function.Status.Revisions, entry)
+}
+
+/*
+buildSourceRevision identifica a fonte construída por um PipelineRun: o resultado 'commit'
+da task 'fetch-source' ou, na sua falta, a revisão registrada na anotação do PipelineRun.
+*/
+func (r *FunctionReconciler) buildSourceRevision(ctx context.Context, pipelineRun *tektonv1.PipelineRun) string {
+	for _, childRef := range pipelineRun.Status.ChildReferences {
+		if childRef.Kind != "TaskRun" || childRef.PipelineTaskName != fetchSourcePipelineTaskName {
+			continue
+		}
+
+		taskRun := &tektonv1.TaskRun{}
+		if err := r.Get(ctx, types.NamespacedName{Name: childRef.Name, Namespace: pipelineRun.Namespace}, taskRun); err != nil {
+			logf.FromContext(ctx).V(1).Info("TaskRun de fetch-source não encontrado para extrair o commit", "TaskRun.Name", childRef.Name, "error", err.Error())
+			break
+		}
+		for _, result := range taskRun.Status.Results {
+			if result.Name == "commit" {
+				if commit := strings.TrimSpace(result.Value.StringVal); commit != "" {
+					return commit
+				}
+			}
+		}
+		break
+	}
+	return pipelineRun.Annotations[SourceRevisionAnnotation]
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestDeployedImage(t *testing.T) {
+	function := &functionsv1alpha1.Function{
+		Spec: functionsv1alpha1.FunctionSpec{
+			Build: functionsv1alpha1.BuildSpec{Image: "registry.io/fn"},
+		},
+		Status: functionsv1alpha1.FunctionStatus{
+			ImageDigest: "registry.io/fn@" + digestB,
+			Revisions: []functionsv1alpha1.RevisionHistoryEntry{
+				{ImageDigest: "registry.io/fn@" + digestB, RevisionName: "fn-00002"},
+				{ImageDigest: "old-registry.io/fn@" + digestA, RevisionName: "fn-00001"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		pinned string
+		want   string
+	}{
+		{name: "not pinned serves the last build", want: "registry.io/fn@" + digestB},
+		{name: "full reference", pinned: "other.io/fn@" + digestA, want: "other.io/fn@" + digestA},
+		{name: "digest found in history", pinned: digestA, want: "old-registry.io/fn@" + digestA},
+		{
+			name:   "digest not in history",
+			pinned: "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc",
+			want:   "registry.io/fn@sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			pinned := function.DeepCopy()
+			pinned.Spec.Deploy.PinnedImage = tt.pinned
+			g.Expect(deployedImage(pinned)).To(Equal(tt.want))
+		})
+	}
+
+	t.Run("applied to the Knative Service", func(t *testing.T) {
+		g := NewWithT(t)
+		pinned := function.DeepCopy()
+		pinned.Spec.Deploy.PinnedImage = digestA
+		ksvc := (&FunctionReconciler{}).buildKnativeService(pinned)
+		g.Expect(ksvc.Spec.Template.Spec.Containers[0].Image).To(Equal("old-registry.io/fn@" + digestA))
+	})
+}
+
+func TestRecordRevision(t *testing.T) {
+	entry := func(image, revision, commit string) functionsv1alpha1.RevisionHistoryEntry {
+		return functionsv1alpha1.RevisionHistoryEntry{ImageDigest: image, RevisionName: revision, Commit: commit}
+	}
+
+	t.Run("new image is prepended", func(t *testing.T) {
+		g := NewWithT(t)
+		history := recordRevision([]functionsv1alpha1.RevisionHistoryEntry{entry("fn@"+digestA, "fn-00001", "abc")},
+			entry("fn@"+digestB, "fn-00002", "def"))
+		g.Expect(history).To(Equal([]functionsv1alpha1.RevisionHistoryEntry{
+			entry("fn@"+digestB, "fn-00002", "def"),
+			entry("fn@"+digestA, "fn-00001", "abc"),
+		}))
+	})
+
+	t.Run("same revision is a no-op", func(t *testing.T) {
+		g := NewWithT(t)
+		history := []functionsv1alpha1.RevisionHistoryEntry{entry("fn@"+digestA, "fn-00001", "abc")}
+		g.Expect(recordRevision(history, entry("fn@"+digestA, "fn-00001", "abc"))).To(Equal(history))
+	})
+
+	t.Run("new revision of the same image replaces the latest entry", func(t *testing.T) {
+		g := NewWithT(t)
+		history := recordRevision([]functionsv1alpha1.RevisionHistoryEntry{entry("fn@"+digestA, "fn-00001", "abc")},
+			entry("fn@"+digestA, "fn-00002", ""))
+		g.Expect(history).To(Equal([]functionsv1alpha1.RevisionHistoryEntry{entry("fn@"+digestA, "fn-00002", "abc")}))
+	})
+
+	t.Run("history is bounded", func(t *testing.T) {
+		g := NewWithT(t)
+		var history []functionsv1alpha1.RevisionHistoryEntry
+		for i := 1; i <= revisionHistoryLimit+2; i++ {
+			history = recordRevision(history, entry(fmt.Sprintf("fn@sha256:%064d", i), fmt.Sprintf("fn-%05d", i), ""))
+		}
+		g.Expect(history).To(HaveLen(revisionHistoryLimit))
+		g.Expect(history[0].RevisionName).To(Equal(fmt.Sprintf("fn-%05d", revisionHistoryLimit+2)))
+		g.Expect(history[revisionHistoryLimit-1].RevisionName).To(Equal("fn-00003"))
+	})
+}
+
+func TestUpdateRevisionHistory(t *testing.T) {
+	now := metav1.NewTime(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	readyService := func(latestReady, latestCreated string) *knservingv1.Service {
+		ksvc := &knservingv1.Service{}
+		ksvc.Status.LatestReadyRevisionName = latestReady
+		ksvc.Status.LatestCreatedRevisionName = latestCreated
+		return ksvc
+	}
+
+	t.Run("records the built commit", func(t *testing.T) {
+		g := NewWithT(t)
+		function := &functionsv1alpha1.Function{Status: functionsv1alpha1.FunctionStatus{
+			ImageDigest: "fn@" + digestA,
+			Build:       &functionsv1alpha1.BuildStatus{SourceRevision: "abc123"},
+		}}
+		updateRevisionHistory(function, readyService("fn-00001", "fn-00001"), now)
+		g.Expect(function.Status.Revisions).To(Equal([]functionsv1alpha1.RevisionHistoryEntry{
+			{ImageDigest: "fn@" + digestA, RevisionName: "fn-00001", Commit: "abc123", DeployedAt: &now},
+		}))
+	})
+
+	t.Run("waits for the latest revision to be ready", func(t *testing.T) {
+		g := NewWithT(t)
+		function := &functionsv1alpha1.Function{Status: functionsv1alpha1.FunctionStatus{ImageDigest: "fn@" + digestB}}
+		updateRevisionHistory(function, readyService("fn-00001", "fn-00002"), now)
+		g.Expect(function.Status.Revisions).To(BeEmpty())
+	})
+
+	t.Run("skips canary and rolled back revisions", func(t *testing.T) {
+		g := NewWithT(t)
+		for _, phase := range []string{rolloutProgressing, rolloutRolledBack} {
+			function := &functionsv1alpha1.Function{Status: functionsv1alpha1.FunctionStatus{
+				ImageDigest: "fn@" + digestB,
+				Rollout:     &functionsv1alpha1.RolloutStatus{Phase: phase, StableRevision: "fn-00001", CanaryRevision: "fn-00002"},
+			}}
+			updateRevisionHistory(function, readyService("fn-00002", "fn-00002"), now)
+			g.Expect(function.Status.Revisions).To(BeEmpty(), phase)
+		}
+	})
+
+	t.Run("records the revision once the rollout succeeds", func(t *testing.T) {
+		g := NewWithT(t)
+		function := &functionsv1alpha1.Function{Status: functionsv1alpha1.FunctionStatus{
+			ImageDigest: "fn@" + digestB,
+			Rollout:     &functionsv1alpha1.RolloutStatus{Phase: rolloutSucceeded, StableRevision: "fn-00002"},
+		}}
+		updateRevisionHistory(function, readyService("fn-00002", "fn-00002"), now)
+		g.Expect(function.Status.Revisions).To(HaveLen(1))
+		g.Expect(function.Status.Revisions[0].RevisionName).To(Equal("fn-00002"))
+	})
+
+	t.Run("pinned image keeps the commit from history", func(t *testing.T) {
+		g := NewWithT(t)
+		function := &functionsv1alpha1.Function{
+			Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{PinnedImage: digestA}},
+			Status: functionsv1alpha1.FunctionStatus{
+				ImageDigest: "fn@" + digestB,
+				Build:       &functionsv1alpha1.BuildStatus{SourceRevision: "def456"},
+				Revisions: []functionsv1alpha1.RevisionHistoryEntry{
+					{ImageDigest: "fn@" + digestB, RevisionName: "fn-00002", Commit: "def456"},
+					{ImageDigest: "fn@" + digestA, RevisionName: "fn-00001", Commit: "abc123"},
+				},
+			},
+		}
+		updateRevisionHistory(function, readyService("fn-00003", "fn-00003"), now)
+		g.Expect(function.Status.Revisions).To(HaveLen(3))
+		g.Expect(function.Status.Revisions[0]).To(Equal(functionsv1alpha1.RevisionHistoryEntry{
+			ImageDigest: "fn@" + digestA, RevisionName: "fn-00003", Commit: "abc123", DeployedAt: &now,
+		}))
+	})
+}
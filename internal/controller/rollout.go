@@ -0,0 +1,381 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// Rollout phases reported in status.rollout.phase
+const (
+	rolloutProgressing = "Progressing"
+	rolloutSucceeded   = "Succeeded"
+	rolloutRolledBack  = "RolledBack"
+)
+
+const (
+	// canaryTag exposes the new revision of a rollout on its own URL
+	canaryTag = "canary"
+	// defaultRolloutPause is the duration of a rollout step without an explicit pause
+	defaultRolloutPause = time.Minute
+	// rolloutPollInterval is how often a rollout waiting for its canary revision is checked
+	rolloutPollInterval = 10 * time.Second
+	// rolloutAnalysisRetryInterval is the delay before retrying an analysis that could not be evaluated
+	rolloutAnalysisRetryInterval = 30 * time.Second
+)
+
+// rolloutAnalyzer evaluates the success criteria of a rollout step for the canary revision.
+// It returns the per-metric results and a description of each failed criterion.
+type rolloutAnalyzer func(ctx context.Context, function *functionsv1alpha1.Function, canaryRevision string) ([]functionsv1alpha1.RolloutMetricResult, []string, error)
+
+// rolloutObservation is the state of the Knative Service a rollout decision is based on.
+type rolloutObservation struct {
+	// currentImage is the image of the Service template in the cluster, desiredImage the one of the last build
+	currentImage string
+	desiredImage string
+	// observed is true when the Service status reflects its latest spec
+	observed              bool
+	latestCreatedRevision string
+	latestReadyRevision   string
+	// configurationFailed is true when the latest created revision could not become ready
+	configurationFailed bool
+}
+
+func observeRollout(ksvc *knservingv1.Service, desiredImage string) rolloutObservation {
+	observation := rolloutObservation{
+		desiredImage:          desiredImage,
+		observed:              ksvc.Status.ObservedGeneration == ksvc.Generation,
+		latestCreatedRevision: ksvc.Status.LatestCreatedRevisionName,
+		latestReadyRevision:   ksvc.Status.LatestReadyRevisionName,
+	}
+	if len(ksvc.Spec.Template.Spec.Containers) > 0 {
+		observation.currentImage = ksvc.Spec.Template.Spec.Containers[0].Image
+	}
+	if condition := ksvc.Status.GetCondition(knservingv1.ServiceConditionConfigurationsReady); condition != nil && condition.IsFalse() {
+		observation.configurationFailed = true
+	}
+	return observation
+}
+
+/*
+validateRollout verifica as regras de 'spec.deploy.rollout' que a validação do CRD não expressa:
+percentuais crescentes, nomes de métricas únicos e limites numéricos válidos.
+*/
+func validateRollout(function *functionsv1alpha1.Function) error {
+	rollout := function.Spec.Deploy.Rollout
+	if rollout == nil {
+		return nil
+	}
+
+	var previous int64
+	for i, step := range rollout.Steps {
+		if step.Percent <= previous {
+			return fmt.Errorf("spec.deploy.rollout.steps[%d]: percentual %d deve ser maior que o do passo anterior (%d)", i, step.Percent, previous)
+		}
+		previous = step.Percent
+	}
+
+	if rollout.Analysis != nil {
+		names := map[string]bool{}
+		for _, metric := range rollout.Analysis.Metrics {
+			if names[metric.Name] {
+				return fmt.Errorf("spec.deploy.rollout.analysis: métrica %q duplicada", metric.Name)
+			}
+			names[metric.Name] = true
+			if _, err := strconv.ParseFloat(metric.Max, 64); err != nil {
+				return fmt.Errorf("spec.deploy.rollout.analysis: máximo inválido para %q: %w", metric.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func rolloutStepPause(step functionsv1alpha1.RolloutStep) time.Duration {
+	if step.Pause != nil {
+		return step.Pause.Duration
+	}
+	return defaultRolloutPause
+}
+
+// rolloutTraffic routes percent of the traffic to the canary and the rest to the stable revision.
+// Without a known canary revision, the canary slice follows the latest revision.
+func rolloutTraffic(stableRevision, canaryRevision string, percent int64) []knservingv1.TrafficTarget {
+	canary := knservingv1.TrafficTarget{Tag: canaryTag, LatestRevision: boolPtr(true), Percent: int64Ptr(percent)}
+	if canaryRevision != "" {
+		canary.LatestRevision = boolPtr(false)
+		canary.RevisionName = canaryRevision
+	}
+	return []knservingv1.TrafficTarget{
+		{RevisionName: stableRevision, LatestRevision: boolPtr(false), Percent: int64Ptr(100 - percent)},
+		canary,
+	}
+}
+
+/*
+advanceRollout avança a máquina de estados do rollout canário e retorna a rota desejada.
+O estado fica em 'function.Status.Rollout':
+  - sem rollout em andamento, uma nova imagem inicia um rollout a partir da revisão pronta atual (a estável);
+  - o primeiro passo começa quando a nova revisão fica pronta;
+  - ao fim da pausa de cada passo, a análise decide entre avançar e voltar para a revisão estável;
+  - após o último passo, a nova revisão passa a receber 100% do tráfego.
+*/
+func advanceRollout(ctx context.Context, function *functionsv1alpha1.Function, observation rolloutObservation,
+	now time.Time, analyze rolloutAnalyzer) []knservingv1.TrafficTarget {
+	steps := function.Spec.Deploy.Rollout.Steps
+	digest := function.Status.ImageDigest
+	status := function.Status.Rollout
+
+	startStep := func(step int32) {
+		status.Step = step
+		status.CanaryPercent = steps[step].Percent
+		status.StepStartTime = &metav1.Time{Time: now}
+		status.Message = fmt.Sprintf("Passo %d/%d: %d%% do tráfego para %s", step+1, len(steps), steps[step].Percent, status.CanaryRevision)
+	}
+	rollback := func(reason string) []knservingv1.TrafficTarget {
+		status.Phase = rolloutRolledBack
+		status.CanaryPercent = 0
+		status.StepStartTime = nil
+		status.Message = fmt.Sprintf("Rollback para %s: %s", status.StableRevision, reason)
+		return rolloutTraffic(status.StableRevision, status.CanaryRevision, 0)
+	}
+
+	if status == nil || status.Phase != rolloutProgressing {
+		// Uma imagem que já falhou no rollout não é tentada de novo: só um novo build inicia outro rollout
+		failedDigest := status != nil && status.Phase == rolloutRolledBack && status.ImageDigest == digest
+		if observation.currentImage != "" && observation.currentImage != observation.desiredImage && !failedDigest {
+			stable := observation.latestReadyRevision
+			if status != nil && status.Phase == rolloutRolledBack {
+				stable = status.StableRevision
+			}
+			if stable != "" {
+				function.Status.Rollout = &functionsv1alpha1.RolloutStatus{
+					Phase:          rolloutProgressing,
+					ImageDigest:    digest,
+					StableRevision: stable,
+					Message:        "Aguardando a nova revisão ficar pronta",
+				}
+				return rolloutTraffic(stable, "", 0)
+			}
+		}
+		if status != nil && status.Phase == rolloutRolledBack {
+			return rolloutTraffic(status.StableRevision, status.CanaryRevision, 0)
+		}
+		return knativeTrafficTargets(nil)
+	}
+
+	// Novo build durante o rollout: recomeçar a partir da mesma revisão estável
+	if status.ImageDigest != digest {
+		function.Status.Rollout = &functionsv1alpha1.RolloutStatus{
+			Phase:          rolloutProgressing,
+			ImageDigest:    digest,
+			StableRevision: status.StableRevision,
+			Message:        "Aguardando a nova revisão ficar pronta",
+		}
+		return rolloutTraffic(status.StableRevision, "", 0)
+	}
+
+	if status.CanaryRevision == "" {
+		switch {
+		case !observation.observed:
+			return rolloutTraffic(status.StableRevision, "", 0)
+		case observation.latestReadyRevision != status.StableRevision &&
+			observation.latestReadyRevision == observation.latestCreatedRevision && observation.latestReadyRevision != "":
+			status.CanaryRevision = observation.latestReadyRevision
+			startStep(0)
+		case observation.configurationFailed && observation.latestCreatedRevision != status.StableRevision:
+			status.CanaryRevision = observation.latestCreatedRevision
+			return rollback(fmt.Sprintf("a revisão %s não ficou pronta", observation.latestCreatedRevision))
+		default:
+			return rolloutTraffic(status.StableRevision, "", 0)
+		}
+	}
+
+	if int(status.Step) >= len(steps) {
+		// Passos removidos do spec durante o rollout
+		startStep(int32(len(steps) - 1))
+	}
+	if status.StepStartTime == nil || now.Before(status.StepStartTime.Add(rolloutStepPause(steps[status.Step]))) {
+		return rolloutTraffic(status.StableRevision, status.CanaryRevision, status.CanaryPercent)
+	}
+
+	if function.Spec.Deploy.Rollout.Analysis != nil {
+		results, failures, err := analyze(ctx, function, status.CanaryRevision)
+		status.Analysis = results
+		if err != nil {
+			// Erro ao consultar as métricas (ex: Prometheus indisponível): manter o passo e tentar de novo
+			status.Message = fmt.Sprintf("Passo %d/%d: análise não avaliada: %v", status.Step+1, len(steps), err)
+			return rolloutTraffic(status.StableRevision, status.CanaryRevision, status.CanaryPercent)
+		}
+		if len(failures) > 0 {
+			return rollback(strings.Join(failures, "; "))
+		}
+	}
+
+	if int(status.Step)+1 < len(steps) {
+		startStep(status.Step + 1)
+		return rolloutTraffic(status.StableRevision, status.CanaryRevision, status.CanaryPercent)
+	}
+
+	status.Phase = rolloutSucceeded
+	status.StableRevision = status.CanaryRevision
+	status.CanaryRevision = ""
+	status.CanaryPercent = 100
+	status.StepStartTime = nil
+	status.Message = fmt.Sprintf("Rollout concluído: %s recebe 100%% do tráfego", status.StableRevision)
+	return knativeTrafficTargets(nil)
+}
+
+/*
+deployedCondition retorna a condição Ready de uma Function implantada. Depois de um rollback,
+a Function continua servindo a revisão estável, mas Ready fica False com reason 'RolledBack'
+até que um novo build seja promovido.
+*/
+func deployedCondition(function *functionsv1alpha1.Function, message string) metav1.Condition {
+	if status := function.Status.Rollout; status != nil && status.Phase == rolloutRolledBack && function.Spec.Deploy.PinnedImage == "" {
+		return metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "RolledBack",
+			Message: status.Message,
+		}
+	}
+	return metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionTrue,
+		Reason:  "Ready",
+		Message: message,
+	}
+}
+
+// rolloutRequeueAfter returns when the rollout of a Function needs to be evaluated again (0 when idle).
+func rolloutRequeueAfter(function *functionsv1alpha1.Function, now time.Time) time.Duration {
+	rollout, status := function.Spec.Deploy.Rollout, function.Status.Rollout
+	if rollout == nil || status == nil || status.Phase != rolloutProgressing || function.Spec.Deploy.PinnedImage != "" {
+		return 0
+	}
+	if status.StepStartTime == nil || int(status.Step) >= len(rollout.Steps) {
+		return rolloutPollInterval
+	}
+	remaining := status.StepStartTime.Add(rolloutStepPause(rollout.Steps[status.Step])).Sub(now)
+	if remaining <= 0 {
+		return rolloutAnalysisRetryInterval
+	}
+	return remaining
+}
+
+/*
+evaluateRolloutAnalysis executa as consultas da análise para a revisão canário.
+Uma consulta sem dados reprova a métrica, pois não há evidência de que a revisão está saudável.
+*/
+func evaluateRolloutAnalysis(ctx context.Context, prometheus *prometheusClient, function *functionsv1alpha1.Function,
+	canaryRevision string) ([]functionsv1alpha1.RolloutMetricResult, []string, error) {
+	placeholders := strings.NewReplacer(
+		"$(revision)", canaryRevision,
+		"$(function)", function.Name,
+		"$(namespace)", function.Namespace,
+	)
+
+	var results []functionsv1alpha1.RolloutMetricResult
+	var failures []string
+	for _, metric := range function.Spec.Deploy.Rollout.Analysis.Metrics {
+		maxValue, err := strconv.ParseFloat(metric.Max, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("máximo inválido para %q: %w", metric.Name, err)
+		}
+		value, found, err := prometheus.query(ctx, placeholders.Replace(metric.Query))
+		if err != nil {
+			return nil, nil, fmt.Errorf("métrica %q: %w", metric.Name, err)
+		}
+
+		result := functionsv1alpha1.RolloutMetricResult{Name: metric.Name}
+		switch {
+		case !found:
+			failures = append(failures, fmt.Sprintf("%s: sem dados", metric.Name))
+		case value > maxValue:
+			result.Value = strconv.FormatFloat(value, 'g', -1, 64)
+			failures = append(failures, fmt.Sprintf("%s=%s acima do máximo %s", metric.Name, result.Value, metric.Max))
+		default:
+			result.Value = strconv.FormatFloat(value, 'g', -1, 64)
+			result.Passed = true
+		}
+		results = append(results, result)
+	}
+	return results, failures, nil
+}
+
+/*
+rolloutPrometheusURL retorna o Prometheus consultado pela análise do rollout: o configurado no operator
+ou, se o operator permitir a substituição, o de 'spec.deploy.rollout.analysis.prometheusURL'.
+Sem a permissão, uma Function não escolhe para onde o operator envia as consultas.
+*/
+func (r *FunctionReconciler) rolloutPrometheusURL(function *functionsv1alpha1.Function) (string, error) {
+	if override := function.Spec.Deploy.Rollout.Analysis.PrometheusURL; override != "" {
+		if !r.AllowPrometheusURLOverride {
+			return "", fmt.Errorf("spec.deploy.rollout.analysis.prometheusURL não é permitido pelo operator (--allow-prometheus-url-override)")
+		}
+		return override, nil
+	}
+	if r.PrometheusURL == "" {
+		return "", fmt.Errorf("spec.deploy.rollout.analysis exige um Prometheus configurado no operator (--prometheus-url)")
+	}
+	return r.PrometheusURL, nil
+}
+
+func (r *FunctionReconciler) analyzeRollout(ctx context.Context, function *functionsv1alpha1.Function,
+	canaryRevision string) ([]functionsv1alpha1.RolloutMetricResult, []string, error) {
+	prometheusURL, err := r.rolloutPrometheusURL(function)
+	if err != nil {
+		return nil, nil, err
+	}
+	return evaluateRolloutAnalysis(ctx, newPrometheusClient(prometheusURL), function, canaryRevision)
+}
+
+// reconcileRollout advances the canary rollout of a Function, persists its status when it changes
+// and returns the traffic targets for the Knative Service.
+func (r *FunctionReconciler) reconcileRollout(ctx context.Context, function *functionsv1alpha1.Function,
+	ksvc *knservingv1.Service, desiredImage string) ([]knservingv1.TrafficTarget, error) {
+	logger := logf.FromContext(ctx)
+
+	previous := function.Status.Rollout.DeepCopy()
+	traffic := advanceRollout(ctx, function, observeRollout(ksvc, desiredImage), time.Now(), r.analyzeRollout)
+	if equality.Semantic.DeepEqual(previous, function.Status.Rollout) {
+		return traffic, nil
+	}
+
+	status := function.Status.Rollout
+	logger.Info("Rollout atualizado",
+		"Phase", status.Phase,
+		"StableRevision", status.StableRevision,
+		"CanaryRevision", status.CanaryRevision,
+		"CanaryPercent", status.CanaryPercent,
+		"Message", status.Message)
+	if err := r.Status().Update(ctx, function); err != nil {
+		return nil, err
+	}
+	return traffic, nil
+}
@@ -0,0 +1,388 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// fakePrometheus is a Prometheus stand-in answering /api/v1/query with a value per query.
+// Queries without a configured value return an empty vector.
+type fakePrometheus struct {
+	mu      sync.Mutex
+	values  map[string]string
+	queries []string
+	fail    bool
+}
+
+func (p *fakePrometheus) set(query, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[query] = value
+}
+
+func (p *fakePrometheus) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if req.URL.Path != "/api/v1/query" {
+		http.NotFound(w, req)
+		return
+	}
+	query := req.URL.Query().Get("query")
+	p.queries = append(p.queries, query)
+
+	if p.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprint(w, `{"status":"error","errorType":"unavailable","error":"storage not ready"}`)
+		return
+	}
+
+	result := []any{}
+	if value, ok := p.values[query]; ok {
+		result = append(result, map[string]any{"metric": map[string]string{}, "value": []any{1700000000.0, value}})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status": "success",
+		"data":   map[string]any{"resultType": "vector", "result": result},
+	})
+}
+
+func newFakePrometheus(t *testing.T) (*fakePrometheus, *httptest.Server) {
+	prometheus := &fakePrometheus{values: map[string]string{}}
+	server := httptest.NewServer(prometheus)
+	t.Cleanup(server.Close)
+	return prometheus, server
+}
+
+func TestPrometheusQuery(t *testing.T) {
+	g := NewWithT(t)
+	prometheus, server := newFakePrometheus(t)
+	prometheus.set("up", "1")
+	prometheus.set("idle_ratio", "NaN")
+	client := newPrometheusClient(server.URL + "/")
+
+	value, found, err := client.query(context.Background(), "up")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(value).To(Equal(1.0))
+
+	_, found, err = client.query(context.Background(), "missing")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(found).To(BeFalse())
+
+	// 0/0 ratios (no traffic) count as missing data
+	_, found, err = client.query(context.Background(), "idle_ratio")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(found).To(BeFalse())
+
+	prometheus.fail = true
+	_, _, err = client.query(context.Background(), "up")
+	g.Expect(err).To(MatchError(ContainSubstring("storage not ready")))
+}
+
+func TestPrometheusQueryScalar(t *testing.T) {
+	g := NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[1700000000,"0.25"]}}`)
+	}))
+	defer server.Close()
+
+	value, found, err := newPrometheusClient(server.URL).query(context.Background(), "scalar(0.25)")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(value).To(Equal(0.25))
+}
+
+func TestValidateRollout(t *testing.T) {
+	step := func(percent int64) functionsv1alpha1.RolloutStep {
+		return functionsv1alpha1.RolloutStep{Percent: percent}
+	}
+	metric := func(name, maxValue string) functionsv1alpha1.RolloutMetric {
+		return functionsv1alpha1.RolloutMetric{Name: name, Query: "q", Max: maxValue}
+	}
+
+	tests := []struct {
+		name    string
+		rollout *functionsv1alpha1.RolloutSpec
+		wantErr string
+	}{
+		{name: "no rollout"},
+		{name: "increasing steps", rollout: &functionsv1alpha1.RolloutSpec{Steps: []functionsv1alpha1.RolloutStep{step(10), step(50), step(100)}}},
+		{name: "decreasing steps", rollout: &functionsv1alpha1.RolloutSpec{Steps: []functionsv1alpha1.RolloutStep{step(50), step(10)}}, wantErr: "maior que o do passo anterior"},
+		{
+			name: "duplicate metrics",
+			rollout: &functionsv1alpha1.RolloutSpec{
+				Steps:    []functionsv1alpha1.RolloutStep{step(10)},
+				Analysis: &functionsv1alpha1.RolloutAnalysis{Metrics: []functionsv1alpha1.RolloutMetric{metric("errors", "0.01"), metric("errors", "0.02")}},
+			},
+			wantErr: `métrica "errors" duplicada`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{
+				Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Rollout: tt.rollout}},
+			}
+			err := validateRollout(function)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestEvaluateRolloutAnalysis(t *testing.T) {
+	g := NewWithT(t)
+	prometheus, server := newFakePrometheus(t)
+	prometheus.set(`error_rate{revision="fn-00002",namespace="team-a"}`, "0.002")
+	prometheus.set(`p99_latency{revision="fn-00002"}`, "0.9")
+
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "fn", Namespace: "team-a"},
+		Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Rollout: &functionsv1alpha1.RolloutSpec{
+			Steps: []functionsv1alpha1.RolloutStep{{Percent: 10}},
+			Analysis: &functionsv1alpha1.RolloutAnalysis{
+				Metrics: []functionsv1alpha1.RolloutMetric{
+					{Name: "error-rate", Query: `error_rate{revision="$(revision)",namespace="$(namespace)"}`, Max: "0.01"},
+					{Name: "latency", Query: `p99_latency{revision="$(revision)"}`, Max: "0.5"},
+					{Name: "saturation", Query: `saturation{function="$(function)"}`, Max: "0.8"},
+				},
+			},
+		}}},
+	}
+
+	results, failures, err := evaluateRolloutAnalysis(context.Background(), newPrometheusClient(server.URL), function, "fn-00002")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(results).To(Equal([]functionsv1alpha1.RolloutMetricResult{
+		{Name: "error-rate", Value: "0.002", Passed: true},
+		{Name: "latency", Value: "0.9"},
+		{Name: "saturation"},
+	}))
+	g.Expect(failures).To(Equal([]string{"latency=0.9 acima do máximo 0.5", "saturation: sem dados"}))
+	g.Expect(prometheus.queries).To(ContainElement(`saturation{function="fn"}`))
+}
+
+func TestRolloutPrometheusURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator string
+		override string
+		allow    bool
+		expected string
+		wantErr  string
+	}{
+		{name: "operator URL", operator: "http://prometheus.monitoring:9090", expected: "http://prometheus.monitoring:9090"},
+		{name: "no URL configured", wantErr: "exige um Prometheus configurado no operator"},
+		{name: "override refused", operator: "http://prometheus.monitoring:9090", override: "http://10.0.0.1:9090", wantErr: "não é permitido pelo operator"},
+		{name: "override allowed", operator: "http://prometheus.monitoring:9090", override: "http://thanos.team-a:9090", allow: true, expected: "http://thanos.team-a:9090"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			r := &FunctionReconciler{PrometheusURL: tt.operator, AllowPrometheusURLOverride: tt.allow}
+			function := &functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{
+				Rollout: &functionsv1alpha1.RolloutSpec{Analysis: &functionsv1alpha1.RolloutAnalysis{PrometheusURL: tt.override}},
+			}}}
+
+			prometheusURL, err := r.rolloutPrometheusURL(function)
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(prometheusURL).To(Equal(tt.expected))
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			}
+		})
+	}
+}
+
+func TestDeployedCondition(t *testing.T) {
+	g := NewWithT(t)
+	function := &functionsv1alpha1.Function{}
+	g.Expect(deployedCondition(function, "ok")).To(Equal(metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Ready", Message: "ok"}))
+
+	function.Status.Rollout = &functionsv1alpha1.RolloutStatus{Phase: rolloutRolledBack, Message: "Rollback para fn-00001: error-rate=0.2 acima do máximo 0.01"}
+	g.Expect(deployedCondition(function, "ok")).To(Equal(metav1.Condition{
+		Type: "Ready", Status: metav1.ConditionFalse, Reason: "RolledBack", Message: "Rollback para fn-00001: error-rate=0.2 acima do máximo 0.01",
+	}))
+
+	// A pinned image suspends the rollout
+	function.Spec.Deploy.PinnedImage = "sha256:abc"
+	g.Expect(deployedCondition(function, "ok").Status).To(Equal(metav1.ConditionTrue))
+}
+
+// rolloutFixture drives advanceRollout through a sequence of observations of the Knative Service.
+type rolloutFixture struct {
+	function *functionsv1alpha1.Function
+	now      time.Time
+	failures []string
+	analyzed int
+}
+
+func newRolloutFixture(withAnalysis bool) *rolloutFixture {
+	rollout := &functionsv1alpha1.RolloutSpec{Steps: []functionsv1alpha1.RolloutStep{
+		{Percent: 10, Pause: &metav1.Duration{Duration: 5 * time.Minute}},
+		{Percent: 50},
+	}}
+	if withAnalysis {
+		rollout.Analysis = &functionsv1alpha1.RolloutAnalysis{}
+	}
+	return &rolloutFixture{
+		function: &functionsv1alpha1.Function{
+			ObjectMeta: metav1.ObjectMeta{Name: "fn", Namespace: "default"},
+			Spec:       functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Rollout: rollout}},
+			Status:     functionsv1alpha1.FunctionStatus{ImageDigest: "registry.io/fn@" + digestB},
+		},
+		now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func (f *rolloutFixture) advance(observation rolloutObservation) []knservingv1.TrafficTarget {
+	analyze := func(context.Context, *functionsv1alpha1.Function, string) ([]functionsv1alpha1.RolloutMetricResult, []string, error) {
+		f.analyzed++
+		return nil, f.failures, nil
+	}
+	return advanceRollout(context.Background(), f.function, observation, f.now, analyze)
+}
+
+// Observations of the Knative Service during a rollout from fn-00001 (image A) to image B
+var (
+	newImageApplied = rolloutObservation{
+		currentImage: "registry.io/fn@" + digestA, desiredImage: "registry.io/fn@" + digestB,
+		observed: true, latestCreatedRevision: "fn-00001", latestReadyRevision: "fn-00001",
+	}
+	canaryReady = rolloutObservation{
+		currentImage: "registry.io/fn@" + digestB, desiredImage: "registry.io/fn@" + digestB,
+		observed: true, latestCreatedRevision: "fn-00002", latestReadyRevision: "fn-00002",
+	}
+	canaryFailed = rolloutObservation{
+		currentImage: "registry.io/fn@" + digestB, desiredImage: "registry.io/fn@" + digestB,
+		observed: true, latestCreatedRevision: "fn-00002", latestReadyRevision: "fn-00001", configurationFailed: true,
+	}
+)
+
+func TestAdvanceRollout(t *testing.T) {
+	t.Run("promotes the canary step by step", func(t *testing.T) {
+		g := NewWithT(t)
+		f := newRolloutFixture(true)
+
+		// A new image starts the rollout: the new revision is created without traffic
+		g.Expect(f.advance(newImageApplied)).To(Equal(rolloutTraffic("fn-00001", "", 0)))
+		g.Expect(f.function.Status.Rollout.Phase).To(Equal(rolloutProgressing))
+		g.Expect(f.function.Status.Rollout.StableRevision).To(Equal("fn-00001"))
+
+		// The canary is ready: first step
+		g.Expect(f.advance(canaryReady)).To(Equal(rolloutTraffic("fn-00001", "fn-00002", 10)))
+		g.Expect(f.function.Status.Rollout.CanaryRevision).To(Equal("fn-00002"))
+		g.Expect(rolloutRequeueAfter(f.function, f.now)).To(Equal(5 * time.Minute))
+
+		// Pause not elapsed: no analysis
+		f.now = f.now.Add(4 * time.Minute)
+		g.Expect(f.advance(canaryReady)).To(Equal(rolloutTraffic("fn-00001", "fn-00002", 10)))
+		g.Expect(f.analyzed).To(Equal(0))
+
+		// Pause elapsed, analysis passes: second step with the default pause
+		f.now = f.now.Add(time.Minute)
+		g.Expect(f.advance(canaryReady)).To(Equal(rolloutTraffic("fn-00001", "fn-00002", 50)))
+		g.Expect(f.analyzed).To(Equal(1))
+		g.Expect(f.function.Status.Rollout.Step).To(Equal(int32(1)))
+		g.Expect(rolloutRequeueAfter(f.function, f.now)).To(Equal(defaultRolloutPause))
+
+		// Last step passes: the new revision gets all traffic
+		f.now = f.now.Add(defaultRolloutPause)
+		g.Expect(f.advance(canaryReady)).To(Equal(knativeTrafficTargets(nil)))
+		g.Expect(f.function.Status.Rollout.Phase).To(Equal(rolloutSucceeded))
+		g.Expect(f.function.Status.Rollout.StableRevision).To(Equal("fn-00002"))
+		g.Expect(rolloutRequeueAfter(f.function, f.now)).To(BeZero())
+
+		// Steady state
+		g.Expect(f.advance(canaryReady)).To(Equal(knativeTrafficTargets(nil)))
+	})
+
+	t.Run("rolls back when the analysis fails", func(t *testing.T) {
+		g := NewWithT(t)
+		f := newRolloutFixture(true)
+		f.advance(newImageApplied)
+		f.advance(canaryReady)
+
+		f.failures = []string{"error-rate=0.2 acima do máximo 0.01"}
+		f.now = f.now.Add(5 * time.Minute)
+		g.Expect(f.advance(canaryReady)).To(Equal(rolloutTraffic("fn-00001", "fn-00002", 0)))
+		g.Expect(f.function.Status.Rollout.Phase).To(Equal(rolloutRolledBack))
+		g.Expect(f.function.Status.Rollout.Message).To(Equal("Rollback para fn-00001: error-rate=0.2 acima do máximo 0.01"))
+
+		// The failed image is not rolled out again
+		g.Expect(f.advance(canaryReady)).To(Equal(rolloutTraffic("fn-00001", "fn-00002", 0)))
+		g.Expect(f.function.Status.Rollout.Phase).To(Equal(rolloutRolledBack))
+
+		// A new build starts a new rollout from the same stable revision
+		f.function.Status.ImageDigest = "registry.io/fn@" + digestA
+		g.Expect(f.advance(rolloutObservation{
+			currentImage: "registry.io/fn@" + digestB, desiredImage: "registry.io/fn@" + digestA,
+			observed: true, latestCreatedRevision: "fn-00002", latestReadyRevision: "fn-00002",
+		})).To(Equal(rolloutTraffic("fn-00001", "", 0)))
+		g.Expect(f.function.Status.Rollout.Phase).To(Equal(rolloutProgressing))
+		g.Expect(f.function.Status.Rollout.StableRevision).To(Equal("fn-00001"))
+	})
+
+	t.Run("rolls back when the canary revision does not become ready", func(t *testing.T) {
+		g := NewWithT(t)
+		f := newRolloutFixture(false)
+		f.advance(newImageApplied)
+
+		g.Expect(f.advance(canaryFailed)).To(Equal(rolloutTraffic("fn-00001", "fn-00002", 0)))
+		g.Expect(f.function.Status.Rollout.Phase).To(Equal(rolloutRolledBack))
+		g.Expect(f.function.Status.Rollout.Message).To(ContainSubstring("a revisão fn-00002 não ficou pronta"))
+	})
+
+	t.Run("advances without analysis", func(t *testing.T) {
+		g := NewWithT(t)
+		f := newRolloutFixture(false)
+		f.advance(newImageApplied)
+		f.advance(canaryReady)
+
+		f.now = f.now.Add(5 * time.Minute)
+		g.Expect(f.advance(canaryReady)).To(Equal(rolloutTraffic("fn-00001", "fn-00002", 50)))
+		g.Expect(f.analyzed).To(BeZero())
+	})
+
+	t.Run("first deploy has no stable revision", func(t *testing.T) {
+		g := NewWithT(t)
+		f := newRolloutFixture(true)
+
+		g.Expect(f.advance(rolloutObservation{desiredImage: "registry.io/fn@" + digestB})).To(Equal(knativeTrafficTargets(nil)))
+		g.Expect(f.function.Status.Rollout).To(BeNil())
+	})
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// permissionVerbs are the verbs spec.deploy.permissions may grant; escalation verbs and '*' are left out
+var permissionVerbs = map[string]bool{
+	"get": true, "list": true, "watch": true, "create": true,
+	"update": true, "patch": true, "delete": true, "deletecollection": true,
+}
+
+/*
+PermissionAllowlist é a política do operator para 'spec.deploy.permissions': para cada recurso, os verbos
+que uma Function pode conceder ao próprio ServiceAccount de runtime. As chaves seguem o formato do kubectl,
+"<recurso>" para o grupo core e "<recurso>.<grupo>" para os demais (por exemplo "jobs.batch" e
+"deployments/status.apps"). Regras fora da política são recusadas.
+*/
+type PermissionAllowlist map[string]map[string]bool
+
+// readVerbs are the verbs of the default allowlist entries.
+var readVerbs = []string{"get", "list", "watch"}
+
+/*
+DefaultPermissionAllowlist só concede leitura de recursos que não guardam credenciais.
+Escrita em Jobs, Pods, Services do Knative, TaskRuns do Tekton ou ServiceAccounts permitiria rodar
+código com outra identidade (por exemplo a '<nome>-sa' do build, que tem o Secret do registry), então
+fica a cargo do administrador liberá-la com '--runtime-permissions-allowlist'.
+*/
+func DefaultPermissionAllowlist() PermissionAllowlist {
+	allowlist := PermissionAllowlist{}
+	for _, resource := range []string{
+		"configmaps", "pods", "pods/log", "services", "endpoints", "events",
+		"events.events.k8s.io", "deployments.apps", "deployments/status.apps", "replicasets.apps",
+		"statefulsets.apps", "jobs.batch", "cronjobs.batch",
+	} {
+		allowlist[resource] = map[string]bool{}
+		for _, verb := range readVerbs {
+			allowlist[resource][verb] = true
+		}
+	}
+	return allowlist
+}
+
+/*
+ParsePermissionAllowlist lê a política da flag '--runtime-permissions-allowlist': entradas separadas por vírgula
+no formato "<recurso>[.<grupo>]=<verbo>|<verbo>", por exemplo "configmaps=get|list,jobs.batch=get|create".
+Sem "=", todos os verbos aceitos por 'spec.deploy.permissions' são liberados para o recurso.
+*/
+func ParsePermissionAllowlist(value string) (PermissionAllowlist, error) {
+	allowlist := PermissionAllowlist{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		resource, verbs, hasVerbs := strings.Cut(entry, "=")
+		resource = strings.TrimSpace(resource)
+		if resource == "" || strings.Contains(resource, rbacv1.ResourceAll) {
+			return nil, fmt.Errorf("entrada inválida %q: informe o recurso sem curingas", entry)
+		}
+		if allowlist[resource] == nil {
+			allowlist[resource] = map[string]bool{}
+		}
+		if !hasVerbs {
+			for verb := range permissionVerbs {
+				allowlist[resource][verb] = true
+			}
+			continue
+		}
+		for _, verb := range strings.Split(verbs, "|") {
+			verb = strings.TrimSpace(verb)
+			if !permissionVerbs[verb] {
+				return nil, fmt.Errorf("entrada inválida %q: o verbo %q não é aceito", entry, verb)
+			}
+			allowlist[resource][verb] = true
+		}
+	}
+	return allowlist, nil
+}
+
+// permissionAllowlist returns the configured allowlist, or the default one when the operator sets none.
+func (r *FunctionReconciler) permissionAllowlist() PermissionAllowlist {
+	if r.PermissionAllowlist == nil {
+		return DefaultPermissionAllowlist()
+	}
+	return r.PermissionAllowlist
+}
+
+// runtimeServiceAccountName is the ServiceAccount the function revisions run as (the "-sa" one is for builds).
+func runtimeServiceAccountName(function *functionsv1alpha1.Function) string {
+	return function.Name + "-runtime"
+}
+
+/*
+validatePermissions verifica as regras de 'spec.deploy.permissions' contra a política do operator.
+Curingas são recusados, e cada par recurso/verbo precisa estar na allowlist: a função não pode conceder
+a si mesma nada que o administrador não tenha liberado.
+*/
+func validatePermissions(function *functionsv1alpha1.Function, allowlist PermissionAllowlist) error {
+	for i, rule := range function.Spec.Deploy.Permissions {
+		field := fmt.Sprintf("spec.deploy.permissions[%d]", i)
+		if len(rule.Resources) == 0 || len(rule.Verbs) == 0 {
+			return fmt.Errorf("%s: resources e verbs são obrigatórios", field)
+		}
+		apiGroups := rule.APIGroups
+		if len(apiGroups) == 0 {
+			apiGroups = []string{""}
+		}
+		for _, group := range apiGroups {
+			if group == rbacv1.APIGroupAll {
+				return fmt.Errorf("%s.apiGroups: curingas não são aceitos", field)
+			}
+		}
+		for _, verb := range rule.Verbs {
+			if !permissionVerbs[verb] {
+				return fmt.Errorf("%s.verbs: o verbo %q não é aceito", field, verb)
+			}
+		}
+		for _, resource := range rule.Resources {
+			if resource == rbacv1.ResourceAll || strings.HasPrefix(resource, "*/") {
+				return fmt.Errorf("%s.resources: curingas não são aceitos", field)
+			}
+			for _, group := range apiGroups {
+				key := resource
+				if group != "" {
+					key = resource + "." + group
+				}
+				for _, verb := range rule.Verbs {
+					if !allowlist[key][verb] {
+						return fmt.Errorf("%s: o operator não permite conceder %q em %q à função", field, verb, key)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// runtimeIdentityConflictError reports a runtime ServiceAccount, Role or RoleBinding the function does not control.
+type runtimeIdentityConflictError struct {
+	kind string
+	key  types.NamespacedName
+}
+
+func (e *runtimeIdentityConflictError) Error() string {
+	return fmt.Sprintf("O %s %s já existe no namespace %s e não pertence a esta função", e.kind, e.key.Name, e.key.Namespace)
+}
+
+// runtimeLabels are the labels of the runtime ServiceAccount, Role and RoleBinding.
+func runtimeLabels(function *functionsv1alpha1.Function) map[string]string {
+	return map[string]string{
+		"functions.zenith.com/managed-by": "zenith-operator",
+		FunctionNameLabel:                 function.Name,
+	}
+}
+
+/*
+buildRuntimeServiceAccount constrói o ServiceAccount de runtime da função, com as annotations de
+'spec.deploy.serviceAccount' e o Secret do registry para o pull das imagens.
+*/
+func buildRuntimeServiceAccount(function *functionsv1alpha1.Function) *v1.ServiceAccount {
+	serviceAccount := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      runtimeServiceAccountName(function),
+			Namespace: function.Namespace,
+			Labels:    runtimeLabels(function),
+		},
+	}
+	if spec := function.Spec.Deploy.ServiceAccount; spec != nil && len(spec.Annotations) > 0 {
+		serviceAccount.Annotations = spec.Annotations
+	}
+	if secretName := function.Spec.Build.RegistrySecretName; secretName != "" {
+		serviceAccount.ImagePullSecrets = []v1.LocalObjectReference{{Name: secretName}}
+	}
+	return serviceAccount
+}
+
+// buildRuntimeRole builds the Role with spec.deploy.permissions, or nil without permissions.
+func buildRuntimeRole(function *functionsv1alpha1.Function) *rbacv1.Role {
+	if len(function.Spec.Deploy.Permissions) == 0 {
+		return nil
+	}
+
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      runtimeServiceAccountName(function),
+			Namespace: function.Namespace,
+			Labels:    runtimeLabels(function),
+		},
+	}
+	for _, permission := range function.Spec.Deploy.Permissions {
+		apiGroups := permission.APIGroups
+		if len(apiGroups) == 0 {
+			apiGroups = []string{""}
+		}
+		role.Rules = append(role.Rules, rbacv1.PolicyRule{
+			APIGroups:     apiGroups,
+			Resources:     permission.Resources,
+			ResourceNames: permission.ResourceNames,
+			Verbs:         permission.Verbs,
+		})
+	}
+	return role
+}
+
+// buildRuntimeRoleBinding binds the runtime Role to the runtime ServiceAccount, or returns nil without permissions.
+func buildRuntimeRoleBinding(function *functionsv1alpha1.Function) *rbacv1.RoleBinding {
+	if len(function.Spec.Deploy.Permissions) == 0 {
+		return nil
+	}
+
+	name := runtimeServiceAccountName(function)
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: function.Namespace,
+			Labels:    runtimeLabels(function),
+		},
+		RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+		Subjects: []rbacv1.Subject{
+			{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: function.Namespace},
+		},
+	}
+}
+
+/*
+reconcileRuntimeIdentity sincroniza o ServiceAccount de runtime e, com 'spec.deploy.permissions',
+o Role e o RoleBinding que concedem as permissões a ele. Sem permissões, o Role e o RoleBinding
+da função são removidos. Objetos de mesmo nome que não pertencem à função não são adotados:
+retorna um runtimeIdentityConflictError.
+*/
+func (r *FunctionReconciler) reconcileRuntimeIdentity(ctx context.Context, function *functionsv1alpha1.Function) error {
+	logger := logf.FromContext(ctx)
+
+	desiredServiceAccount := buildRuntimeServiceAccount(function)
+	serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: desiredServiceAccount.Name, Namespace: function.Namespace}}
+	if err := r.checkRuntimeOwner(ctx, function, serviceAccount, "ServiceAccount"); err != nil {
+		return err
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, serviceAccount, func() error {
+		serviceAccount.Labels = desiredServiceAccount.Labels
+		serviceAccount.Annotations = desiredServiceAccount.Annotations
+		serviceAccount.ImagePullSecrets = desiredServiceAccount.ImagePullSecrets
+		return controllerutil.SetControllerReference(function, serviceAccount, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("ServiceAccount de runtime sincronizado", "ServiceAccountName", serviceAccount.Name, "Operation", op)
+	}
+
+	desiredRole := buildRuntimeRole(function)
+	if desiredRole == nil {
+		key := types.NamespacedName{Name: runtimeServiceAccountName(function), Namespace: function.Namespace}
+		if err := r.deleteOwned(ctx, function, key, &rbacv1.RoleBinding{}); err != nil {
+			return err
+		}
+		return r.deleteOwned(ctx, function, key, &rbacv1.Role{})
+	}
+
+	role := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: desiredRole.Name, Namespace: function.Namespace}}
+	if err := r.checkRuntimeOwner(ctx, function, role, "Role"); err != nil {
+		return err
+	}
+	op, err = controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+		role.Labels = desiredRole.Labels
+		role.Rules = desiredRole.Rules
+		return controllerutil.SetControllerReference(function, role, r.Scheme)
+	})
+	if err != nil {
+		// Sem 'escalate', a API só deixa o operator conceder permissões que ele mesmo tem
+		return fmt.Errorf("falha ao sincronizar o Role %s: %w", role.Name, err)
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("Role de runtime sincronizado", "Role.Name", role.Name, "Operation", op)
+	}
+
+	desiredRoleBinding := buildRuntimeRoleBinding(function)
+	roleBinding := &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: desiredRoleBinding.Name, Namespace: function.Namespace}}
+	if err := r.checkRuntimeOwner(ctx, function, roleBinding, "RoleBinding"); err != nil {
+		return err
+	}
+	op, err = controllerutil.CreateOrUpdate(ctx, r.Client, roleBinding, func() error {
+		roleBinding.Labels = desiredRoleBinding.Labels
+		// roleRef é imutável: só é definido na criação (o nome é sempre o do Role da função)
+		if roleBinding.RoleRef.Name == "" {
+			roleBinding.RoleRef = desiredRoleBinding.RoleRef
+		}
+		roleBinding.Subjects = desiredRoleBinding.Subjects
+		return controllerutil.SetControllerReference(function, roleBinding, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("RoleBinding de runtime sincronizado", "RoleBinding.Name", roleBinding.Name, "Operation", op)
+	}
+	return nil
+}
+
+/*
+checkRuntimeOwner lê o objeto de runtime, se existir, e recusa um objeto que não é controlado pela função:
+adotá-lo daria à função um ServiceAccount ou permissões criados por outra pessoa.
+*/
+func (r *FunctionReconciler) checkRuntimeOwner(ctx context.Context, function *functionsv1alpha1.Function, obj client.Object, kind string) error {
+	key := client.ObjectKeyFromObject(obj)
+	if err := r.Get(ctx, key, obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(obj, function) {
+		return &runtimeIdentityConflictError{kind: kind, key: key}
+	}
+	return nil
+}
+
+// deleteOwned deletes the object with the given key if it exists and is controlled by the function.
+func (r *FunctionReconciler) deleteOwned(ctx context.Context, function *functionsv1alpha1.Function, key types.NamespacedName, obj client.Object) error {
+	if err := r.Get(ctx, key, obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(obj, function) {
+		return nil
+	}
+	logf.FromContext(ctx).Info("Permissões removidas do spec, deletando recurso de RBAC", "Name", key.Name)
+	if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
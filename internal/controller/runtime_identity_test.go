@@ -0,0 +1,379 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestValidatePermissions(t *testing.T) {
+	rule := func(group, resource string, verbs ...string) functionsv1alpha1.PermissionRule {
+		permission := functionsv1alpha1.PermissionRule{Resources: []string{resource}, Verbs: verbs}
+		if group != "" {
+			permission.APIGroups = []string{group}
+		}
+		return permission
+	}
+	adminAllowlist, err := ParsePermissionAllowlist("configmaps=get|list|watch, jobs.batch=get|list|create, pods/log")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		rule      functionsv1alpha1.PermissionRule
+		allowlist PermissionAllowlist
+		wantErr   string
+	}{
+		{name: "core group", rule: rule("", "pods/log", "get")},
+		{name: "read status", rule: rule("apps", "deployments/status", "get")},
+		{name: "read jobs", rule: rule("batch", "jobs", "get", "list", "watch")},
+		{
+			name:    "wildcard resource",
+			rule:    rule("", "*", "get"),
+			wantErr: "spec.deploy.permissions[0].resources: curingas",
+		},
+		{
+			name:    "wildcard group",
+			rule:    rule("*", "jobs", "get"),
+			wantErr: "spec.deploy.permissions[0].apiGroups: curingas",
+		},
+		{
+			name:    "escalation verb",
+			rule:    rule("", "serviceaccounts", "impersonate"),
+			wantErr: `o verbo "impersonate" não é aceito`,
+		},
+		{
+			name:    "no verbs",
+			rule:    functionsv1alpha1.PermissionRule{Resources: []string{"configmaps"}},
+			wantErr: "resources e verbs são obrigatórios",
+		},
+		// Cada caso abaixo deixaria a função rodar código ou ler credenciais com outra identidade
+		{
+			name:    "create TaskRuns",
+			rule:    rule("tekton.dev", "taskruns", "create"),
+			wantErr: `o operator não permite conceder "create" em "taskruns.tekton.dev"`,
+		},
+		{
+			name:    "create PipelineRuns",
+			rule:    rule("tekton.dev", "pipelineruns", "create"),
+			wantErr: `o operator não permite conceder "create" em "pipelineruns.tekton.dev"`,
+		},
+		{
+			name:    "create Knative Services",
+			rule:    rule("serving.knative.dev", "services", "create"),
+			wantErr: `o operator não permite conceder "create" em "services.serving.knative.dev"`,
+		},
+		{
+			name:    "create ServiceAccounts",
+			rule:    rule("", "serviceaccounts", "create"),
+			wantErr: `o operator não permite conceder "create" em "serviceaccounts"`,
+		},
+		{
+			name:    "write ConfigMaps",
+			rule:    rule("", "configmaps", "get", "update"),
+			wantErr: `o operator não permite conceder "update" em "configmaps"`,
+		},
+		{
+			name:    "create Pods",
+			rule:    rule("", "pods", "create"),
+			wantErr: `o operator não permite conceder "create" em "pods"`,
+		},
+		{
+			name:    "create Jobs",
+			rule:    rule("batch", "jobs", "create"),
+			wantErr: `o operator não permite conceder "create" em "jobs.batch"`,
+		},
+		{
+			name:    "secrets",
+			rule:    rule("", "secrets", "get"),
+			wantErr: `o operator não permite conceder "get" em "secrets"`,
+		},
+		{
+			name:    "service account tokens",
+			rule:    rule("", "serviceaccounts/token", "create"),
+			wantErr: `o operator não permite conceder "create" em "serviceaccounts/token"`,
+		},
+		{
+			name:    "exec into pods",
+			rule:    rule("", "pods/exec", "create"),
+			wantErr: `o operator não permite conceder "create" em "pods/exec"`,
+		},
+		{
+			name:    "rbac group",
+			rule:    rule("rbac.authorization.k8s.io", "rolebindings", "create"),
+			wantErr: `o operator não permite conceder "create" em "rolebindings.rbac.authorization.k8s.io"`,
+		},
+		{
+			name:    "write status",
+			rule:    rule("apps", "deployments/status", "get", "patch"),
+			wantErr: `o operator não permite conceder "patch" em "deployments/status.apps"`,
+		},
+		{
+			name:    "resource of another group with the same name",
+			rule:    rule("example.com", "configmaps", "get"),
+			wantErr: `o operator não permite conceder "get" em "configmaps.example.com"`,
+		},
+		{name: "allowed by the administrator", rule: rule("batch", "jobs", "create", "list"), allowlist: adminAllowlist},
+		{name: "entry without verbs", rule: rule("", "pods/log", "get", "delete"), allowlist: adminAllowlist},
+		{
+			name:      "outside the administrator allowlist",
+			rule:      rule("apps", "deployments", "get"),
+			allowlist: adminAllowlist,
+			wantErr:   `o operator não permite conceder "get" em "deployments.apps"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{
+				Permissions: []functionsv1alpha1.PermissionRule{tt.rule},
+			}}}
+			allowlist := tt.allowlist
+			if allowlist == nil {
+				allowlist = DefaultPermissionAllowlist()
+			}
+
+			err := validatePermissions(function, allowlist)
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			}
+		})
+	}
+}
+
+func TestParsePermissionAllowlist(t *testing.T) {
+	g := NewWithT(t)
+
+	allowlist, err := ParsePermissionAllowlist("configmaps=get|list, jobs.batch=create,,pods/log")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(allowlist).To(HaveLen(3))
+	g.Expect(allowlist["configmaps"]).To(Equal(map[string]bool{"get": true, "list": true}))
+	g.Expect(allowlist["jobs.batch"]).To(Equal(map[string]bool{"create": true}))
+	g.Expect(allowlist["pods/log"]).To(Equal(permissionVerbs))
+
+	_, err = ParsePermissionAllowlist("*.batch=get")
+	g.Expect(err).To(MatchError(ContainSubstring("sem curingas")))
+	_, err = ParsePermissionAllowlist("jobs.batch=get|escalate")
+	g.Expect(err).To(MatchError(ContainSubstring(`o verbo "escalate" não é aceito`)))
+	_, err = ParsePermissionAllowlist("=get")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestBuildRuntimeIdentity(t *testing.T) {
+	g := NewWithT(t)
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "reports", Namespace: "default", UID: "reports-uid"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			Build: functionsv1alpha1.BuildSpec{RegistrySecretName: "registry-credentials"},
+			Deploy: functionsv1alpha1.DeploySpec{
+				ServiceAccount: &functionsv1alpha1.RuntimeServiceAccountSpec{
+					Annotations: map[string]string{"iam.gke.io/gcp-service-account": "reports@acme.iam.gserviceaccount.com"},
+				},
+				Permissions: []functionsv1alpha1.PermissionRule{
+					{Resources: []string{"configmaps"}, ResourceNames: []string{"report-settings"}, Verbs: []string{"get", "watch"}},
+					{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"get", "list"}},
+				},
+			},
+		},
+	}
+
+	serviceAccount := buildRuntimeServiceAccount(function)
+	g.Expect(serviceAccount.Name).To(Equal("reports-runtime"))
+	g.Expect(serviceAccount.Annotations).To(HaveKeyWithValue("iam.gke.io/gcp-service-account", "reports@acme.iam.gserviceaccount.com"))
+	g.Expect(serviceAccount.ImagePullSecrets).To(Equal([]v1.LocalObjectReference{{Name: "registry-credentials"}}))
+
+	role := buildRuntimeRole(function)
+	g.Expect(role.Rules).To(Equal([]rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"report-settings"}, Verbs: []string{"get", "watch"}},
+		{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"get", "list"}},
+	}))
+
+	roleBinding := buildRuntimeRoleBinding(function)
+	g.Expect(roleBinding.RoleRef).To(Equal(rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "Role", Name: "reports-runtime"}))
+	g.Expect(roleBinding.Subjects).To(Equal([]rbacv1.Subject{{Kind: "ServiceAccount", Name: "reports-runtime", Namespace: "default"}}))
+
+	function.Spec.Deploy.Permissions = nil
+	g.Expect(buildRuntimeRole(function)).To(BeNil())
+	g.Expect(buildRuntimeRoleBinding(function)).To(BeNil())
+
+	r := &FunctionReconciler{}
+	g.Expect(r.buildKnativeService(function).Spec.Template.Spec.ServiceAccountName).To(Equal("reports-runtime"))
+}
+
+func TestReconcileRuntimeIdentity(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "reports-runtime", Namespace: "default"}
+	addToScheme := []func(*runtime.Scheme) error{v1.AddToScheme, rbacv1.AddToScheme}
+	base := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "reports", Namespace: "default", UID: "reports-uid"},
+		Spec: functionsv1alpha1.FunctionSpec{
+			Build: functionsv1alpha1.BuildSpec{RegistrySecretName: "registry-credentials"},
+			Deploy: functionsv1alpha1.DeploySpec{
+				ServiceAccount: &functionsv1alpha1.RuntimeServiceAccountSpec{
+					Annotations: map[string]string{"iam.gke.io/gcp-service-account": "reports@acme.iam.gserviceaccount.com"},
+				},
+				Permissions: []functionsv1alpha1.PermissionRule{
+					{Resources: []string{"configmaps"}, ResourceNames: []string{"report-settings"}, Verbs: []string{"get", "watch"}},
+					{APIGroups: []string{"batch"}, Resources: []string{"jobs"}, Verbs: []string{"get", "list"}},
+				},
+			},
+		},
+	}
+
+	t.Run("creates the ServiceAccount, Role and RoleBinding", func(t *testing.T) {
+		g := NewWithT(t)
+		r := newFakeReconciler(addToScheme)
+		function := base.DeepCopy()
+		g.Expect(r.reconcileRuntimeIdentity(ctx, function)).To(Succeed())
+
+		serviceAccount := &v1.ServiceAccount{}
+		g.Expect(r.Get(ctx, key, serviceAccount)).To(Succeed())
+		g.Expect(metav1.IsControlledBy(serviceAccount, function)).To(BeTrue())
+		g.Expect(serviceAccount.Annotations).To(HaveKey("iam.gke.io/gcp-service-account"))
+
+		role := &rbacv1.Role{}
+		g.Expect(r.Get(ctx, key, role)).To(Succeed())
+		g.Expect(role.Rules).To(HaveLen(2))
+		g.Expect(metav1.IsControlledBy(role, function)).To(BeTrue())
+
+		roleBinding := &rbacv1.RoleBinding{}
+		g.Expect(r.Get(ctx, key, roleBinding)).To(Succeed())
+		g.Expect(roleBinding.RoleRef.Name).To(Equal("reports-runtime"))
+	})
+
+	t.Run("updates the rules and annotations", func(t *testing.T) {
+		g := NewWithT(t)
+		r := newFakeReconciler(addToScheme)
+		function := base.DeepCopy()
+		g.Expect(r.reconcileRuntimeIdentity(ctx, function)).To(Succeed())
+
+		function.Spec.Deploy.ServiceAccount = &functionsv1alpha1.RuntimeServiceAccountSpec{
+			Annotations: map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::111122223333:role/reports"},
+		}
+		function.Spec.Deploy.Permissions = function.Spec.Deploy.Permissions[:1]
+		g.Expect(r.reconcileRuntimeIdentity(ctx, function)).To(Succeed())
+
+		serviceAccount := &v1.ServiceAccount{}
+		g.Expect(r.Get(ctx, key, serviceAccount)).To(Succeed())
+		g.Expect(serviceAccount.Annotations).To(Equal(map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::111122223333:role/reports"}))
+
+		role := &rbacv1.Role{}
+		g.Expect(r.Get(ctx, key, role)).To(Succeed())
+		g.Expect(role.Rules).To(HaveLen(1))
+	})
+
+	t.Run("removed permissions delete the Role and RoleBinding", func(t *testing.T) {
+		g := NewWithT(t)
+		r := newFakeReconciler(addToScheme)
+		function := base.DeepCopy()
+		g.Expect(r.reconcileRuntimeIdentity(ctx, function)).To(Succeed())
+
+		function.Spec.Deploy.Permissions = nil
+		g.Expect(r.reconcileRuntimeIdentity(ctx, function)).To(Succeed())
+
+		g.Expect(errors.IsNotFound(r.Get(ctx, key, &rbacv1.Role{}))).To(BeTrue())
+		g.Expect(errors.IsNotFound(r.Get(ctx, key, &rbacv1.RoleBinding{}))).To(BeTrue())
+		g.Expect(r.Get(ctx, key, &v1.ServiceAccount{})).To(Succeed())
+	})
+
+	t.Run("a Role owned by someone else is left alone", func(t *testing.T) {
+		g := NewWithT(t)
+		foreign := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "reports-runtime", Namespace: "default"}}
+		r := newFakeReconciler(addToScheme, foreign)
+		function := base.DeepCopy()
+		function.Spec.Deploy.Permissions = nil
+
+		g.Expect(r.reconcileRuntimeIdentity(ctx, function)).To(Succeed())
+		g.Expect(r.Get(ctx, key, &rbacv1.Role{})).To(Succeed())
+	})
+
+	conflicts := []struct {
+		name    string
+		foreign client.Object
+		wantErr string
+	}{
+		{
+			name:    "ServiceAccount",
+			foreign: &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "reports-runtime", Namespace: "default"}},
+			wantErr: "O ServiceAccount reports-runtime já existe no namespace default e não pertence a esta função",
+		},
+		{
+			name:    "Role",
+			foreign: &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "reports-runtime", Namespace: "default"}},
+			wantErr: "O Role reports-runtime já existe",
+		},
+		{
+			name: "RoleBinding",
+			foreign: &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "reports-runtime", Namespace: "default"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+			},
+			wantErr: "O RoleBinding reports-runtime já existe",
+		},
+	}
+	for _, tt := range conflicts {
+		t.Run("a "+tt.name+" owned by someone else is not adopted", func(t *testing.T) {
+			g := NewWithT(t)
+			r := newFakeReconciler(addToScheme, tt.foreign)
+			function := base.DeepCopy()
+
+			err := r.reconcileRuntimeIdentity(ctx, function)
+			var conflict *runtimeIdentityConflictError
+			g.Expect(stderrors.As(err, &conflict)).To(BeTrue())
+			g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+
+			current := tt.foreign.DeepCopyObject().(client.Object)
+			g.Expect(r.Get(ctx, key, current)).To(Succeed())
+			g.Expect(current.GetOwnerReferences()).To(BeEmpty())
+			g.Expect(current.GetLabels()).To(BeEmpty())
+		})
+	}
+}
+
+func TestRuntimeServiceAccountDrift(t *testing.T) {
+	g := NewWithT(t)
+	r := &FunctionReconciler{}
+	desired := r.buildKnativeService(&functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "reports", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{
+			ServiceAccount: &functionsv1alpha1.RuntimeServiceAccountSpec{},
+		}},
+	})
+
+	// Knative Services criados antes do ServiceAccount de runtime rodavam como 'default'
+	current := desired.DeepCopy()
+	current.Spec.Template.Spec.ServiceAccountName = ""
+	g.Expect(podSettingsChanged(current, desired)).To(BeTrue())
+	g.Expect(podSettingsChanged(desired.DeepCopy(), desired)).To(BeFalse())
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3ArchiveSuffixes are the archive formats the source-fetch Task can unpack
+var s3ArchiveSuffixes = []string{".tar.gz", ".tgz", ".tar"}
+
+// s3Object describes the object version a build is based on.
+type s3Object struct {
+	Key          string
+	ETag         string
+	VersionID    string
+	LastModified time.Time
+}
+
+// s3Client is a minimal client for S3-compatible object storage, covering the two read-only calls
+// the controller needs to detect new source archives: HeadObject and ListObjectsV2.
+// Requests use path-style addressing, which works with AWS S3 and MinIO alike.
+type s3Client struct {
+	endpoint        string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	httpClient      *http.Client
+	now             func() time.Time
+}
+
+// newS3Client creates a client for the given endpoint. An empty endpoint means AWS S3 for the region.
+// Without credentials, requests are sent unsigned (anonymous access).
+func newS3Client(endpoint, region, accessKeyID, secretAccessKey string) *s3Client {
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	return &s3Client{
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		region:          region,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		now:             time.Now,
+	}
+}
+
+/*
+validateS3Endpoint aceita apenas esquema http(s), host e porta, e só endpoints liberados pelo operator
+('--allowed-s3-endpoints'): o controller consulta o endpoint com a própria identidade de rede, então
+uma Function não pode apontá-lo para serviços internos, o loopback ou o serviço de metadados da nuvem.
+Sem endpoint, a fonte usa a AWS S3 da região.
+*/
+func validateS3Endpoint(endpoint string, allowed []string) error {
+	if endpoint == "" {
+		return nil
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("o endpoint %q precisa usar http ou https", endpoint)
+	}
+	if parsed.Host == "" || parsed.User != nil || strings.Trim(parsed.Path, "/") != "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return fmt.Errorf("o endpoint %q deve conter apenas esquema, host e porta", endpoint)
+	}
+	for _, entry := range allowed {
+		candidate, err := url.Parse(strings.TrimSpace(entry))
+		if err == nil && strings.EqualFold(candidate.Scheme, parsed.Scheme) && strings.EqualFold(candidate.Host, parsed.Host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("o endpoint %q não está entre os endpoints S3 permitidos pelo operator", endpoint)
+}
+
+// headObject returns the current version of an object.
+func (c *s3Client) headObject(ctx context.Context, bucket, key string) (*s3Object, error) {
+	resp, err := c.do(ctx, http.MethodHead, "/"+bucket+"/"+key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HEAD s3://%s/%s: %s", bucket, key, resp.Status)
+	}
+
+	object := &s3Object{
+		Key:       key,
+		ETag:      strings.Trim(resp.Header.Get("ETag"), `"`),
+		VersionID: resp.Header.Get("x-amz-version-id"),
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		object.LastModified = lastModified
+	}
+	return object, nil
+}
+
+// listObjectsV2Result is the subset of the ListObjectsV2 response used by the controller.
+type listObjectsV2Result struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		ETag         string    `xml:"ETag"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// latestArchive returns the most recently modified archive (.tar, .tar.gz, .tgz) under a prefix.
+// Ties are broken by key so the choice is stable.
+func (c *s3Client) latestArchive(ctx context.Context, bucket, prefix string) (*s3Object, error) {
+	var latest *s3Object
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, "/"+bucket, query)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close() //nolint:errcheck
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("LIST s3://%s/%s: %s", bucket, prefix, resp.Status)
+		}
+
+		var result listObjectsV2Result
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("LIST s3://%s/%s: resposta inválida: %w", bucket, prefix, err)
+		}
+
+		for _, content := range result.Contents {
+			if !isSourceArchive(content.Key) {
+				continue
+			}
+			if latest == nil || content.LastModified.After(latest.LastModified) ||
+				(content.LastModified.Equal(latest.LastModified) && content.Key > latest.Key) {
+				latest = &s3Object{
+					Key:          content.Key,
+					ETag:         strings.Trim(content.ETag, `"`),
+					LastModified: content.LastModified,
+				}
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		continuationToken = result.NextContinuationToken
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("nenhum arquivo .tar, .tar.gz ou .tgz encontrado em s3://%s/%s", bucket, prefix)
+	}
+	return latest, nil
+}
+
+// isSourceArchive reports whether the key has an archive extension supported by the source-fetch Task.
+func isSourceArchive(key string) bool {
+	for _, suffix := range s3ArchiveSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// do sends a request with an empty body, signing it when credentials are configured.
+func (c *s3Client) do(ctx context.Context, method, path string, query url.Values) (*http.Response, error) {
+	endpoint, err := url.Parse(c.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint S3 inválido %q: %w", c.endpoint, err)
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + path
+	endpoint.RawPath = s3EscapePath(endpoint.Path)
+	endpoint.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.accessKeyID != "" {
+		req.Header.Set("x-amz-content-sha256", emptyPayloadHash)
+		signV4(req, "s3", c.region, c.accessKeyID, c.secretAccessKey, emptyPayloadHash, c.now())
+	}
+	return c.httpClient.Do(req)
+}
+
+/*
+signV4 assina a requisição com AWS Signature Version 4 (cabeçalho Authorization).
+Assina o cabeçalho 'host' e todos os cabeçalhos 'x-amz-*' presentes.
+*/
+func signV4(req *http.Request, service, region, accessKeyID, secretAccessKey, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes a string as required by SigV4 (RFC 3986 unreserved characters are kept).
+func s3Escape(value string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// s3EscapePath encodes each segment of a path, keeping the "/" separators.
+func s3EscapePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3CanonicalQuery encodes query parameters sorted by name, as required by SigV4.
+func s3CanonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(name)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
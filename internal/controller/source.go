@@ -0,0 +1,455 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+const (
+	// SourceRevisionAnnotation records on the PipelineRun which revision of a non-git source
+	// (or which pinned Git commit) it built.
+	// When the source changes, the finished PipelineRun is replaced by a new build.
+	SourceRevisionAnnotation = "functions.zenith.com/source-revision"
+
+	// Source modes understood by the source-fetch Task
+	sourceModeInline = "inline"
+	sourceModeOCI    = "oci"
+	sourceModeS3     = "s3"
+
+	// inlineSourceWorkspaceName is the pipeline workspace bound to the ConfigMap holding inline files
+	inlineSourceWorkspaceName = "inline-source"
+	// s3CredentialsWorkspaceName is the pipeline workspace bound to the Secret holding S3 credentials
+	s3CredentialsWorkspaceName = "s3-credentials"
+
+	// defaultS3PollInterval is how often an S3 source is checked for a new object version
+	defaultS3PollInterval = 5 * time.Minute
+	// minS3PollInterval bounds spec.source.s3.pollInterval, so a Function cannot flood the endpoint
+	minS3PollInterval = 30 * time.Second
+)
+
+// gitCommitPattern matches a full Git commit SHA
+var gitCommitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ociDigestPattern matches an OCI reference pinned by digest
+var ociDigestPattern = regexp.MustCompile(`^[^@\s]+@sha256:[0-9a-f]{64}$`)
+
+// resolvedSource is the current state of a non-git source, as seen by the reconciler.
+type resolvedSource struct {
+	// Revision identifies the source content; a change triggers a new build
+	Revision string
+	// S3Key is the object key to fetch, resolved from spec.source.s3.prefix when needed
+	S3Key string
+}
+
+// inlineSourceConfigMapName returns the name of the operator-owned ConfigMap holding spec.source.inline.files.
+func inlineSourceConfigMapName(function *functionsv1alpha1.Function) string {
+	return function.Name + "-source"
+}
+
+/*
+inlineSourceItems converte 'spec.source.inline.files' no conteúdo do ConfigMap e nos itens do volume.
+Chaves de ConfigMap não aceitam "/", então cada arquivo recebe uma chave sintética
+("file-000", "file-001", ...) em ordem de caminho, e o item do volume o projeta no caminho original.
+*/
+func inlineSourceItems(files map[string]string) (map[string]string, []v1.KeyToPath) {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	data := make(map[string]string, len(paths))
+	items := make([]v1.KeyToPath, 0, len(paths))
+	for i, path := range paths {
+		key := fmt.Sprintf("file-%03d", i)
+		data[key] = files[path]
+		items = append(items, v1.KeyToPath{Key: key, Path: path})
+	}
+	return data, items
+}
+
+// hashSourceFiles returns a stable digest of a set of files (path -> content).
+func hashSourceFiles(files map[string]string, binaryFiles map[string][]byte) string {
+	paths := make([]string, 0, len(files)+len(binaryFiles))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	for path := range binaryFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, path := range paths {
+		hash.Write([]byte(path))
+		hash.Write([]byte{0})
+		if content, ok := files[path]; ok {
+			hash.Write([]byte(content))
+		} else {
+			hash.Write(binaryFiles[path])
+		}
+		hash.Write([]byte{0})
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+/*
+reconcileSource prepara fontes alternativas ao Git ('spec.source') antes do build:
+  - sincroniza o ConfigMap '<function>-source' com 'spec.source.inline.files';
+  - verifica o ConfigMap referenciado por 'spec.source.inline.configMapRef';
+  - consulta a versão atual (ETag) do objeto em 'spec.source.s3'.
+
+Retorna a revisão da fonte, usada para detectar mudanças e disparar um novo build.
+Para Functions baseadas em Git, retorna o commit de 'gitRevision' quando ele é um SHA completo
+(ex: os previews de pull requests); branches e tags resultam em uma revisão vazia.
+*/
+func (r *FunctionReconciler) reconcileSource(ctx context.Context, function *functionsv1alpha1.Function) (resolvedSource, ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	source := function.Spec.Source
+	if source == nil || source.S3 == nil {
+		// 'status.source' só registra consultas a fontes remotas; é removido com a próxima atualização de status
+		function.Status.Source = nil
+	}
+	if source == nil {
+		if gitCommitPattern.MatchString(function.Spec.GitRevision) {
+			return resolvedSource{Revision: function.Spec.GitRevision}, ctrl.Result{}, nil
+		}
+		return resolvedSource{}, ctrl.Result{}, nil
+	}
+
+	switch {
+	case source.Inline != nil && source.Inline.Files != nil:
+		data, _ := inlineSourceItems(source.Inline.Files)
+		configMap := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      inlineSourceConfigMapName(function),
+				Namespace: function.Namespace,
+			},
+		}
+		op, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+			if configMap.Labels == nil {
+				configMap.Labels = map[string]string{}
+			}
+			configMap.Labels["functions.zenith.com/managed-by"] = "zenith-operator"
+			if !equality.Semantic.DeepEqual(configMap.Data, data) {
+				configMap.Data = data
+			}
+			return controllerutil.SetControllerReference(function, configMap, r.Scheme)
+		})
+		if err != nil {
+			logger.Error(err, "Falha ao sincronizar ConfigMap do código-fonte inline")
+			return resolvedSource{}, ctrl.Result{}, err
+		}
+		if op != controllerutil.OperationResultNone {
+			logger.Info("ConfigMap do código-fonte inline sincronizado", "ConfigMap.Name", configMap.Name, "Operation", op)
+		}
+		return resolvedSource{Revision: hashSourceFiles(source.Inline.Files, nil)}, ctrl.Result{}, nil
+
+	case source.Inline != nil && source.Inline.ConfigMapRef != nil:
+		configMap := &v1.ConfigMap{}
+		err := r.Get(ctx, types.NamespacedName{Name: source.Inline.ConfigMapRef.Name, Namespace: function.Namespace}, configMap)
+		if errors.IsNotFound(err) {
+			logger.Error(err, "ConfigMap do código-fonte não encontrado", "ConfigMap.Name", source.Inline.ConfigMapRef.Name)
+			result, err := r.setSourceCondition(ctx, function, "SourceNotFound",
+				fmt.Sprintf("ConfigMap do código-fonte não encontrado: %s", source.Inline.ConfigMapRef.Name), 30*time.Second)
+			return resolvedSource{}, result, err
+		} else if err != nil {
+			logger.Error(err, "Falha ao verificar ConfigMap do código-fonte")
+			return resolvedSource{}, ctrl.Result{}, err
+		}
+		return resolvedSource{Revision: hashSourceFiles(configMap.Data, configMap.BinaryData)}, ctrl.Result{}, nil
+
+	case source.OCI != nil:
+		// Functions criadas antes da validação do CRD podem ter uma tag: sem digest, um novo push não seria detectado.
+		// O requeue interrompe a reconciliação antes do build; a correção do spec gera nova reconciliação antes disso
+		if !ociDigestPattern.MatchString(source.OCI.Image) {
+			result, err := r.setSourceCondition(ctx, function, "InvalidSource",
+				fmt.Sprintf("spec.source.oci.image: a referência %q precisa ser fixada por digest (@sha256:...)", source.OCI.Image), 5*time.Minute)
+			return resolvedSource{}, result, err
+		}
+		// O digest identifica o conteúdo: um novo artefato exige uma nova referência no spec
+		return resolvedSource{Revision: source.OCI.Image}, ctrl.Result{}, nil
+
+	case source.S3 != nil:
+		return r.reconcileS3Source(ctx, function)
+	}
+
+	return resolvedSource{}, ctrl.Result{}, nil
+}
+
+/*
+reconcileS3Source consulta o bucket para descobrir o objeto a construir e sua versão.
+Com 'key', usa HeadObject; com 'prefix', usa o arquivo modificado mais recentemente sob o prefixo.
+A revisão combina chave, ETag e (se o bucket for versionado) o versionId, de modo que
+um novo upload do mesmo arquivo dispare um novo build.
+O bucket é consultado no máximo uma vez por 'pollInterval': entre as consultas, reconciliações
+disparadas por outros eventos reaproveitam a revisão de 'status.source'.
+*/
+func (r *FunctionReconciler) reconcileS3Source(ctx context.Context, function *functionsv1alpha1.Function) (resolvedSource, ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+	s3Source := function.Spec.Source.S3
+
+	if err := validateS3Endpoint(s3Source.Endpoint, r.AllowedS3Endpoints); err != nil {
+		// O requeue interrompe a reconciliação antes do build e mantém a verificação no ritmo do pollInterval;
+		// corrigir o spec ou as flags do operator gera uma nova reconciliação antes disso
+		result, err := r.setSourceCondition(ctx, function, "InvalidSource", err.Error(), s3PollInterval(function))
+		return resolvedSource{}, result, err
+	}
+
+	now := time.Now()
+	if !sourceCheckDue(function, s3PollInterval(function), now) {
+		return resolvedSource{Revision: function.Status.Source.Revision, S3Key: function.Status.Source.S3Key}, ctrl.Result{}, nil
+	}
+
+	var accessKeyID, secretAccessKey string
+	if s3Source.CredentialsSecretRef != nil {
+		secret := &v1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: s3Source.CredentialsSecretRef.Name, Namespace: function.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			logger.Error(err, "Secret de credenciais S3 não encontrado", "Secret.Name", s3Source.CredentialsSecretRef.Name)
+			result, err := r.setSourceCondition(ctx, function, "SourceNotFound",
+				fmt.Sprintf("Secret de credenciais S3 não encontrado: %s", s3Source.CredentialsSecretRef.Name), 30*time.Second)
+			return resolvedSource{}, result, err
+		} else if err != nil {
+			logger.Error(err, "Falha ao buscar Secret de credenciais S3")
+			return resolvedSource{}, ctrl.Result{}, err
+		}
+		accessKeyID = string(secret.Data["AWS_ACCESS_KEY_ID"])
+		secretAccessKey = string(secret.Data["AWS_SECRET_ACCESS_KEY"])
+	}
+
+	object, err := resolveS3Object(ctx, newS3Client(s3Source.Endpoint, s3Source.Region, accessKeyID, secretAccessKey), s3Source)
+	if err != nil {
+		logger.Error(err, "Falha ao consultar o código-fonte no S3", "Bucket", s3Source.Bucket)
+		result, err := r.setSourceCondition(ctx, function, "SourceUnavailable",
+			fmt.Sprintf("Falha ao consultar o código-fonte no S3: %v", err), time.Minute)
+		return resolvedSource{}, result, err
+	}
+
+	resolved := resolvedSource{Revision: s3Revision(s3Source.Bucket, object), S3Key: object.Key}
+	function.Status.Source = &functionsv1alpha1.SourceStatus{
+		Revision:           resolved.Revision,
+		S3Key:              resolved.S3Key,
+		LastCheckedTime:    &metav1.Time{Time: now},
+		ObservedGeneration: function.Generation,
+	}
+	if err := r.Status().Update(ctx, function); err != nil {
+		logger.Error(err, "Falha ao registrar a consulta ao código-fonte no S3")
+		return resolvedSource{}, ctrl.Result{}, err
+	}
+	return resolved, ctrl.Result{}, nil
+}
+
+// sourceCheckDue reports whether the remote source must be queried: on the first check, after a spec change
+// or once the poll interval has passed since the last successful check.
+func sourceCheckDue(function *functionsv1alpha1.Function, interval time.Duration, now time.Time) bool {
+	checked := function.Status.Source
+	return checked == nil || checked.LastCheckedTime == nil || checked.ObservedGeneration != function.Generation ||
+		!now.Before(checked.LastCheckedTime.Add(interval))
+}
+
+// sourcePollRequeueAfter returns the delay until the next check of the S3 source of a Function, or 0 without one.
+func sourcePollRequeueAfter(function *functionsv1alpha1.Function, now time.Time) time.Duration {
+	interval := s3PollInterval(function)
+	if interval == 0 {
+		return 0
+	}
+	if checked := function.Status.Source; checked != nil && checked.LastCheckedTime != nil {
+		return max(checked.LastCheckedTime.Add(interval).Sub(now), time.Second)
+	}
+	return interval
+}
+
+// resolveS3Object returns the object a Function builds from: the configured key, or the latest archive under the prefix.
+func resolveS3Object(ctx context.Context, client *s3Client, s3Source *functionsv1alpha1.S3Source) (*s3Object, error) {
+	if s3Source.Key != "" {
+		return client.headObject(ctx, s3Source.Bucket, s3Source.Key)
+	}
+	return client.latestArchive(ctx, s3Source.Bucket, s3Source.Prefix)
+}
+
+// s3Revision formats the source revision of an S3 object, e.g. "s3://bucket/app.tar.gz#etag".
+func s3Revision(bucket string, object *s3Object) string {
+	revision := fmt.Sprintf("s3://%s/%s#%s", bucket, object.Key, object.ETag)
+	if object.VersionID != "" {
+		revision += "?versionId=" + object.VersionID
+	}
+	return revision
+}
+
+// s3PollInterval returns how often the S3 source of a Function is checked for changes.
+func s3PollInterval(function *functionsv1alpha1.Function) time.Duration {
+	if function.Spec.Source == nil || function.Spec.Source.S3 == nil {
+		return 0
+	}
+	if interval := function.Spec.Source.S3.PollInterval; interval != nil && interval.Duration > 0 {
+		return max(interval.Duration, minS3PollInterval)
+	}
+	return defaultS3PollInterval
+}
+
+// setSourceCondition reports a source problem in the Ready condition and requeues after the given delay.
+func (r *FunctionReconciler) setSourceCondition(ctx context.Context, function *functionsv1alpha1.Function, reason, message string, requeueAfter time.Duration) (ctrl.Result, error) {
+	condition := metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}
+	meta.SetStatusCondition(&function.Status.Conditions, condition)
+	function.Status.ObservedGeneration = function.Generation
+	if err := r.Status().Update(ctx, function); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+/*
+sourceFetchPipelineTask retorna a task 'fetch-source' do pipeline embutido para a fonte da Function,
+junto com os workspaces adicionais que o pipeline precisa declarar e vincular.
+Para Git usa a Task 'git-clone'; para fontes inline, OCI e S3 usa a Task 'zenith-source-fetch'.
+Em todos os casos o código termina no workspace compartilhado e a task expõe o resultado 'commit'.
+*/
+func (r *FunctionReconciler) sourceFetchPipelineTask(function *functionsv1alpha1.Function, gitRevision, sharedWorkspaceName string) (tektonv1.PipelineTask, []tektonv1.PipelineWorkspaceDeclaration, []tektonv1.WorkspaceBinding) {
+	stringParam := func(name, value string) tektonv1.Param {
+		return tektonv1.Param{Name: name, Value: tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: value}}
+	}
+
+	source := function.Spec.Source
+	if source == nil {
+		return tektonv1.PipelineTask{
+			Name: "fetch-source",
+			TaskRef: &tektonv1.TaskRef{
+				Name: GitCloneTaskName,
+			},
+			Workspaces: []tektonv1.WorkspacePipelineTaskBinding{
+				{Name: "output", Workspace: sharedWorkspaceName},
+			},
+			Params: []tektonv1.Param{
+				stringParam("url", function.Spec.GitRepo),
+				stringParam("revision", gitRevision),
+			},
+		}, nil, nil
+	}
+
+	task := tektonv1.PipelineTask{
+		Name:    "fetch-source",
+		TaskRef: &tektonv1.TaskRef{Name: SourceFetchTaskName},
+		Workspaces: []tektonv1.WorkspacePipelineTaskBinding{
+			{Name: "output", Workspace: sharedWorkspaceName},
+		},
+	}
+
+	if source.OCI != nil {
+		plainHTTP := "false"
+		if r.detectInsecureRegistries(source.OCI.Image) != "" {
+			plainHTTP = "true"
+		}
+		task.Params = []tektonv1.Param{
+			stringParam("mode", sourceModeOCI),
+			stringParam("oci-image", source.OCI.Image),
+			stringParam("oci-plain-http", plainHTTP),
+		}
+		return task, nil, nil
+	}
+
+	if source.S3 != nil {
+		// O cliente usado pelo controller aplica os mesmos padrões de endpoint e região
+		s3 := newS3Client(source.S3.Endpoint, source.S3.Region, "", "")
+		task.Params = []tektonv1.Param{
+			stringParam("mode", sourceModeS3),
+			stringParam("s3-endpoint", s3.endpoint),
+			stringParam("s3-region", s3.region),
+			stringParam("s3-bucket", source.S3.Bucket),
+			stringParam("s3-key", source.S3.Key),
+			stringParam("s3-path", s3EscapePath("/"+source.S3.Bucket+"/"+source.S3.Key)),
+		}
+		if source.S3.CredentialsSecretRef == nil {
+			return task, nil, nil
+		}
+		task.Workspaces = append(task.Workspaces, tektonv1.WorkspacePipelineTaskBinding{
+			Name:      s3CredentialsWorkspaceName,
+			Workspace: s3CredentialsWorkspaceName,
+		})
+		return task,
+			[]tektonv1.PipelineWorkspaceDeclaration{{Name: s3CredentialsWorkspaceName}},
+			[]tektonv1.WorkspaceBinding{{Name: s3CredentialsWorkspaceName, Secret: &v1.SecretVolumeSource{SecretName: source.S3.CredentialsSecretRef.Name}}}
+	}
+
+	// Fonte inline: o ConfigMap é montado como workspace e copiado para o workspace compartilhado
+	configMapSource := &v1.ConfigMapVolumeSource{}
+	if source.Inline != nil && source.Inline.ConfigMapRef != nil {
+		configMapSource.Name = source.Inline.ConfigMapRef.Name
+	} else if source.Inline != nil {
+		configMapSource.Name = inlineSourceConfigMapName(function)
+		_, configMapSource.Items = inlineSourceItems(source.Inline.Files)
+	}
+
+	task.Params = []tektonv1.Param{stringParam("mode", sourceModeInline)}
+	task.Workspaces = append(task.Workspaces, tektonv1.WorkspacePipelineTaskBinding{
+		Name:      "inline",
+		Workspace: inlineSourceWorkspaceName,
+	})
+	return task,
+		[]tektonv1.PipelineWorkspaceDeclaration{{Name: inlineSourceWorkspaceName}},
+		[]tektonv1.WorkspaceBinding{{Name: inlineSourceWorkspaceName, ConfigMap: configMapSource}}
+}
+
+// functionsForSourceConfigMap maps a ConfigMap to the Functions using it through spec.source.inline.configMapRef,
+// so that editing the ConfigMap triggers a rebuild.
+func (r *FunctionReconciler) functionsForSourceConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	var functions functionsv1alpha1.FunctionList
+	if err := r.List(ctx, &functions, client.InNamespace(obj.GetNamespace())); err != nil {
+		logf.FromContext(ctx).Error(err, "Falha ao listar Functions para o ConfigMap", "ConfigMap.Name", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, function := range functions.Items {
+		source := function.Spec.Source
+		if source != nil && source.Inline != nil && source.Inline.ConfigMapRef != nil &&
+			source.Inline.ConfigMapRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: function.Name, Namespace: function.Namespace},
+			})
+		}
+	}
+	return requests
+}
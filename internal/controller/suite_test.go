@@ -30,6 +30,7 @@ import (
 	"k8s.io/client-go/rest"
 	kneventingv1 "knative.dev/eventing/pkg/apis/eventing/v1"
 	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	knservingv1beta1 "knative.dev/serving/pkg/apis/serving/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -71,6 +72,9 @@ var _ = BeforeSuite(func() {
 	err = knservingv1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = knservingv1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	err = kneventingv1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
@@ -33,6 +33,8 @@ const (
 	GitCloneTaskName = "git-clone"
 	// BuildpacksPhasesTaskName is the name of the buildpacks-phases Task
 	BuildpacksPhasesTaskName = "buildpacks-phases"
+	// SourceFetchTaskName is the name of the Task materializing inline and OCI sources
+	SourceFetchTaskName = "zenith-source-fetch"
 	// TaskVersionLabel is the label key for the task version
 	TaskVersionLabel = "app.kubernetes.io/version"
 	// ManagedByLabel is the label key for managed-by
@@ -58,6 +60,12 @@ func (r *FunctionReconciler) ensureTektonTasks(ctx context.Context, namespace st
 		return err
 	}
 
+	// Ensure zenith-source-fetch Task exists
+	if err := r.ensureSourceFetchTask(ctx, namespace); err != nil {
+		logger.Error(err, "Failed to ensure zenith-source-fetch Task", "namespace", namespace)
+		return err
+	}
+
 	logger.Info("Tekton Tasks ensured successfully", "namespace", namespace)
 	return nil
 }
@@ -105,7 +113,7 @@ func (r *FunctionReconciler) ensureBuildpacksPhasesTask(ctx context.Context, nam
 		// Task already exists, check if it's managed by us
 		if existingTask.Labels[ManagedByLabel] == ManagedByValue {
 			logger.V(1).Info("buildpacks-phases Task already exists and is managed by operator", "namespace", namespace)
-			return nil
+			return r.upgradeManagedTask(ctx, existingTask, r.buildBuildpacksPhasesTask(namespace))
 		}
 		// Task exists but not managed by us, don't overwrite
 		logger.Info("buildpacks-phases Task exists but not managed by operator, skipping", "namespace", namespace)
@@ -126,6 +134,57 @@ func (r *FunctionReconciler) ensureBuildpacksPhasesTask(ctx context.Context, nam
 	return nil
 }
 
+// ensureSourceFetchTask ensures the zenith-source-fetch Task exists in the namespace
+func (r *FunctionReconciler) ensureSourceFetchTask(ctx context.Context, namespace string) error {
+	logger := log.FromContext(ctx)
+
+	// Check if Task already exists
+	existingTask := &tektonv1.Task{}
+	err := r.Get(ctx, types.NamespacedName{Name: SourceFetchTaskName, Namespace: namespace}, existingTask)
+	if err == nil {
+		// Task already exists, check if it's managed by us
+		if existingTask.Labels[ManagedByLabel] == ManagedByValue {
+			logger.V(1).Info("zenith-source-fetch Task already exists and is managed by operator", "namespace", namespace)
+			return r.upgradeManagedTask(ctx, existingTask, r.buildSourceFetchTask(namespace))
+		}
+		// Task exists but not managed by us, don't overwrite
+		logger.Info("zenith-source-fetch Task exists but not managed by operator, skipping", "namespace", namespace)
+		return nil
+	}
+
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	// Create the Task
+	task := r.buildSourceFetchTask(namespace)
+	if err := r.Create(ctx, task); err != nil {
+		return err
+	}
+
+	logger.Info("Created zenith-source-fetch Task", "namespace", namespace)
+	return nil
+}
+
+// upgradeManagedTask replaces an operator-managed Task created by a previous operator release
+// (older version label) with the current definition.
+func (r *FunctionReconciler) upgradeManagedTask(ctx context.Context, existingTask, desired *tektonv1.Task) error {
+	if existingTask.Labels[TaskVersionLabel] == desired.Labels[TaskVersionLabel] {
+		return nil
+	}
+
+	existingTask.Labels = desired.Labels
+	existingTask.Annotations = desired.Annotations
+	existingTask.Spec = desired.Spec
+	if err := r.Update(ctx, existingTask); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("Updated managed Task", "task", existingTask.Name, "namespace", existingTask.Namespace,
+		"version", desired.Labels[TaskVersionLabel])
+	return nil
+}
+
 // buildGitCloneTask builds the git-clone Task definition
 func (r *FunctionReconciler) buildGitCloneTask(namespace string) *tektonv1.Task {
 	return &tektonv1.Task{
@@ -299,7 +358,7 @@ func (r *FunctionReconciler) buildBuildpacksPhasesTask(namespace string) *tekton
 			Name:      BuildpacksPhasesTaskName,
 			Namespace: namespace,
 			Labels: map[string]string{
-				TaskVersionLabel: "0.4",
+				TaskVersionLabel: "0.5",
 				ManagedByLabel:   ManagedByValue,
 			},
 			Annotations: map[string]string{
@@ -345,6 +404,7 @@ This task supports the Platform spec 0.13: https://github.com/buildpacks/spec/bl
 			},
 			Results: []tektonv1.TaskResult{
 				{Name: "APP_IMAGE_DIGEST", Description: "The digest of the built `APP_IMAGE`."},
+				{Name: BuildMetadataResult, Description: "JSON document with the detected buildpacks (id, version) and the exported process types."},
 			},
 			StepTemplate: &tektonv1.StepTemplate{
 				Env: []corev1.EnvVar{
@@ -569,6 +629,82 @@ func buildBuildpacksSteps() []tektonv1.Step {
 	}
 }
 
+// buildSourceFetchTask builds the zenith-source-fetch Task definition.
+// It materializes non-git sources (inline files, OCI artifacts) into the "output" workspace,
+// running only the step that matches the "mode" param.
+func (r *FunctionReconciler) buildSourceFetchTask(namespace string) *tektonv1.Task {
+	return &tektonv1.Task{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SourceFetchTaskName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				TaskVersionLabel: "0.3",
+				ManagedByLabel:   ManagedByValue,
+			},
+		},
+		Spec: tektonv1.TaskSpec{
+			Description: "Materializes a Function source that does not come from git (inline files, an OCI artifact or an S3 object) into the output workspace.",
+			Workspaces: []tektonv1.WorkspaceDeclaration{
+				{Name: "output", Description: "The source is written to the volume backing this Workspace."},
+				{Name: "inline", Optional: true, Description: "ConfigMap holding the inline source files (mode 'inline')."},
+				{Name: "s3-credentials", Optional: true, Description: "Secret with AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (mode 's3'). Requests are anonymous when not bound."},
+			},
+			Params: tektonv1.ParamSpecs{
+				{Name: "mode", Type: tektonv1.ParamTypeString, Description: "Source kind: 'inline', 'oci' or 's3'."},
+				{Name: "oci-image", Type: tektonv1.ParamTypeString, Description: "Reference of the OCI artifact holding the source tarball (mode 'oci').", Default: &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: ""}},
+				{Name: "oci-plain-http", Type: tektonv1.ParamTypeString, Description: "Pull the OCI artifact over plain HTTP.", Default: &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: "false"}},
+				{Name: "s3-endpoint", Type: tektonv1.ParamTypeString, Description: "S3 endpoint URL (mode 's3').", Default: &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: ""}},
+				{Name: "s3-region", Type: tektonv1.ParamTypeString, Description: "S3 region used to sign requests (mode 's3').", Default: &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: "us-east-1"}},
+				{Name: "s3-bucket", Type: tektonv1.ParamTypeString, Description: "Bucket holding the source archive (mode 's3').", Default: &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: ""}},
+				{Name: "s3-key", Type: tektonv1.ParamTypeString, Description: "Key of the source archive, a .tar, .tar.gz or .tgz file (mode 's3').", Default: &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: ""}},
+				{Name: "s3-path", Type: tektonv1.ParamTypeString, Description: "Percent-encoded request path of the archive, /<bucket>/<key> (mode 's3').", Default: &tektonv1.ParamValue{Type: tektonv1.ParamTypeString, StringVal: ""}},
+			},
+			Results: []tektonv1.TaskResult{
+				{Name: "commit", Description: "Identifier of the fetched source (content digest for inline sources, artifact digest for OCI, object ETag for S3)."},
+			},
+			Steps: []tektonv1.Step{
+				{
+					Name:   "inline",
+					Image:  "docker.io/library/busybox:1.36",
+					Script: inlineSourceScript,
+					When: tektonv1.StepWhenExpressions{
+						{Input: "$(params.mode)", Operator: selection.In, Values: []string{sourceModeInline}},
+					},
+				},
+				{
+					Name:   "oci",
+					Image:  "ghcr.io/oras-project/oras:v1.2.0",
+					Script: ociSourceScript,
+					Env: []corev1.EnvVar{
+						{Name: "PARAM_OCI_IMAGE", Value: "$(params.oci-image)"},
+						{Name: "PARAM_PLAIN_HTTP", Value: "$(params.oci-plain-http)"},
+					},
+					When: tektonv1.StepWhenExpressions{
+						{Input: "$(params.mode)", Operator: selection.In, Values: []string{sourceModeOCI}},
+					},
+				},
+				{
+					Name:   "s3",
+					Image:  "docker.io/curlimages/curl:8.10.1",
+					Script: s3SourceScript,
+					Env: []corev1.EnvVar{
+						{Name: "PARAM_S3_ENDPOINT", Value: "$(params.s3-endpoint)"},
+						{Name: "PARAM_S3_REGION", Value: "$(params.s3-region)"},
+						{Name: "PARAM_S3_BUCKET", Value: "$(params.s3-bucket)"},
+						{Name: "PARAM_S3_KEY", Value: "$(params.s3-key)"},
+						{Name: "PARAM_S3_PATH", Value: "$(params.s3-path)"},
+						{Name: "CREDENTIALS_BOUND", Value: "$(workspaces.s3-credentials.bound)"},
+						{Name: "CREDENTIALS_PATH", Value: "$(workspaces.s3-credentials.path)"},
+					},
+					When: tektonv1.StepWhenExpressions{
+						{Input: "$(params.mode)", Operator: selection.In, Values: []string{sourceModeS3}},
+					},
+				},
+			},
+		},
+	}
+}
+
 // int64Ptr returns a pointer to an int64
 func int64Ptr(i int64) *int64 {
 	return &i
@@ -715,6 +851,7 @@ export BUILD_IMAGE=${CNB_BUILD_IMAGE:-${CNB_BUILDER_IMAGE}}
 // resultsScript is the script for the results step
 const resultsScript = `#!/usr/bin/env python3
 
+import json
 import tomllib
 
 def write_to_file(filename, content):
@@ -739,4 +876,112 @@ if None not in (image_id, manifest_size):
   print(f"image container id (when using daemon): {image_id}, manifest size: {manifest_size}")
 
 write_to_file('$(results.APP_IMAGE_DIGEST.path)',digest)
+
+# Build metadata: the buildpacks that took part in the build and the exported process types
+metadata = {"buildpacks": [], "processes": []}
+try:
+  with open("/layers/group.toml", "rb") as f:
+    for bp in tomllib.load(f).get("group", []):
+      metadata["buildpacks"].append({"id": bp.get("id", ""), "version": bp.get("version", "")})
+except (OSError, tomllib.TOMLDecodeError) as e:
+  print(f"Could not read group.toml: {e}")
+
+try:
+  with open("/layers/config/metadata.toml", "rb") as f:
+    metadata["processes"] = [p.get("type", "") for p in tomllib.load(f).get("processes", [])]
+except (OSError, tomllib.TOMLDecodeError) as e:
+  print(f"Could not read config/metadata.toml: {e}")
+
+print("#### Build metadata ####")
+print(metadata)
+
+# Task results share the 4KB termination message with APP_IMAGE_DIGEST: keep the payload small
+payload = json.dumps(metadata, separators=(",", ":"))
+if len(payload) > 2048:
+  payload = json.dumps({"buildpacks": metadata["buildpacks"][:20], "processes": metadata["processes"][:10]}, separators=(",", ":"))
+write_to_file('$(results.BUILD_METADATA.path)',payload)
+`
+
+// inlineSourceScript copies the files of the "inline" workspace (a ConfigMap volume) to the output workspace.
+// ConfigMap volumes keep the real files under hidden "..data" directories and expose them through
+// symlinks, so hidden ".." entries are skipped and symlinks are dereferenced.
+const inlineSourceScript = `#!/bin/sh
+set -eu
+
+SRC="$(workspaces.inline.path)"
+DEST="$(workspaces.output.path)"
+
+cd "${SRC}"
+for entry in * .[!.]*; do
+  [ -e "${entry}" ] || continue
+  cp -RL "${entry}" "${DEST}/"
+done
+
+cd "${DEST}"
+DIGEST=$(find . -type f | sort | xargs sha256sum | sha256sum | cut -d' ' -f1)
+printf "sha256:%s" "${DIGEST}" > "$(results.commit.path)"
+`
+
+// ociSourceScript pulls the OCI artifact and unpacks the source tarball(s) it contains into the output workspace.
+const ociSourceScript = `#!/bin/sh
+set -eu
+
+FLAGS=""
+if [ "${PARAM_PLAIN_HTTP}" = "true" ]; then
+  FLAGS="--plain-http"
+fi
+
+ARTIFACT_DIR=$(mktemp -d)
+oras pull ${FLAGS} -o "${ARTIFACT_DIR}" "${PARAM_OCI_IMAGE}"
+
+FOUND=""
+for f in "${ARTIFACT_DIR}"/*.tar.gz "${ARTIFACT_DIR}"/*.tgz "${ARTIFACT_DIR}"/*.tar; do
+  [ -f "${f}" ] || continue
+  case "${f}" in
+    *.tar) tar -xf "${f}" -C "$(workspaces.output.path)" ;;
+    *) tar -xzf "${f}" -C "$(workspaces.output.path)" ;;
+  esac
+  FOUND="yes"
+done
+
+if [ -z "${FOUND}" ]; then
+  echo "No .tar, .tar.gz or .tgz file found in artifact ${PARAM_OCI_IMAGE}"
+  exit 1
+fi
+
+DIGEST=$(oras resolve ${FLAGS} "${PARAM_OCI_IMAGE}")
+printf "%s" "${DIGEST}" > "$(results.commit.path)"
+`
+
+// s3SourceScript downloads the source archive from an S3-compatible endpoint (path-style, SigV4 signed
+// when credentials are bound) and unpacks it into the output workspace.
+const s3SourceScript = `#!/bin/sh
+set -eu
+
+# PARAM_S3_PATH is already percent-encoded by the operator (keys may contain spaces, '#', '?', ...)
+URL="${PARAM_S3_ENDPOINT%/}${PARAM_S3_PATH}"
+ARCHIVE=$(mktemp)
+HEADERS=$(mktemp)
+
+set -- --fail --silent --show-error --location --dump-header "${HEADERS}" --output "${ARCHIVE}"
+if [ "${CREDENTIALS_BOUND}" = "true" ]; then
+  ACCESS_KEY=$(cat "${CREDENTIALS_PATH}/AWS_ACCESS_KEY_ID")
+  SECRET_KEY=$(cat "${CREDENTIALS_PATH}/AWS_SECRET_ACCESS_KEY")
+  set -- "$@" --aws-sigv4 "aws:amz:${PARAM_S3_REGION}:s3" --user "${ACCESS_KEY}:${SECRET_KEY}"
+fi
+
+echo "Downloading s3://${PARAM_S3_BUCKET}/${PARAM_S3_KEY} from ${PARAM_S3_ENDPOINT}"
+curl "$@" "${URL}"
+
+case "${PARAM_S3_KEY}" in
+  *.tar) tar -xf "${ARCHIVE}" -C "$(workspaces.output.path)" ;;
+  *.tar.gz|*.tgz) tar -xzf "${ARCHIVE}" -C "$(workspaces.output.path)" ;;
+  *)
+    echo "Unsupported archive ${PARAM_S3_KEY}: expected a .tar, .tar.gz or .tgz file"
+    exit 1
+    ;;
+esac
+
+ETAG=$(grep -i '^etag:' "${HEADERS}" | tail -n 1 | cut -d' ' -f2- | tr -d '"\r')
+printf "%s" "${ETAG}" > "$(results.commit.path)"
 `
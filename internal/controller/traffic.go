@@ -0,0 +1,272 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// knativeServiceLabel is the label Knative sets on the Revisions of a Service
+const knativeServiceLabel = "serving.knative.dev/service"
+
+// revisionNotFoundError reports a traffic target whose image digest has no Knative Revision.
+type revisionNotFoundError struct {
+	digest string
+}
+
+func (e *revisionNotFoundError) Error() string {
+	return fmt.Sprintf("nenhuma revisão da função serve a imagem %s", e.digest)
+}
+
+/*
+validateTraffic verifica as regras de 'spec.deploy.traffic' e 'spec.deploy.tags' que a validação
+do CRD não expressa: os percentuais somam 100 e as tags são únicas em toda a rota, incluindo
+a tag "canary", reservada ao rollout.
+*/
+func validateTraffic(function *functionsv1alpha1.Function) error {
+	targets := function.Spec.Deploy.Traffic
+
+	var total int64
+	tags := map[string]bool{}
+	for _, target := range targets {
+		total += target.Percent
+		if target.Tag == "" {
+			continue
+		}
+		if tags[target.Tag] {
+			return fmt.Errorf("spec.deploy.traffic: tag %q duplicada", target.Tag)
+		}
+		tags[target.Tag] = true
+	}
+	if len(targets) > 0 && total != 100 {
+		return fmt.Errorf("spec.deploy.traffic: os percentuais somam %d, esperado 100", total)
+	}
+
+	for _, tag := range function.Spec.Deploy.Tags {
+		if tags[tag.Name] {
+			return fmt.Errorf("spec.deploy.tags: tag %q já usada em spec.deploy.traffic", tag.Name)
+		}
+		if function.Spec.Deploy.Rollout != nil && tag.Name == canaryTag {
+			return fmt.Errorf("spec.deploy.tags: a tag %q é reservada ao rollout", tag.Name)
+		}
+	}
+	return nil
+}
+
+// imageDigestOf returns the "sha256:..." part of an image reference, or "" if it is not pinned by digest.
+func imageDigestOf(image string) string {
+	if _, digest, found := strings.Cut(image, "@"); found {
+		return digest
+	}
+	if strings.HasPrefix(image, "sha256:") {
+		return image
+	}
+	return ""
+}
+
+/*
+indexRevisionsByDigest mapeia o digest da imagem de cada revisão para o nome da revisão.
+Quando várias revisões servem a mesma imagem (ex: só as variáveis de ambiente mudaram),
+vence a mais recente, pela 'generation' da configuração que a criou.
+*/
+func indexRevisionsByDigest(revisions []knservingv1.Revision) map[string]string {
+	index := map[string]string{}
+	generations := map[string]int64{}
+	for _, revision := range revisions {
+		if len(revision.Spec.Containers) == 0 {
+			continue
+		}
+		digest := imageDigestOf(revision.Spec.Containers[0].Image)
+		if digest == "" {
+			continue
+		}
+		generation := revisionConfigurationGeneration(&revision)
+		if name, found := index[digest]; found &&
+			(generations[digest] > generation || (generations[digest] == generation && name > revision.Name)) {
+			continue
+		}
+		index[digest] = revision.Name
+		generations[digest] = generation
+	}
+	return index
+}
+
+// revisionConfigurationGeneration returns the Configuration generation that created a Revision.
+func revisionConfigurationGeneration(revision *knservingv1.Revision) int64 {
+	generation, _ := strconv.ParseInt(revision.Labels["serving.knative.dev/configurationGeneration"], 10, 64)
+	return generation
+}
+
+// resolveTrafficTargets fills the revision name of targets that reference an image digest.
+func resolveTrafficTargets(targets []functionsv1alpha1.TrafficTarget, revisionsByDigest map[string]string) ([]functionsv1alpha1.TrafficTarget, error) {
+	resolved := make([]functionsv1alpha1.TrafficTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.ImageDigest != "" && target.RevisionName == "" {
+			digest := imageDigestOf(target.ImageDigest)
+			name, found := revisionsByDigest[digest]
+			if !found {
+				return nil, &revisionNotFoundError{digest: digest}
+			}
+			target.RevisionName = name
+		}
+		resolved = append(resolved, target)
+	}
+	return resolved, nil
+}
+
+// resolveRevisionTags fills the revision name of tags that reference an image digest.
+func resolveRevisionTags(tags []functionsv1alpha1.RevisionTag, revisionsByDigest map[string]string) ([]functionsv1alpha1.RevisionTag, error) {
+	resolved := make([]functionsv1alpha1.RevisionTag, 0, len(tags))
+	for _, tag := range tags {
+		if tag.ImageDigest != "" && tag.RevisionName == "" {
+			digest := imageDigestOf(tag.ImageDigest)
+			name, found := revisionsByDigest[digest]
+			if !found {
+				return nil, &revisionNotFoundError{digest: digest}
+			}
+			tag.RevisionName = name
+		}
+		resolved = append(resolved, tag)
+	}
+	return resolved, nil
+}
+
+/*
+resolveTraffic resolve os alvos por digest de imagem de 'spec.deploy.traffic' e 'spec.deploy.tags'
+contra as revisões Knative da função. As revisões só são listadas se algum alvo usar um digest.
+Os erros indicam o campo do alvo não encontrado.
+*/
+func (r *FunctionReconciler) resolveTraffic(ctx context.Context, function *functionsv1alpha1.Function) ([]functionsv1alpha1.TrafficTarget, []functionsv1alpha1.RevisionTag, error) {
+	needsLookup := false
+	for _, target := range function.Spec.Deploy.Traffic {
+		needsLookup = needsLookup || target.ImageDigest != ""
+	}
+	for _, tag := range function.Spec.Deploy.Tags {
+		needsLookup = needsLookup || tag.ImageDigest != ""
+	}
+	if !needsLookup {
+		return function.Spec.Deploy.Traffic, function.Spec.Deploy.Tags, nil
+	}
+
+	revisions := &knservingv1.RevisionList{}
+	if err := r.List(ctx, revisions, client.InNamespace(function.Namespace),
+		client.MatchingLabels{knativeServiceLabel: function.Name}); err != nil {
+		return nil, nil, err
+	}
+	revisionsByDigest := indexRevisionsByDigest(revisions.Items)
+
+	traffic, err := resolveTrafficTargets(function.Spec.Deploy.Traffic, revisionsByDigest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("spec.deploy.traffic: %w", err)
+	}
+	tags, err := resolveRevisionTags(function.Spec.Deploy.Tags, revisionsByDigest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("spec.deploy.tags: %w", err)
+	}
+	return traffic, tags, nil
+}
+
+/*
+knativeTrafficTargets traduz a divisão de tráfego da Function para a rota do Knative Service.
+Sem alvos, reproduz o padrão do Knative (100% para a revisão mais recente) de forma explícita,
+para que a comparação com o Service no cluster (já com os defaults aplicados) seja estável.
+*/
+func knativeTrafficTargets(targets []functionsv1alpha1.TrafficTarget) []knservingv1.TrafficTarget {
+	if len(targets) == 0 {
+		return []knservingv1.TrafficTarget{{LatestRevision: boolPtr(true), Percent: int64Ptr(100)}}
+	}
+
+	knativeTargets := make([]knservingv1.TrafficTarget, 0, len(targets))
+	for _, target := range targets {
+		knativeTarget := knservingv1.TrafficTarget{
+			Tag:            target.Tag,
+			LatestRevision: boolPtr(target.LatestRevision),
+			Percent:        int64Ptr(target.Percent),
+		}
+		if !target.LatestRevision {
+			knativeTarget.RevisionName = target.RevisionName
+		}
+		knativeTargets = append(knativeTargets, knativeTarget)
+	}
+	return knativeTargets
+}
+
+// knativeTagTargets translates the Function tags into route targets that receive no traffic.
+func knativeTagTargets(tags []functionsv1alpha1.RevisionTag) []knservingv1.TrafficTarget {
+	knativeTargets := make([]knservingv1.TrafficTarget, 0, len(tags))
+	for _, tag := range tags {
+		knativeTarget := knservingv1.TrafficTarget{
+			Tag:            tag.Name,
+			LatestRevision: boolPtr(tag.LatestRevision),
+			Percent:        int64Ptr(0),
+		}
+		if !tag.LatestRevision {
+			knativeTarget.RevisionName = tag.RevisionName
+		}
+		knativeTargets = append(knativeTargets, knativeTarget)
+	}
+	return knativeTargets
+}
+
+// tagURLs reports the URL of each Function tag from the route status of the Knative Service.
+func tagURLs(function *functionsv1alpha1.Function, ksvc *knservingv1.Service) map[string]string {
+	declared := map[string]bool{}
+	for _, tag := range function.Spec.Deploy.Tags {
+		declared[tag.Name] = true
+	}
+
+	urls := map[string]string{}
+	for _, target := range ksvc.Status.Traffic {
+		if declared[target.Tag] && target.URL != nil {
+			urls[target.Tag] = target.URL.String()
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+	return urls
+}
+
+// trafficStatus reports the effective split from the route status of the Knative Service.
+func trafficStatus(ksvc *knservingv1.Service) []functionsv1alpha1.TrafficTargetStatus {
+	var status []functionsv1alpha1.TrafficTargetStatus
+	for _, target := range ksvc.Status.Traffic {
+		entry := functionsv1alpha1.TrafficTargetStatus{
+			RevisionName: target.RevisionName,
+			Tag:          target.Tag,
+		}
+		if target.LatestRevision != nil {
+			entry.LatestRevision = *target.LatestRevision
+		}
+		if target.Percent != nil {
+			entry.Percent = *target.Percent
+		}
+		if target.URL != nil {
+			entry.URL = target.URL.String()
+		}
+		status = append(status, entry)
+	}
+	return status
+}
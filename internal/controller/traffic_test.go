@@ -0,0 +1,299 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+const (
+	digestA = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB = "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+func testRevision(name, image, generation string) knservingv1.Revision {
+	return knservingv1.Revision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"serving.knative.dev/configurationGeneration": generation},
+		},
+		Spec: knservingv1.RevisionSpec{
+			PodSpec: v1.PodSpec{Containers: []v1.Container{{Image: image}}},
+		},
+	}
+}
+
+func TestValidateTraffic(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []functionsv1alpha1.TrafficTarget
+		wantErr string
+	}{
+		{name: "no targets"},
+		{
+			name: "valid split with tags",
+			targets: []functionsv1alpha1.TrafficTarget{
+				{LatestRevision: true, Percent: 90, Tag: "latest"},
+				{RevisionName: "fn-00001", Percent: 10, Tag: "stable"},
+				{ImageDigest: digestA, Percent: 0, Tag: "old"},
+			},
+		},
+		{
+			name: "percentages below 100",
+			targets: []functionsv1alpha1.TrafficTarget{
+				{LatestRevision: true, Percent: 50},
+				{RevisionName: "fn-00001", Percent: 40},
+			},
+			wantErr: "somam 90",
+		},
+		{
+			name: "duplicate tags",
+			targets: []functionsv1alpha1.TrafficTarget{
+				{LatestRevision: true, Percent: 50, Tag: "a"},
+				{RevisionName: "fn-00001", Percent: 50, Tag: "a"},
+			},
+			wantErr: `tag "a" duplicada`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{
+				Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Traffic: tt.targets}},
+			}
+			err := validateTraffic(function)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestIndexRevisionsByDigest(t *testing.T) {
+	g := NewWithT(t)
+
+	index := indexRevisionsByDigest([]knservingv1.Revision{
+		testRevision("fn-00001", "registry.io/fn@"+digestA, "1"),
+		// Same image, newer configuration (e.g. only env changed): the newest revision wins
+		testRevision("fn-00003", "registry.io/fn@"+digestA, "3"),
+		testRevision("fn-00002", "127.0.0.1:30500/fn@"+digestB, "2"),
+		testRevision("fn-00004", "registry.io/fn:latest", "4"),
+	})
+
+	g.Expect(index).To(Equal(map[string]string{
+		digestA: "fn-00003",
+		digestB: "fn-00002",
+	}))
+}
+
+func TestResolveTrafficTargets(t *testing.T) {
+	index := map[string]string{digestA: "fn-00001"}
+
+	t.Run("digest targets get the revision name", func(t *testing.T) {
+		g := NewWithT(t)
+		resolved, err := resolveTrafficTargets([]functionsv1alpha1.TrafficTarget{
+			{LatestRevision: true, Percent: 80},
+			{ImageDigest: "registry.io/fn@" + digestA, Percent: 20, Tag: "stable"},
+		}, index)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(resolved[0].RevisionName).To(BeEmpty())
+		g.Expect(resolved[1].RevisionName).To(Equal("fn-00001"))
+		g.Expect(resolved[1].Tag).To(Equal("stable"))
+	})
+
+	t.Run("unknown digest", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := resolveTrafficTargets([]functionsv1alpha1.TrafficTarget{
+			{ImageDigest: digestB, Percent: 100},
+		}, index)
+		var notFound *revisionNotFoundError
+		g.Expect(err).To(BeAssignableToTypeOf(notFound))
+		g.Expect(err.Error()).To(ContainSubstring(digestB))
+	})
+}
+
+func TestKnativeTrafficTargets(t *testing.T) {
+	t.Run("defaults to 100% on the latest revision", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(knativeTrafficTargets(nil)).To(Equal([]knservingv1.TrafficTarget{
+			{LatestRevision: boolPtr(true), Percent: int64Ptr(100)},
+		}))
+	})
+
+	t.Run("split between latest and pinned revisions", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(knativeTrafficTargets([]functionsv1alpha1.TrafficTarget{
+			{LatestRevision: true, Percent: 90, Tag: "candidate"},
+			{RevisionName: "fn-00001", Percent: 10, Tag: "stable"},
+		})).To(Equal([]knservingv1.TrafficTarget{
+			{Tag: "candidate", LatestRevision: boolPtr(true), Percent: int64Ptr(90)},
+			{Tag: "stable", RevisionName: "fn-00001", LatestRevision: boolPtr(false), Percent: int64Ptr(10)},
+		}))
+	})
+
+	t.Run("applied to the Knative Service route", func(t *testing.T) {
+		g := NewWithT(t)
+		r := &FunctionReconciler{}
+		function := &functionsv1alpha1.Function{
+			ObjectMeta: metav1.ObjectMeta{Name: "fn", Namespace: "default"},
+			Spec: functionsv1alpha1.FunctionSpec{
+				Deploy: functionsv1alpha1.DeploySpec{Traffic: []functionsv1alpha1.TrafficTarget{
+					{LatestRevision: true, Percent: 50},
+					{RevisionName: "fn-00001", Percent: 50},
+				}},
+			},
+			Status: functionsv1alpha1.FunctionStatus{ImageDigest: "registry.io/fn@" + digestA},
+		}
+		ksvc := r.buildKnativeService(function)
+		g.Expect(ksvc.Spec.Traffic).To(HaveLen(2))
+		g.Expect(ksvc.Spec.Traffic[1].RevisionName).To(Equal("fn-00001"))
+	})
+}
+
+func TestTrafficStatus(t *testing.T) {
+	g := NewWithT(t)
+
+	ksvc := &knservingv1.Service{}
+	ksvc.Status.Traffic = []knservingv1.TrafficTarget{
+		{RevisionName: "fn-00002", LatestRevision: boolPtr(true), Percent: int64Ptr(90)},
+		{RevisionName: "fn-00001", LatestRevision: boolPtr(false), Percent: int64Ptr(10), Tag: "stable",
+			URL: apis.HTTP("stable-fn.default.svc.cluster.local")},
+	}
+
+	g.Expect(trafficStatus(ksvc)).To(Equal([]functionsv1alpha1.TrafficTargetStatus{
+		{RevisionName: "fn-00002", LatestRevision: true, Percent: 90},
+		{RevisionName: "fn-00001", Percent: 10, Tag: "stable", URL: "http://stable-fn.default.svc.cluster.local"},
+	}))
+}
+
+func TestValidateTrafficTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		deploy  functionsv1alpha1.DeploySpec
+		wantErr string
+	}{
+		{
+			name:   "tags without traffic split",
+			deploy: functionsv1alpha1.DeploySpec{Tags: []functionsv1alpha1.RevisionTag{{Name: "candidate", LatestRevision: true}}},
+		},
+		{
+			name: "tag already used by a traffic target",
+			deploy: functionsv1alpha1.DeploySpec{
+				Traffic: []functionsv1alpha1.TrafficTarget{{LatestRevision: true, Percent: 100, Tag: "candidate"}},
+				Tags:    []functionsv1alpha1.RevisionTag{{Name: "candidate", RevisionName: "fn-00001"}},
+			},
+			wantErr: `tag "candidate" já usada`,
+		},
+		{
+			name: "canary is reserved for the rollout",
+			deploy: functionsv1alpha1.DeploySpec{
+				Rollout: &functionsv1alpha1.RolloutSpec{Steps: []functionsv1alpha1.RolloutStep{{Percent: 50}}},
+				Tags:    []functionsv1alpha1.RevisionTag{{Name: "canary", LatestRevision: true}},
+			},
+			wantErr: "reservada ao rollout",
+		},
+		{
+			name:   "canary is free without a rollout",
+			deploy: functionsv1alpha1.DeploySpec{Tags: []functionsv1alpha1.RevisionTag{{Name: "canary", LatestRevision: true}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: tt.deploy}}
+			err := validateTraffic(function)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestResolveRevisionTags(t *testing.T) {
+	g := NewWithT(t)
+	index := map[string]string{digestA: "fn-00001"}
+
+	resolved, err := resolveRevisionTags([]functionsv1alpha1.RevisionTag{
+		{Name: "candidate", LatestRevision: true},
+		{Name: "qa", ImageDigest: "registry.io/fn@" + digestA},
+	}, index)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(resolved[0].RevisionName).To(BeEmpty())
+	g.Expect(resolved[1].RevisionName).To(Equal("fn-00001"))
+
+	_, err = resolveRevisionTags([]functionsv1alpha1.RevisionTag{{Name: "qa", ImageDigest: digestB}}, index)
+	var notFound *revisionNotFoundError
+	g.Expect(err).To(BeAssignableToTypeOf(notFound))
+}
+
+func TestKnativeTagTargets(t *testing.T) {
+	g := NewWithT(t)
+
+	tags := []functionsv1alpha1.RevisionTag{
+		{Name: "candidate", LatestRevision: true},
+		{Name: "qa", RevisionName: "fn-00002"},
+	}
+	g.Expect(knativeTagTargets(tags)).To(Equal([]knservingv1.TrafficTarget{
+		{Tag: "candidate", LatestRevision: boolPtr(true), Percent: int64Ptr(0)},
+		{Tag: "qa", RevisionName: "fn-00002", LatestRevision: boolPtr(false), Percent: int64Ptr(0)},
+	}))
+
+	// Tags are added after the split, which keeps 100% on the latest revision by default
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "fn", Namespace: "default"},
+		Spec:       functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Tags: tags}},
+		Status:     functionsv1alpha1.FunctionStatus{ImageDigest: "registry.io/fn@" + digestA},
+	}
+	ksvc := (&FunctionReconciler{}).buildKnativeService(function)
+	g.Expect(ksvc.Spec.Traffic).To(HaveLen(3))
+	g.Expect(ksvc.Spec.Traffic[0]).To(Equal(knservingv1.TrafficTarget{LatestRevision: boolPtr(true), Percent: int64Ptr(100)}))
+	g.Expect(ksvc.Spec.Traffic[2].Tag).To(Equal("qa"))
+}
+
+func TestTagURLs(t *testing.T) {
+	g := NewWithT(t)
+
+	function := &functionsv1alpha1.Function{
+		Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{
+			Tags: []functionsv1alpha1.RevisionTag{{Name: "candidate", LatestRevision: true}},
+		}},
+	}
+	ksvc := &knservingv1.Service{}
+	ksvc.Status.Traffic = []knservingv1.TrafficTarget{
+		{RevisionName: "fn-00001", Percent: int64Ptr(100), Tag: "stable", URL: apis.HTTP("stable-fn.default.svc.cluster.local")},
+		{RevisionName: "fn-00002", Percent: int64Ptr(0), Tag: "candidate", URL: apis.HTTP("candidate-fn.default.svc.cluster.local")},
+	}
+
+	g.Expect(tagURLs(function, ksvc)).To(Equal(map[string]string{
+		"candidate": "http://candidate-fn.default.svc.cluster.local",
+	}))
+	g.Expect(tagURLs(&functionsv1alpha1.Function{}, ksvc)).To(BeNil())
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	knconfig "knative.dev/serving/pkg/apis/config"
+	knservingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+// VolumeFilesChecksumAnnotation records the digest of the inline volume files on the revision template,
+// so that editing a file rolls out a new revision.
+const VolumeFilesChecksumAnnotation = "functions.zenith.com/volume-files-checksum"
+
+func volumeFilesConfigMapName(function *functionsv1alpha1.Function) string {
+	return function.Name + "-files"
+}
+
+/*
+volumeFilesItems reúne os arquivos inline de todos os volumes em um único ConfigMap.
+As chaves recebem o nome do volume como prefixo ("certs.file-000") e os itens de cada volume
+projetam as chaves nos caminhos originais.
+*/
+func volumeFilesItems(function *functionsv1alpha1.Function) (map[string]string, map[string][]v1.KeyToPath) {
+	data := map[string]string{}
+	items := map[string][]v1.KeyToPath{}
+	for _, volume := range function.Spec.Deploy.Volumes {
+		if volume.Files == nil {
+			continue
+		}
+		volumeData, volumeItems := inlineSourceItems(volume.Files)
+		for key, content := range volumeData {
+			data[volume.Name+"."+key] = content
+		}
+		for _, item := range volumeItems {
+			items[volume.Name] = append(items[volume.Name], v1.KeyToPath{Key: volume.Name + "." + item.Key, Path: item.Path})
+		}
+	}
+	return data, items
+}
+
+// volumeFilesChecksum returns the digest of every inline volume file, or "" if there are none.
+func volumeFilesChecksum(function *functionsv1alpha1.Function) string {
+	files := map[string]string{}
+	for _, volume := range function.Spec.Deploy.Volumes {
+		for path, content := range volume.Files {
+			files[volume.Name+"/"+path] = content
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+	return hashSourceFiles(files, nil)
+}
+
+// buildVolumeFilesConfigMap builds the ConfigMap holding the inline volume files, or nil if there are none.
+func buildVolumeFilesConfigMap(function *functionsv1alpha1.Function) *v1.ConfigMap {
+	data, _ := volumeFilesItems(function)
+	if len(data) == 0 {
+		return nil
+	}
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      volumeFilesConfigMapName(function),
+			Namespace: function.Namespace,
+			Labels:    map[string]string{"functions.zenith.com/managed-by": "zenith-operator"},
+		},
+		Data: data,
+	}
+}
+
+/*
+functionVolumes traduz 'spec.deploy.volumes' nos volumes do Pod e nas montagens do container.
+Secrets, ConfigMaps, volumes projetados e arquivos inline são montados como somente leitura,
+como o Knative exige (e aplicaria por padrão).
+*/
+func functionVolumes(function *functionsv1alpha1.Function) ([]v1.Volume, []v1.VolumeMount) {
+	if len(function.Spec.Deploy.Volumes) == 0 {
+		return nil, nil
+	}
+
+	_, fileItems := volumeFilesItems(function)
+	volumes := make([]v1.Volume, 0, len(function.Spec.Deploy.Volumes))
+	mounts := make([]v1.VolumeMount, 0, len(function.Spec.Deploy.Volumes))
+	for _, volume := range function.Spec.Deploy.Volumes {
+		podVolume := v1.Volume{Name: volume.Name}
+		readOnly := true
+		switch {
+		case volume.Secret != nil:
+			podVolume.Secret = volume.Secret
+		case volume.ConfigMap != nil:
+			podVolume.ConfigMap = volume.ConfigMap
+		case volume.Projected != nil:
+			podVolume.Projected = volume.Projected
+		case volume.EmptyDir != nil:
+			podVolume.EmptyDir = volume.EmptyDir
+			readOnly = false
+		case volume.PersistentVolumeClaim != nil:
+			podVolume.PersistentVolumeClaim = volume.PersistentVolumeClaim
+			readOnly = volume.PersistentVolumeClaim.ReadOnly
+		case volume.Files != nil:
+			podVolume.ConfigMap = &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: volumeFilesConfigMapName(function)},
+				Items:                fileItems[volume.Name],
+			}
+		}
+		volumes = append(volumes, podVolume)
+		mounts = append(mounts, v1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: volume.MountPath,
+			SubPath:   volume.SubPath,
+			ReadOnly:  readOnly,
+		})
+	}
+	return volumes, mounts
+}
+
+/*
+validateVolumeFeatures verifica os volumes de 'spec.deploy.volumes' contra as feature flags do Knative:
+PersistentVolumeClaims exigem 'kubernetes.podspec-persistent-volume-claim' e, quando montados
+com escrita, também 'kubernetes.podspec-persistent-volume-write'.
+*/
+func validateVolumeFeatures(function *functionsv1alpha1.Function, features *knconfig.Features) error {
+	for i, volume := range function.Spec.Deploy.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		field := fmt.Sprintf("spec.deploy.volumes[%d].persistentVolumeClaim", i)
+		if features.PodSpecPersistentVolumeClaim != knconfig.Enabled {
+			return fmt.Errorf("%s exige a flag 'kubernetes.podspec-persistent-volume-claim' habilitada no Knative (config-features)", field)
+		}
+		if !volume.PersistentVolumeClaim.ReadOnly && features.PodSpecPersistentVolumeWrite != knconfig.Enabled {
+			return fmt.Errorf("%s sem readOnly exige a flag 'kubernetes.podspec-persistent-volume-write' habilitada no Knative (config-features)", field)
+		}
+	}
+	return nil
+}
+
+// volumesChanged reports whether the volumes or the mounts of any container differ from the desired ones.
+func volumesChanged(current, desired *knservingv1.Service) bool {
+	if !equality.Semantic.DeepEqual(current.Spec.Template.Spec.Volumes, desired.Spec.Template.Spec.Volumes) {
+		return true
+	}
+	pairs, ok := containerPairs(current, desired)
+	if !ok {
+		return true
+	}
+	for _, pair := range pairs {
+		if !equality.Semantic.DeepEqual(pair[0].VolumeMounts, pair[1].VolumeMounts) {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileVolumeFiles creates, updates or removes the ConfigMap holding the inline volume files.
+func (r *FunctionReconciler) reconcileVolumeFiles(ctx context.Context, function *functionsv1alpha1.Function) error {
+	logger := logf.FromContext(ctx)
+
+	desired := buildVolumeFilesConfigMap(function)
+	if desired == nil {
+		existing := &v1.ConfigMap{}
+		err := r.Get(ctx, types.NamespacedName{Name: volumeFilesConfigMapName(function), Namespace: function.Namespace}, existing)
+		if errors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(existing, function) {
+			return nil
+		}
+		logger.Info("Arquivos inline removidos dos volumes, deletando ConfigMap", "ConfigMap.Name", existing.Name)
+		return client.IgnoreNotFound(r.Delete(ctx, existing))
+	}
+
+	configMap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		configMap.Labels["functions.zenith.com/managed-by"] = "zenith-operator"
+		if !equality.Semantic.DeepEqual(configMap.Data, desired.Data) {
+			configMap.Data = desired.Data
+		}
+		return controllerutil.SetControllerReference(function, configMap, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("ConfigMap dos arquivos de volume sincronizado", "ConfigMap.Name", configMap.Name, "Operation", op)
+	}
+	return nil
+}
+
+/*
+validateVolumeReferences valida que os Secrets, ConfigMaps e PersistentVolumeClaims
+referenciados nos volumes existem no namespace da função, como validateEnvReferences faz com o env.
+Retorna um Result não-vazio se a validação falhar e a reconciliação deve parar.
+*/
+func (r *FunctionReconciler) validateVolumeReferences(ctx context.Context, function *functionsv1alpha1.Function) (ctrl.Result, error) {
+	for _, volume := range function.Spec.Deploy.Volumes {
+		var secrets []*v1.SecretEnvSource
+		var configMaps []*v1.ConfigMapEnvSource
+
+		switch {
+		case volume.Secret != nil:
+			secrets = append(secrets, &v1.SecretEnvSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: volume.Secret.SecretName},
+				Optional:             volume.Secret.Optional,
+			})
+		case volume.ConfigMap != nil:
+			configMaps = append(configMaps, &v1.ConfigMapEnvSource{
+				LocalObjectReference: volume.ConfigMap.LocalObjectReference,
+				Optional:             volume.ConfigMap.Optional,
+			})
+		case volume.Projected != nil:
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil {
+					secrets = append(secrets, &v1.SecretEnvSource{
+						LocalObjectReference: source.Secret.LocalObjectReference,
+						Optional:             source.Secret.Optional,
+					})
+				}
+				if source.ConfigMap != nil {
+					configMaps = append(configMaps, &v1.ConfigMapEnvSource{
+						LocalObjectReference: source.ConfigMap.LocalObjectReference,
+						Optional:             source.ConfigMap.Optional,
+					})
+				}
+			}
+		case volume.PersistentVolumeClaim != nil:
+			if result, err := r.validatePersistentVolumeClaimRef(ctx, function, volume.PersistentVolumeClaim.ClaimName); err != nil || result.RequeueAfter > 0 {
+				return result, err
+			}
+		}
+
+		for _, secretRef := range secrets {
+			if result, err := r.validateSecretEnvSource(ctx, function, secretRef); err != nil || result.RequeueAfter > 0 {
+				return result, err
+			}
+		}
+		for _, configMapRef := range configMaps {
+			if result, err := r.validateConfigMapEnvSource(ctx, function, configMapRef); err != nil || result.RequeueAfter > 0 {
+				return result, err
+			}
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// validatePersistentVolumeClaimRef valida que um PersistentVolumeClaim existe no namespace da função
+func (r *FunctionReconciler) validatePersistentVolumeClaimRef(ctx context.Context, function *functionsv1alpha1.Function, claimName string) (ctrl.Result, error) {
+	logger := logf.FromContext(ctx)
+
+	claim := &v1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: claimName, Namespace: function.Namespace}, claim)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Error(err, "PersistentVolumeClaim não encontrado", "PersistentVolumeClaim.Name", claimName)
+		condition := metav1.Condition{
+			Type:    "Ready",
+			Status:  metav1.ConditionFalse,
+			Reason:  "PersistentVolumeClaimNotFound",
+			Message: fmt.Sprintf("PersistentVolumeClaim não encontrado: %s. Crie o PersistentVolumeClaim no namespace %s antes de deployar a função.", claimName, function.Namespace),
+		}
+		meta.SetStatusCondition(&function.Status.Conditions, condition)
+		function.Status.ObservedGeneration = function.Generation
+		if err := r.Status().Update(ctx, function); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	} else if err != nil {
+		logger.Error(err, "Falha ao verificar PersistentVolumeClaim")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
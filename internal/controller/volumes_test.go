@@ -0,0 +1,190 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	functionsv1alpha1 "github.com/lucasgois1/zenith-operator/api/v1alpha1"
+)
+
+func TestFunctionVolumes(t *testing.T) {
+	g := NewWithT(t)
+
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "reports", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Volumes: []functionsv1alpha1.FunctionVolume{
+			{Name: "certs", MountPath: "/etc/certs", Secret: &v1.SecretVolumeSource{SecretName: "client-tls"}},
+			{Name: "scratch", MountPath: "/tmp/work", EmptyDir: &v1.EmptyDirVolumeSource{}},
+			{Name: "data", MountPath: "/data", PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "reports-data"}},
+			{Name: "config", MountPath: "/etc/reports", Files: map[string]string{
+				"templates/summary.html": "<h1>{{ .Title }}</h1>",
+				"app.json":               `{"pageSize": 50}`,
+			}},
+		}}},
+	}
+	volumes, mounts := functionVolumes(function)
+	g.Expect(volumes).To(Equal([]v1.Volume{
+		{Name: "certs", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "client-tls"}}},
+		{Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+		{Name: "data", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "reports-data"}}},
+		{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+			LocalObjectReference: v1.LocalObjectReference{Name: "reports-files"},
+			Items: []v1.KeyToPath{
+				{Key: "config.file-000", Path: "app.json"},
+				{Key: "config.file-001", Path: "templates/summary.html"},
+			},
+		}}},
+	}))
+	// Read-only except for emptyDir and writable claims, as Knative would default them
+	g.Expect(mounts).To(Equal([]v1.VolumeMount{
+		{Name: "certs", MountPath: "/etc/certs", ReadOnly: true},
+		{Name: "scratch", MountPath: "/tmp/work"},
+		{Name: "data", MountPath: "/data"},
+		{Name: "config", MountPath: "/etc/reports", ReadOnly: true},
+	}))
+
+	volumes, mounts = functionVolumes(&functionsv1alpha1.Function{})
+	g.Expect(volumes).To(BeNil())
+	g.Expect(mounts).To(BeNil())
+}
+
+func TestValidateVolumeFeatures(t *testing.T) {
+	claim := func(readOnly bool) []functionsv1alpha1.FunctionVolume {
+		return []functionsv1alpha1.FunctionVolume{
+			{Name: "scratch", MountPath: "/tmp/work", EmptyDir: &v1.EmptyDirVolumeSource{}},
+			{Name: "data", MountPath: "/data", PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "reports-data", ReadOnly: readOnly}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		volumes []functionsv1alpha1.FunctionVolume
+		flags   map[string]string
+		wantErr string
+	}{
+		{name: "no claims", volumes: claim(false)[:1]},
+		{
+			name:    "claims disabled",
+			volumes: claim(true),
+			wantErr: "spec.deploy.volumes[1].persistentVolumeClaim exige a flag 'kubernetes.podspec-persistent-volume-claim'",
+		},
+		{
+			name:    "read-only claim",
+			volumes: claim(true),
+			flags:   map[string]string{"kubernetes.podspec-persistent-volume-claim": "enabled"},
+		},
+		{
+			name:    "writable claim without the write flag",
+			volumes: claim(false),
+			flags:   map[string]string{"kubernetes.podspec-persistent-volume-claim": "enabled"},
+			wantErr: "exige a flag 'kubernetes.podspec-persistent-volume-write'",
+		},
+		{
+			name:    "writable claim",
+			volumes: claim(false),
+			flags: map[string]string{
+				"kubernetes.podspec-persistent-volume-claim": "enabled",
+				"kubernetes.podspec-persistent-volume-write": "enabled",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			function := &functionsv1alpha1.Function{Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Volumes: tt.volumes}}}
+
+			err := validateVolumeFeatures(function, knativeFeaturesFrom(t, tt.flags))
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			}
+		})
+	}
+}
+
+func TestBuildVolumeFilesConfigMap(t *testing.T) {
+	g := NewWithT(t)
+
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "reports", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Volumes: []functionsv1alpha1.FunctionVolume{
+			{Name: "scratch", MountPath: "/tmp/work", EmptyDir: &v1.EmptyDirVolumeSource{}},
+			{Name: "config", MountPath: "/etc/reports", Files: map[string]string{
+				"templates/summary.html": "<h1>{{ .Title }}</h1>",
+				"app.json":               `{"pageSize": 50}`,
+			}},
+		}}},
+	}
+	configMap := buildVolumeFilesConfigMap(function)
+	g.Expect(configMap.Name).To(Equal("reports-files"))
+	g.Expect(configMap.Data).To(Equal(map[string]string{
+		"config.file-000": `{"pageSize": 50}`,
+		"config.file-001": "<h1>{{ .Title }}</h1>",
+	}))
+
+	g.Expect(buildVolumeFilesConfigMap(&functionsv1alpha1.Function{})).To(BeNil())
+}
+
+func TestBuildKnativeServiceVolumes(t *testing.T) {
+	r := &FunctionReconciler{}
+	function := &functionsv1alpha1.Function{
+		ObjectMeta: metav1.ObjectMeta{Name: "reports", Namespace: "default"},
+		Spec: functionsv1alpha1.FunctionSpec{Deploy: functionsv1alpha1.DeploySpec{Volumes: []functionsv1alpha1.FunctionVolume{
+			{Name: "certs", MountPath: "/etc/certs", Secret: &v1.SecretVolumeSource{SecretName: "client-tls"}},
+			{Name: "scratch", MountPath: "/tmp/work", EmptyDir: &v1.EmptyDirVolumeSource{}},
+			{Name: "config", MountPath: "/etc/reports", Files: map[string]string{"app.json": `{"pageSize": 50}`}},
+		}}},
+	}
+	ksvc := r.buildKnativeService(function)
+
+	t.Run("volumes and mounts", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(ksvc.Spec.Template.Spec.Volumes).To(HaveLen(3))
+		g.Expect(ksvc.Spec.Template.Spec.Containers[0].VolumeMounts).To(HaveLen(3))
+		g.Expect(ksvc.Spec.Template.Annotations).To(HaveKey(VolumeFilesChecksumAnnotation))
+	})
+
+	t.Run("editing an inline file changes the revision template", func(t *testing.T) {
+		g := NewWithT(t)
+		edited := function.DeepCopy()
+		edited.Spec.Deploy.Volumes[2].Files["app.json"] = `{"pageSize": 100}`
+		editedKsvc := r.buildKnativeService(edited)
+		g.Expect(editedKsvc.Spec.Template.Annotations[VolumeFilesChecksumAnnotation]).
+			NotTo(Equal(ksvc.Spec.Template.Annotations[VolumeFilesChecksumAnnotation]))
+		g.Expect(volumesChanged(ksvc, editedKsvc)).To(BeFalse())
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(volumesChanged(ksvc.DeepCopy(), ksvc)).To(BeFalse())
+
+		changed := function.DeepCopy()
+		changed.Spec.Deploy.Volumes[0].MountPath = "/etc/tls"
+		g.Expect(volumesChanged(ksvc, r.buildKnativeService(changed))).To(BeTrue())
+
+		removed := function.DeepCopy()
+		removed.Spec.Deploy.Volumes = removed.Spec.Deploy.Volumes[:1]
+		g.Expect(volumesChanged(ksvc, r.buildKnativeService(removed))).To(BeTrue())
+	})
+}